@@ -3,13 +3,208 @@
 func main() {
     // ... other setup ...
 
-    th := handlers.NewTorrentHandler("http://localhost:5000")
+    cfg := config.Get().Config()
+    torrentSvc := services.NewTorrentService(rtorrent.New("http://localhost:5000"))
+
+    viewStore := views.NewStore()
+    labelStore := labels.NewStore()
+    trackerStore := trackers.NewStore(
+        filepath.Join(fileutil.GetSettingsPath(), "trackers"),
+        filepath.Join("plugins", "tracklabels", "trackers"),
+    )
+    // Hit-and-run policies of private trackers guard every removal
+    hnrStore, err := hnr.NewStore(fileutil.GetSettingsPath())
+    if err != nil {
+        log.Fatalf("failed to load hit-and-run policies: %v", err)
+    }
+    torrentSvc.SetRemoveGuard(hnrStore.Guard)
+    services.RegisterListFilter("protected", func(t *services.Torrent) bool {
+        return hnrStore.Obligation(t, time.Now()).Protected
+    })
+
+    th := handlers.NewTorrentHandler(handlers.TorrentHandlerConfig{
+        TorrentService: torrentSvc,
+        Views:          viewStore,
+        Labels:         labelStore,
+        Trackers:       trackerStore,
+        HitAndRun:      hnrStore,
+    })
 
     r := chi.NewRouter()
-    r.Get("/torrents", th.GetTorrents)
+    r.Get("/torrents", th.HandleTorrentList)
     r.Post("/torrents/{hash}/start", th.StartTorrent)
     r.Post("/torrents/{hash}/pause", th.PauseTorrent)
     r.Delete("/torrents/{hash}", th.DeleteTorrent)
 
+    // Context menus and bulk actions
+    if err := handlers.RegisterCoreActions(handlers.Actions(), torrentSvc); err != nil {
+        log.Fatalf("failed to register actions: %v", err)
+    }
+    handlers.Actions().SetPermissionChecker(func(r *http.Request, permission string) bool {
+        return cfg.Permissions.Allows(permission)
+    })
+    r.Get("/objects/menu", handlers.HandleObjectMenu)
+    r.Get("/objects/modal", handlers.HandleObjectModal)
+    r.Post("/objects/action", handlers.HandleObjectAction)
+    r.Post("/objects/{type}/{action}", handlers.HandleObjectAction)
+
+    // qBittorrent Web API compatibility for external tools
+    qbt := qbittorrent.New(qbittorrent.Config{
+        TorrentService: torrentSvc,
+        AuthEnabled:    cfg.Auth.Enabled,
+        Username:       cfg.Auth.Username,
+        Password:       cfg.Auth.Password,
+    })
+    r.Mount("/api/v2", qbt.Routes())
+
+    // Saved views
+    vh := viewshandler.New(viewshandler.Config{
+        Store:          viewStore,
+        TorrentService: torrentSvc,
+    })
+    r.Mount("/views", vh.Routes())
+
+    // Label management
+    lh := labelshandler.New(labelshandler.Config{
+        TorrentService: torrentSvc,
+        Store:          labelStore,
+    })
+    r.Mount("/labels", lh.Routes())
+
+    // Tracker groups
+    trh := trackershandler.New(trackershandler.Config{
+        TorrentService: torrentSvc,
+        Store:          trackerStore,
+    })
+    r.Mount("/trackers", trh.Routes())
+
+    // Directory browser for the add, move and create dialogs
+    browseRoots := cfg.Server.BrowseRoots
+    if len(browseRoots) == 0 {
+        browseRoots = []string{cfg.Server.DownloadDir}
+    }
+    bh := browsehandler.New(browsehandler.Config{
+        Browser: browse.New(torrentSvc.Client(), browseRoots...),
+    })
+    r.Mount("/browse", bh.Routes())
+
+    // Downloads of completed torrent files
+    dh := datahandler.New(datahandler.Config{TorrentService: torrentSvc})
+    r.Mount("/data", dh.Routes())
+
+    // Original .torrent files
+    sh := sourcehandler.New(sourcehandler.Config{TorrentService: torrentSvc})
+    r.Mount("/source", sh.Routes())
+
+    // Rules that set label, directory, priority and throttle of new torrents
+    ruleStore, err := rules.NewStore(fileutil.GetSettingsPath())
+    if err != nil {
+        log.Fatalf("failed to load rules: %v", err)
+    }
+    rh := ruleshandler.New(ruleshandler.Config{
+        TorrentService: torrentSvc,
+        Store:          ruleStore,
+    })
+    r.Mount("/rules", rh.Routes())
+
+    // Throttle channels, set up again in rTorrent on every start
+    throttleStore, err := throttle.NewStore(torrentSvc, fileutil.GetSettingsPath())
+    if err != nil {
+        log.Fatalf("failed to load throttle channels: %v", err)
+    }
+    if err := throttleStore.Apply(); err != nil {
+        log.Printf("failed to set up throttle channels: %v", err)
+    }
+    tth := throttlehandler.New(throttlehandler.Config{Store: throttleStore})
+    r.Mount("/throttle", tth.Routes())
+
+    // Rules run first; a label's channel applies when no rule chose one
+    torrentSvc.SetAddFilter(func(info *services.AddInfo, opts *services.AddTorrentOptions) {
+        ruleStore.Filter(info, opts)
+        throttleStore.Filter(info, opts)
+    })
+
+    // Adding torrents, directly or after picking files in a preview
+    ath := addtorrent.New(addtorrent.Config{
+        MaxFileSize:    32 << 20,
+        TempDir:        cfg.Server.TempDir,
+        TorrentService: torrentSvc,
+    })
+    r.Mount("/torrents/add", ath.Routes())
+
+    // Bulk import and export of magnet links
+    bmh := bulkmagnethandler.New(bulkmagnethandler.Config{TorrentService: torrentSvc})
+    r.Mount("/bulk", bmh.Routes())
+
+    // Share links, served to people without accounts under /s
+    shareStore, err := share.NewStore(fileutil.GetSettingsPath())
+    if err != nil {
+        log.Fatalf("failed to open share links: %v", err)
+    }
+    shh := sharehandler.New(sharehandler.Config{
+        Store:          shareStore,
+        TorrentService: torrentSvc,
+    })
+    r.Mount("/share", shh.Routes())
+    r.Mount("/s", shh.PublicRoutes())
+
+    // Read-only WebDAV view of completed torrents
+    if cfg.Server.WebDAVPath != "" {
+        davRoots := cfg.Server.WebDAVRoots
+        if len(davRoots) == 0 {
+            davRoots = []string{cfg.Server.DownloadDir}
+        }
+        davh := webdavhandler.New(webdavhandler.Config{
+            Prefix:      cfg.Server.WebDAVPath,
+            FS:          davfs.New(torrentSvc, browse.New(torrentSvc.Client(), davRoots...)),
+            AuthEnabled: cfg.Auth.Enabled,
+            Username:    cfg.Auth.Username,
+            Password:    cfg.Auth.Password,
+        })
+        r.Mount(cfg.Server.WebDAVPath, davh)
+    }
+
+    // Watch directories
+    if len(cfg.Watch.Dirs) > 0 {
+        watcher := watch.New(torrentSvc, cfg.Watch)
+        watcher.Start()
+        defer watcher.Stop()
+    }
+
+    // Move data of finished torrents, previewed under /automove
+    mover := automove.New(torrentSvc, cfg.AutoMove)
+    if cfg.AutoMove.Enabled {
+        mover.Start()
+        defer mover.Stop()
+    }
+    amh := automovehandler.New(automovehandler.Config{Mover: mover})
+    r.Mount("/automove", amh.Routes())
+
+    hnrh := hnrhandler.New(hnrhandler.Config{
+        TorrentService: torrentSvc,
+        Store:          hnrStore,
+    })
+    r.Mount("/hnr", hnrh.Routes())
+
+    // Ratio groups, checked every checkTimesInterval minutes
+    ratioManager, err := ratio.NewManager(torrentSvc, fileutil.GetSettingsPath())
+    if err != nil {
+        log.Fatalf("failed to load ratio groups: %v", err)
+    }
+    ratioManager.Start()
+    defer ratioManager.Stop()
+    rth := ratiohandler.New(ratiohandler.Config{Manager: ratioManager})
+    r.Mount("/ratio", rth.Routes())
+
+    // Weekly grid of speed limits and stopped torrents
+    sched, err := scheduler.New(torrentSvc, fileutil.GetSettingsPath())
+    if err != nil {
+        log.Fatalf("failed to load scheduler: %v", err)
+    }
+    sched.Start()
+    defer sched.Stop()
+    sch := schedulerhandler.New(schedulerhandler.Config{Scheduler: sched})
+    r.Mount("/scheduler", sch.Routes())
+
     // ... start server ...
-}
\ No newline at end of file
+}
@@ -0,0 +1,254 @@
+// internal/automove/automove.go
+package automove
+
+import (
+    "fmt"
+    "path/filepath"
+    "regexp"
+    "strings"
+    "sync"
+    "time"
+
+    "rutorrent-web/internal/config"
+    "rutorrent-web/internal/services"
+)
+
+const (
+    // checkInterval is how often the snapshot is checked for finished torrents
+    checkInterval = 10 * time.Second
+    // maxLog bounds the moves kept for the log
+    maxLog = 200
+    // defaultUnlabeled is {label} of torrents without one
+    defaultUnlabeled = "unlabeled"
+)
+
+var placeholder = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// Move is where a torrent's data went, or would go in a dry run
+type Move struct {
+    Hash   string    `json:"hash"`
+    Name   string    `json:"name"`
+    From   string    `json:"from"`
+    To     string    `json:"to"`
+    DryRun bool      `json:"dry_run,omitempty"`
+    Error  string    `json:"error,omitempty"`
+    Time   time.Time `json:"time"`
+}
+
+// Mover moves the data of torrents when they finish downloading. Only
+// torrents seen incomplete are moved, so torrents that are already
+// complete at startup or added complete stay where they are.
+type Mover struct {
+    torrents *services.TorrentService
+    cfg      config.AutoMoveConfig
+    complete map[string]bool // completion at the last check, nil before the first
+    log      []Move
+    stop     chan struct{}
+    once     sync.Once
+    mu       sync.Mutex
+}
+
+// New creates a mover with the given configuration
+func New(torrents *services.TorrentService, cfg config.AutoMoveConfig) *Mover {
+    return &Mover{
+        torrents: torrents,
+        cfg:      cfg,
+        stop:     make(chan struct{}),
+    }
+}
+
+// Start checks for finished torrents in the background until Stop is called
+func (m *Mover) Start() {
+    go func() {
+        ticker := time.NewTicker(checkInterval)
+        defer ticker.Stop()
+
+        for {
+            select {
+            case <-ticker.C:
+                m.Check()
+            case <-m.stop:
+                return
+            }
+        }
+    }()
+}
+
+// Stop ends background checks
+func (m *Mover) Stop() {
+    m.once.Do(func() { close(m.stop) })
+}
+
+// Check moves the torrents that finished since the last check
+func (m *Mover) Check() {
+    snapshot := m.torrents.Snapshot()
+    complete := make(map[string]bool, len(snapshot))
+    var finished []*services.Torrent
+    for _, t := range snapshot {
+        done := t.Complete && !t.Hashing
+        complete[t.Hash] = done
+        if was, seen := m.complete[t.Hash]; seen && !was && done && m.wanted(t) {
+            finished = append(finished, t)
+        }
+    }
+    m.complete = complete
+
+    for _, t := range finished {
+        move := m.plan(m.cfg.Template, t)
+        move.DryRun = m.cfg.DryRun
+        if move.Error == "" && !m.cfg.DryRun {
+            if err := m.torrents.MoveData(t.Hash, move.To); err != nil {
+                move.Error = err.Error()
+            }
+        }
+        if move.Error != "" {
+            services.Warnf("automove: %s: %s", t.Name, move.Error)
+        } else {
+            services.Infof("automove: %s moved to %s", t.Name, move.To)
+        }
+        m.record(move)
+    }
+}
+
+// Plan returns where every complete torrent the mover handles would go
+// with template, the configured one if empty. Nothing is moved.
+func (m *Mover) Plan(template string) []Move {
+    if template == "" {
+        template = m.cfg.Template
+    }
+    var moves []Move
+    for _, t := range m.torrents.Snapshot() {
+        if t.Complete && m.wanted(t) {
+            move := m.plan(template, t)
+            move.DryRun = true
+            moves = append(moves, move)
+        }
+    }
+    return moves
+}
+
+// Log returns the recent moves, newest first
+func (m *Mover) Log() []Move {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    moves := make([]Move, len(m.log))
+    for i, move := range m.log {
+        moves[len(m.log)-1-i] = move
+    }
+    return moves
+}
+
+func (m *Mover) record(move Move) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    m.log = append(m.log, move)
+    if len(m.log) > maxLog {
+        m.log = m.log[len(m.log)-maxLog:]
+    }
+}
+
+// wanted reports whether the label filter lets a torrent through
+func (m *Mover) wanted(t *services.Torrent) bool {
+    if len(m.cfg.Labels) == 0 {
+        return true
+    }
+    for _, label := range m.cfg.Labels {
+        if strings.EqualFold(label, t.Label) {
+            return true
+        }
+    }
+    return false
+}
+
+func (m *Mover) plan(template string, t *services.Torrent) Move {
+    move := Move{Hash: t.Hash, Name: t.Name, From: t.BasePath, Time: time.Now()}
+    unlabeled := m.cfg.Unlabeled
+    if unlabeled == "" {
+        unlabeled = defaultUnlabeled
+    }
+    dest, err := Destination(template, t, unlabeled)
+    if err != nil {
+        move.Error = err.Error()
+        return move
+    }
+    move.To = dest
+    return move
+}
+
+// Destination expands a template into the new data path of a torrent.
+// Single-file torrents whose path doesn't end in their name get the file
+// put inside the expanded path.
+func Destination(template string, t *services.Torrent, unlabeled string) (string, error) {
+    if template == "" {
+        return "", fmt.Errorf("no destination template")
+    }
+
+    when := t.FinishedAt
+    if when.IsZero() {
+        when = time.Now()
+    }
+    label := cleanPath(t.Label)
+    if label == "" {
+        label = cleanPath(unlabeled)
+    }
+    tracker := t.PrimaryTracker()
+    if tracker == "" {
+        tracker = "unknown"
+    }
+    values := map[string]string{
+        "label":   label,
+        "name":    cleanName(t.Name),
+        "hash":    t.Hash,
+        "tracker": cleanName(tracker),
+        "date":    when.Format("2006-01-02"),
+        "year":    when.Format("2006"),
+        "month":   when.Format("01"),
+        "day":     when.Format("02"),
+    }
+
+    var unknown string
+    dest := placeholder.ReplaceAllStringFunc(template, func(match string) string {
+        key := match[1 : len(match)-1]
+        v, ok := values[key]
+        if !ok && unknown == "" {
+            unknown = match
+        }
+        return v
+    })
+    if unknown != "" {
+        return "", fmt.Errorf("unknown placeholder %s", unknown)
+    }
+
+    dest = filepath.Clean(dest)
+    if !filepath.IsAbs(dest) || filepath.Dir(dest) == dest {
+        return "", fmt.Errorf("destination %q is not an absolute path below /", dest)
+    }
+    if !t.MultiFile && filepath.Base(dest) != t.Name {
+        dest = filepath.Join(dest, t.Name)
+    }
+    return dest, nil
+}
+
+// cleanName makes a value safe as a single path element
+func cleanName(s string) string {
+    s = strings.NewReplacer("/", "_", "\\", "_", "\x00", "").Replace(strings.TrimSpace(s))
+    if s == "" || s == "." || s == ".." {
+        return "_"
+    }
+    return s
+}
+
+// cleanPath makes a value such as a nested label safe as a relative path
+func cleanPath(s string) string {
+    var parts []string
+    for _, part := range strings.Split(s, "/") {
+        part = strings.TrimSpace(part)
+        if part == "" {
+            continue
+        }
+        parts = append(parts, cleanName(part))
+    }
+    return strings.Join(parts, "/")
+}
@@ -0,0 +1,276 @@
+// internal/browse/browse.go
+package browse
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "os/user"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "rutorrent-web/internal/rtorrent"
+    "rutorrent-web/internal/util/permission"
+    "rutorrent-web/internal/util/utility"
+)
+
+var (
+    // ErrOutsideRoot is returned for paths that don't resolve to a location
+    // inside one of the configured roots
+    ErrOutsideRoot = errors.New("path is outside the allowed directories")
+    // ErrNotDirectory is returned when a directory was expected
+    ErrNotDirectory = errors.New("not a directory")
+    // ErrNotWritable is returned when rTorrent can't write to a directory
+    ErrNotWritable = errors.New("directory is not writable")
+)
+
+// dirWritable are the mode bits needed to create entries in a directory:
+// write and search
+const dirWritable = 0x0003
+
+// Entry is a single item of a directory listing
+type Entry struct {
+    Name     string    `json:"name"`
+    Path     string    `json:"path"`
+    IsDir    bool      `json:"is_dir"`
+    Size     int64     `json:"size"`
+    Modified time.Time `json:"modified"`
+    Writable bool      `json:"writable"` // for the rTorrent user
+    Symlink  bool      `json:"symlink"`
+}
+
+// Listing is the content of a directory
+type Listing struct {
+    Path     string  `json:"path"`
+    Parent   string  `json:"parent"` // empty at a root
+    Root     string  `json:"root"`
+    Writable bool    `json:"writable"`
+    Entries  []Entry `json:"entries"`
+}
+
+// Browser lists directories below a fixed set of roots. Paths are resolved
+// through symlinks before they are checked, so a link pointing out of a
+// root can't be used to escape it.
+type Browser struct {
+    client *rtorrent.Client
+    roots  []string
+
+    uid      int
+    gids     []int
+    resolved bool
+    mu       sync.Mutex
+}
+
+// New creates a browser confined to roots. Roots that don't exist are
+// ignored.
+func New(client *rtorrent.Client, roots ...string) *Browser {
+    b := &Browser{client: client}
+    for _, root := range roots {
+        if root == "" {
+            continue
+        }
+        resolved, err := filepath.EvalSymlinks(filepath.Clean(root))
+        if err != nil {
+            continue
+        }
+        b.roots = append(b.roots, resolved)
+    }
+    return b
+}
+
+// Roots returns the resolved roots
+func (b *Browser) Roots() []string {
+    return append([]string(nil), b.roots...)
+}
+
+// Resolve cleans path, resolves its symlinks and checks that the result lies
+// in a root. It returns the resolved path and the root containing it.
+func (b *Browser) Resolve(path string) (string, string, error) {
+    if path == "" || !filepath.IsAbs(path) {
+        return "", "", ErrOutsideRoot
+    }
+    resolved, err := filepath.EvalSymlinks(filepath.Clean(path))
+    if err != nil {
+        // Don't reveal whether paths outside the roots exist
+        if _, ok := b.rootOf(filepath.Clean(path)); !ok {
+            return "", "", ErrOutsideRoot
+        }
+        return "", "", err
+    }
+    root, ok := b.rootOf(resolved)
+    if !ok {
+        return "", "", ErrOutsideRoot
+    }
+    return resolved, root, nil
+}
+
+// List returns the content of a directory, directories first. With dirsOnly
+// files are left out. Entries whose symlinks point outside the roots are
+// skipped.
+func (b *Browser) List(path string, dirsOnly bool) (*Listing, error) {
+    if path == "" && len(b.roots) > 0 {
+        path = b.roots[0]
+    }
+    dir, root, err := b.Resolve(path)
+    if err != nil {
+        return nil, err
+    }
+    info, err := os.Stat(dir)
+    if err != nil {
+        return nil, err
+    }
+    if !info.IsDir() {
+        return nil, ErrNotDirectory
+    }
+
+    items, err := os.ReadDir(dir)
+    if err != nil {
+        return nil, err
+    }
+
+    uid, gids := b.identity()
+    listing := &Listing{
+        Path:     dir,
+        Root:     root,
+        Writable: b.allowed(uid, gids, dir, dirWritable),
+        Entries:  make([]Entry, 0, len(items)),
+    }
+    if dir != root {
+        listing.Parent = filepath.Dir(dir)
+    }
+
+    for _, item := range items {
+        entry, ok := b.entry(dir, item, uid, gids)
+        if !ok || (dirsOnly && !entry.IsDir) {
+            continue
+        }
+        listing.Entries = append(listing.Entries, entry)
+    }
+    sort.Slice(listing.Entries, func(i, j int) bool {
+        a, c := listing.Entries[i], listing.Entries[j]
+        if a.IsDir != c.IsDir {
+            return a.IsDir
+        }
+        return strings.ToLower(a.Name) < strings.ToLower(c.Name)
+    })
+    return listing, nil
+}
+
+// Mkdir creates the directory name inside parent and returns its path. The
+// directory is created by rTorrent so that it belongs to the rTorrent user.
+func (b *Browser) Mkdir(parent, name string) (string, error) {
+    name = strings.TrimSpace(name)
+    if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\\x00") {
+        return "", fmt.Errorf("invalid directory name: %q", name)
+    }
+    dir, _, err := b.Resolve(parent)
+    if err != nil {
+        return "", err
+    }
+    if info, err := os.Stat(dir); err != nil {
+        return "", err
+    } else if !info.IsDir() {
+        return "", ErrNotDirectory
+    }
+
+    uid, gids := b.identity()
+    if !b.allowed(uid, gids, dir, dirWritable) {
+        return "", ErrNotWritable
+    }
+
+    path := filepath.Join(dir, name)
+    if _, err := os.Lstat(path); err == nil {
+        return "", fmt.Errorf("%s already exists", name)
+    }
+    if _, err := b.client.CallValues("execute.throw", "", "mkdir", "--", path); err != nil {
+        return "", fmt.Errorf("error creating directory: %w", err)
+    }
+    return path, nil
+}
+
+// entry describes one directory item. Symlinks are followed and dropped when
+// their target is missing or outside the roots.
+func (b *Browser) entry(dir string, item os.DirEntry, uid int, gids []int) (Entry, bool) {
+    path := filepath.Join(dir, item.Name())
+    entry := Entry{
+        Name:    item.Name(),
+        Path:    path,
+        Symlink: item.Type()&os.ModeSymlink != 0,
+    }
+    if entry.Symlink {
+        target, err := filepath.EvalSymlinks(path)
+        if err != nil {
+            return entry, false
+        }
+        if _, ok := b.rootOf(target); !ok {
+            return entry, false
+        }
+    }
+
+    info, err := os.Stat(path)
+    if err != nil {
+        return entry, false
+    }
+    entry.IsDir = info.IsDir()
+    entry.Modified = info.ModTime()
+    if entry.IsDir {
+        entry.Writable = b.allowed(uid, gids, path, dirWritable)
+    } else {
+        entry.Size = info.Size()
+        entry.Writable = b.allowed(uid, gids, path, permission.PermWrite)
+    }
+    return entry, true
+}
+
+// rootOf returns the root containing a resolved path
+func (b *Browser) rootOf(resolved string) (string, bool) {
+    for _, root := range b.roots {
+        if utility.NewPathUtil(root).IsSubPath(resolved) {
+            return root, true
+        }
+    }
+    return "", false
+}
+
+func (b *Browser) allowed(uid int, gids []int, path string, flags int) bool {
+    ok, err := permission.DoesUserHave(uid, gids, path, flags)
+    return err == nil && ok
+}
+
+// identity returns the uid and groups rTorrent runs as. They are asked from
+// rTorrent once; while that fails the web server's own identity is used.
+func (b *Browser) identity() (int, []int) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    if !b.resolved {
+        uid, gids, err := b.queryIdentity()
+        if err != nil {
+            gids, _ := os.Getgroups()
+            return os.Getuid(), append(gids, os.Getgid())
+        }
+        b.uid, b.gids, b.resolved = uid, gids, true
+    }
+    return b.uid, b.gids
+}
+
+func (b *Browser) queryIdentity() (int, []int, error) {
+    out, err := b.client.CallValues("execute.capture", "", "id", "-u")
+    if err != nil {
+        return 0, nil, err
+    }
+    uid, err := strconv.Atoi(strings.TrimSpace(rtorrent.AsString(out)))
+    if err != nil {
+        return 0, nil, err
+    }
+    // Group lookups need the user in the local user database
+    var gids []int
+    if u, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+        gids, _ = permission.GetGroupIDs(u.Username)
+    }
+    return uid, gids, nil
+}
@@ -6,6 +6,7 @@ import (
     "fmt"
     "os"
     "path/filepath"
+    "strings"
     "sync"
 )
 
@@ -23,6 +24,9 @@ type Config struct {
         BaseURL      string `json:"base_url"`
         TempDir      string `json:"temp_dir"`
         DownloadDir  string `json:"download_dir"`
+        BrowseRoots  []string `json:"browse_roots"` // directories the file browser may show, download_dir if empty
+        WebDAVPath   string   `json:"webdav_path"`  // where completed torrents are served over WebDAV, disabled if empty
+        WebDAVRoots  []string `json:"webdav_roots"` // directories WebDAV may serve from, download_dir if empty
     } `json:"server"`
 
     Auth struct {
@@ -31,6 +35,58 @@ type Config struct {
         Username string `json:"username,omitempty"`
         Password string `json:"password,omitempty"`
     } `json:"auth"`
+
+    Permissions PermissionsConfig `json:"permissions"`
+    Watch       WatchConfig       `json:"watch"`
+    AutoMove    AutoMoveConfig    `json:"automove"`
+}
+
+// PermissionsConfig restricts what web users may do. Permissions are action
+// names such as "torrent.remove_data"; "torrent.*" denies every torrent
+// permission.
+type PermissionsConfig struct {
+    Deny []string `json:"deny,omitempty"`
+}
+
+// Allows reports whether a permission is not denied
+func (c PermissionsConfig) Allows(permission string) bool {
+    for _, denied := range c.Deny {
+        if denied == permission || denied == "*" {
+            return false
+        }
+        if strings.HasSuffix(denied, ".*") && strings.HasPrefix(permission, denied[:len(denied)-1]) {
+            return false
+        }
+    }
+    return true
+}
+
+// WatchConfig lists the directories polled for new .torrent and .magnet files
+type WatchConfig struct {
+    Interval int        `json:"interval"` // seconds between scans, 10 if unset
+    Dirs     []WatchDir `json:"dirs"`
+}
+
+// AutoMoveConfig moves the data of finished torrents. Template placeholders
+// are {label}, {name}, {hash}, {tracker}, {date}, {year}, {month} and {day};
+// dates are those of completion.
+type AutoMoveConfig struct {
+    Enabled   bool     `json:"enabled"`
+    Template  string   `json:"template"`            // new data path, "/media/{label}/{name}" for example
+    Labels    []string `json:"labels,omitempty"`    // only move torrents with these labels, any if empty
+    Unlabeled string   `json:"unlabeled,omitempty"` // {label} of torrents without one, "unlabeled" if empty
+    DryRun    bool     `json:"dry_run"`             // log where torrents would go instead of moving them
+}
+
+// WatchDir is one watched directory. Files in subfolders are labeled with
+// the subfolder path, "movies/hd" for <path>/movies/hd/x.torrent.
+type WatchDir struct {
+    Path         string            `json:"path"`
+    Label        string            `json:"label,omitempty"`     // label of files directly in path
+    Directory    string            `json:"directory,omitempty"` // download directory, rTorrent's default if empty
+    AppendFolder bool              `json:"append_folder"`       // download to directory/<subfolder>
+    Folders      map[string]string `json:"folders,omitempty"`   // subfolder to download directory, overrides the above
+    Start        bool              `json:"start"`
 }
 
 var defaultConfig = Config{
@@ -47,6 +103,9 @@ var defaultConfig = Config{
         BaseURL      string `json:"base_url"`
         TempDir      string `json:"temp_dir"`
         DownloadDir  string `json:"download_dir"`
+        BrowseRoots  []string `json:"browse_roots"`
+        WebDAVPath   string   `json:"webdav_path"`
+        WebDAVRoots  []string `json:"webdav_roots"`
     }{
         Port:        3000,
         Host:        "127.0.0.1", 
@@ -0,0 +1,335 @@
+// internal/davfs/davfs.go
+package davfs
+
+import (
+    "context"
+    "io"
+    "io/fs"
+    "os"
+    "path"
+    "path/filepath"
+    "sort"
+    "strings"
+    "time"
+
+    "golang.org/x/net/webdav"
+
+    "rutorrent-web/internal/browse"
+    "rutorrent-web/internal/services"
+    "rutorrent-web/internal/util/utility"
+)
+
+const (
+    // ByName holds every completed torrent under its name
+    ByName = "by-name"
+    // ByLabel holds a folder per label with that label's torrents
+    ByLabel = "by-label"
+    // NoLabel is the label folder of torrents without a label
+    NoLabel = "_unlabeled"
+)
+
+// FS is a read-only webdav.FileSystem over the data of completed torrents.
+// The top levels are virtual:
+//
+//	/by-name/<torrent>/...
+//	/by-label/<label>/<torrent>/...
+//
+// Below a torrent the real files are shown. Every path is resolved through
+// symlinks and must stay inside the torrent's data and the jail's roots;
+// torrents stored elsewhere aren't listed at all.
+type FS struct {
+    torrents *services.TorrentService
+    jail     *browse.Browser
+}
+
+// New creates a file system over the torrents of svc, confined to the roots
+// of jail
+func New(svc *services.TorrentService, jail *browse.Browser) *FS {
+    return &FS{torrents: svc, jail: jail}
+}
+
+// Mkdir implements webdav.FileSystem, the file system is read-only
+func (f *FS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+    return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrPermission}
+}
+
+// RemoveAll implements webdav.FileSystem, the file system is read-only
+func (f *FS) RemoveAll(ctx context.Context, name string) error {
+    return &os.PathError{Op: "remove", Path: name, Err: os.ErrPermission}
+}
+
+// Rename implements webdav.FileSystem, the file system is read-only
+func (f *FS) Rename(ctx context.Context, oldName, newName string) error {
+    return &os.PathError{Op: "rename", Path: oldName, Err: os.ErrPermission}
+}
+
+// Stat implements webdav.FileSystem
+func (f *FS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+    n, err := f.lookup(name)
+    if err != nil {
+        return nil, err
+    }
+    return n.stat()
+}
+
+// OpenFile implements webdav.FileSystem. Only opening for reading is
+// allowed.
+func (f *FS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+    if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+        return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrPermission}
+    }
+    n, err := f.lookup(name)
+    if err != nil {
+        return nil, err
+    }
+    if n.path == "" {
+        return &virtualDir{info: n.info, entries: n.entries}, nil
+    }
+    file, err := os.Open(n.path)
+    if err != nil {
+        return nil, err
+    }
+    return &realFile{File: file, name: n.info.Name()}, nil
+}
+
+// node is a looked up path: a virtual folder with its entries, or a real
+// file or directory
+type node struct {
+    info    os.FileInfo
+    entries []os.FileInfo // virtual folders only
+    path    string        // resolved location of real entries
+}
+
+func (n *node) stat() (os.FileInfo, error) {
+    if n.path == "" {
+        return n.info, nil
+    }
+    info, err := os.Stat(n.path)
+    if err != nil {
+        return nil, err
+    }
+    return renamed{info, n.info.Name()}, nil
+}
+
+// lookup resolves a slash separated path
+func (f *FS) lookup(name string) (*node, error) {
+    notFound := &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+
+    var parts []string
+    if p := strings.Trim(path.Clean("/"+name), "/"); p != "" {
+        parts = strings.Split(p, "/")
+    }
+    if len(parts) == 0 {
+        return &node{
+            info:    dirInfo("/"),
+            entries: []os.FileInfo{dirInfo(ByLabel), dirInfo(ByName)},
+        }, nil
+    }
+
+    torrents := f.index()
+    var folder map[string]*services.Torrent
+    var rest []string
+    switch parts[0] {
+    case ByName:
+        if len(parts) == 1 {
+            return f.folder(ByName, names(torrents)), nil
+        }
+        folder, rest = names(torrents), parts[1:]
+    case ByLabel:
+        labels := make(map[string][]*services.Torrent)
+        for _, t := range torrents {
+            labels[labelFolder(t.Label)] = append(labels[labelFolder(t.Label)], t)
+        }
+        if len(parts) == 1 {
+            entries := make([]os.FileInfo, 0, len(labels))
+            for label := range labels {
+                entries = append(entries, dirInfo(label))
+            }
+            return &node{info: dirInfo(ByLabel), entries: sortInfos(entries)}, nil
+        }
+        labelled, ok := labels[parts[1]]
+        if !ok {
+            return nil, notFound
+        }
+        if len(parts) == 2 {
+            return f.folder(parts[1], names(labelled)), nil
+        }
+        folder, rest = names(labelled), parts[2:]
+    default:
+        return nil, notFound
+    }
+
+    t, ok := folder[rest[0]]
+    if !ok {
+        return nil, notFound
+    }
+    base, err := f.data(t)
+    if err != nil {
+        return nil, notFound
+    }
+    if len(rest) == 1 {
+        return &node{info: dirInfo(rest[0]), path: base}, nil
+    }
+
+    // Inside a torrent: only multi-file torrents have content, and it has
+    // to stay below the torrent's folder
+    resolved, _, err := f.jail.Resolve(filepath.Join(append([]string{base}, rest[1:]...)...))
+    if err != nil || resolved == base || !utility.NewPathUtil(base).IsSubPath(resolved) {
+        return nil, notFound
+    }
+    return &node{info: dirInfo(rest[len(rest)-1]), path: resolved}, nil
+}
+
+// folder builds a virtual folder listing torrents by their display names
+func (f *FS) folder(name string, torrents map[string]*services.Torrent) *node {
+    entries := make([]os.FileInfo, 0, len(torrents))
+    for display, t := range torrents {
+        base, err := f.data(t)
+        if err != nil {
+            continue
+        }
+        info, err := os.Stat(base)
+        if err != nil {
+            continue
+        }
+        entries = append(entries, renamed{info, display})
+    }
+    return &node{info: dirInfo(name), entries: sortInfos(entries)}
+}
+
+// index returns the completed torrents, oldest first so display names stay
+// stable as torrents are added
+func (f *FS) index() []*services.Torrent {
+    var torrents []*services.Torrent
+    for _, t := range f.torrents.Snapshot() {
+        if t.Complete {
+            torrents = append(torrents, t)
+        }
+    }
+    sort.Slice(torrents, func(i, j int) bool {
+        if !torrents[i].AddedAt.Equal(torrents[j].AddedAt) {
+            return torrents[i].AddedAt.Before(torrents[j].AddedAt)
+        }
+        return torrents[i].Hash < torrents[j].Hash
+    })
+    return torrents
+}
+
+// data returns the resolved location of a torrent's data, the folder of
+// multi-file torrents and the file of single-file ones
+func (f *FS) data(t *services.Torrent) (string, error) {
+    p := t.BasePath
+    if p == "" {
+        if t.MultiFile {
+            p = t.Directory
+        } else {
+            p = filepath.Join(t.Directory, t.Name)
+        }
+    }
+    resolved, _, err := f.jail.Resolve(p)
+    return resolved, err
+}
+
+// names gives torrents unique file names. Torrents sharing a name after the
+// first get the start of their hash appended.
+func names(torrents []*services.Torrent) map[string]*services.Torrent {
+    m := make(map[string]*services.Torrent, len(torrents))
+    for _, t := range torrents {
+        name := clean(t.Name, t.Hash)
+        if _, taken := m[name]; taken {
+            name += " [" + t.Hash[:8] + "]"
+        }
+        m[name] = t
+    }
+    return m
+}
+
+func labelFolder(label string) string {
+    if label == "" {
+        return NoLabel
+    }
+    return clean(label, NoLabel)
+}
+
+// clean makes a torrent or label name usable as a single path element
+func clean(name, fallback string) string {
+    name = strings.Map(func(r rune) rune {
+        if r == '/' || r == '\\' || r < 0x20 {
+            return '_'
+        }
+        return r
+    }, name)
+    if name = strings.TrimSpace(name); name == "" || name == "." || name == ".." {
+        return fallback
+    }
+    return name
+}
+
+func sortInfos(infos []os.FileInfo) []os.FileInfo {
+    sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+    return infos
+}
+
+// renamed shows a real file under a display name
+type renamed struct {
+    os.FileInfo
+    name string
+}
+
+func (r renamed) Name() string { return r.name }
+
+// dirInfo describes a virtual folder
+type dirInfo string
+
+func (d dirInfo) Name() string       { return string(d) }
+func (d dirInfo) Size() int64        { return 0 }
+func (d dirInfo) Mode() os.FileMode  { return fs.ModeDir | 0555 }
+func (d dirInfo) ModTime() time.Time { return time.Time{} }
+func (d dirInfo) IsDir() bool        { return true }
+func (d dirInfo) Sys() interface{}   { return nil }
+
+// virtualDir is an open virtual folder
+type virtualDir struct {
+    info    os.FileInfo
+    entries []os.FileInfo
+    pos     int
+}
+
+func (d *virtualDir) Close() error                                 { return nil }
+func (d *virtualDir) Read(p []byte) (int, error)                   { return 0, fs.ErrInvalid }
+func (d *virtualDir) Seek(offset int64, whence int) (int64, error) { return 0, fs.ErrInvalid }
+func (d *virtualDir) Write(p []byte) (int, error)                  { return 0, fs.ErrPermission }
+func (d *virtualDir) Stat() (os.FileInfo, error)                   { return d.info, nil }
+
+// Readdir follows os.File.Readdir
+func (d *virtualDir) Readdir(count int) ([]os.FileInfo, error) {
+    rest := d.entries[d.pos:]
+    if count <= 0 {
+        d.pos = len(d.entries)
+        return rest, nil
+    }
+    if len(rest) == 0 {
+        return nil, io.EOF
+    }
+    if count > len(rest) {
+        count = len(rest)
+    }
+    d.pos += count
+    return rest[:count], nil
+}
+
+// realFile is an open file or directory on disk that can't be written
+type realFile struct {
+    *os.File
+    name string
+}
+
+func (f *realFile) Write(p []byte) (int, error) { return 0, fs.ErrPermission }
+
+func (f *realFile) Stat() (os.FileInfo, error) {
+    info, err := f.File.Stat()
+    if err != nil {
+        return nil, err
+    }
+    return renamed{info, f.name}, nil
+}
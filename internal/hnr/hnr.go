@@ -0,0 +1,196 @@
+// internal/hnr/hnr.go
+package hnr
+
+import (
+    "fmt"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+
+    "rutorrent-web/internal/services"
+    "rutorrent-web/pkg/fileutil"
+)
+
+// Policy is the seeding a private tracker requires before a torrent may be
+// removed. rTorrent doesn't count seeding time, so the time since the
+// torrent finished, or was added when it never downloaded, is used instead.
+type Policy struct {
+    Tracker     string  `json:"tracker"`       // registered domain, "example.org"
+    MinSeedTime int     `json:"min_seed_time"` // hours since the torrent finished
+    MinRatio    float64 `json:"min_ratio"`
+    AnyOf       bool    `json:"any_of"` // either requirement satisfies; both are needed otherwise
+    Grace       int     `json:"grace"`  // hours after adding in which an unfinished torrent isn't protected
+}
+
+// Obligation is what a torrent still owes its tracker
+type Obligation struct {
+    Protected bool          `json:"protected"`
+    Tracker   string        `json:"tracker,omitempty"`
+    SeedTime  time.Duration `json:"seed_time"` // remaining
+    Ratio     float64       `json:"ratio"`     // remaining
+    AnyOf     bool          `json:"any_of"`    // one of SeedTime and Ratio is enough
+}
+
+// String describes the remaining obligation, "12h or ratio 0.35" for
+// example; empty when the torrent isn't protected
+func (o Obligation) String() string {
+    if !o.Protected {
+        return ""
+    }
+    var parts []string
+    if o.SeedTime > 0 {
+        parts = append(parts, formatDuration(o.SeedTime))
+    }
+    if o.Ratio > 0 {
+        parts = append(parts, fmt.Sprintf("ratio %.2f", o.Ratio))
+    }
+    if o.AnyOf {
+        return strings.Join(parts, " or ")
+    }
+    return strings.Join(parts, " and ")
+}
+
+func formatDuration(d time.Duration) string {
+    hours := int(d.Round(time.Hour) / time.Hour)
+    if hours < 1 {
+        return "<1h"
+    }
+    if hours >= 48 {
+        return fmt.Sprintf("%dd %dh", hours/24, hours%24)
+    }
+    return fmt.Sprintf("%dh", hours)
+}
+
+// Store keeps the tracker policies shared by all users in hnr.json
+type Store struct {
+    path     string
+    policies []Policy
+    mu       sync.RWMutex
+}
+
+// NewStore opens the policies kept in dir
+func NewStore(dir string) (*Store, error) {
+    s := &Store{path: filepath.Join(dir, "hnr.json")}
+    if err := fileutil.ReadJSON(s.path, &s.policies); err != nil {
+        return nil, err
+    }
+    return s, nil
+}
+
+// Policies returns the tracker policies
+func (s *Store) Policies() []Policy {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    return append([]Policy{}, s.policies...)
+}
+
+// SetPolicies validates and stores the tracker policies
+func (s *Store) SetPolicies(policies []Policy) error {
+    seen := make(map[string]bool, len(policies))
+    for i := range policies {
+        p := &policies[i]
+        p.Tracker = strings.ToLower(strings.TrimSpace(p.Tracker))
+        if p.Tracker == "" {
+            return fmt.Errorf("policy %d has no tracker", i+1)
+        }
+        if seen[p.Tracker] {
+            return fmt.Errorf("duplicate policy for %s", p.Tracker)
+        }
+        seen[p.Tracker] = true
+        if p.MinSeedTime < 0 || p.MinRatio < 0 || p.Grace < 0 {
+            return fmt.Errorf("policy for %s: values can't be negative", p.Tracker)
+        }
+        if p.MinSeedTime == 0 && p.MinRatio == 0 {
+            return fmt.Errorf("policy for %s requires neither seed time nor ratio", p.Tracker)
+        }
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if err := fileutil.WriteJSON(s.path, policies); err != nil {
+        return err
+    }
+    s.policies = policies
+    return nil
+}
+
+// PolicyFor returns the policy of the first of a torrent's trackers that
+// has one, nil if none does
+func (s *Store) PolicyFor(t *services.Torrent) *Policy {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    for _, domain := range t.TrackerDomains() {
+        for i := range s.policies {
+            if s.policies[i].Tracker == domain {
+                p := s.policies[i]
+                return &p
+            }
+        }
+    }
+    return nil
+}
+
+// Obligation returns what a torrent still owes its tracker at now
+func (s *Store) Obligation(t *services.Torrent, now time.Time) Obligation {
+    p := s.PolicyFor(t)
+    if p == nil {
+        return Obligation{}
+    }
+    o := Obligation{Tracker: p.Tracker, AnyOf: p.AnyOf}
+
+    if !t.Complete {
+        if p.Grace > 0 && now.Sub(t.AddedAt) < time.Duration(p.Grace)*time.Hour {
+            return o
+        }
+        o.SeedTime = time.Duration(p.MinSeedTime) * time.Hour
+        o.Ratio = p.MinRatio - t.Ratio
+    } else {
+        // Data added already complete, fast-resumed for example, never
+        // gets d.timestamp.finished; it has seeded since it was added
+        since := t.FinishedAt
+        if since.IsZero() {
+            since = t.AddedAt
+        }
+        seeded := now.Sub(since)
+        if since.IsZero() || seeded < 0 {
+            seeded = 0
+        }
+        o.SeedTime = time.Duration(p.MinSeedTime)*time.Hour - seeded
+        o.Ratio = p.MinRatio - t.Ratio
+    }
+    if o.SeedTime < 0 || p.MinSeedTime == 0 {
+        o.SeedTime = 0
+    }
+    if o.Ratio < 0 || p.MinRatio == 0 {
+        o.Ratio = 0
+    }
+
+    timeDone := p.MinSeedTime == 0 || o.SeedTime == 0
+    ratioDone := p.MinRatio == 0 || o.Ratio == 0
+    if p.AnyOf {
+        // A requirement that isn't set can't satisfy the policy on its own
+        timeDone = p.MinSeedTime > 0 && o.SeedTime == 0
+        ratioDone = p.MinRatio > 0 && o.Ratio == 0
+        o.Protected = !timeDone && !ratioDone
+    } else {
+        o.Protected = !timeDone || !ratioDone
+    }
+    return o
+}
+
+// Guard refuses to remove protected torrents. It is meant for
+// services.TorrentService.SetRemoveGuard.
+func (s *Store) Guard(t *services.Torrent, deleteData bool) error {
+    o := s.Obligation(t, time.Now())
+    if !o.Protected {
+        return nil
+    }
+    return &services.ProtectedError{
+        Hash:   t.Hash,
+        Name:   t.Name,
+        Reason: fmt.Sprintf("%s still requires %s", o.Tracker, o),
+    }
+}
@@ -0,0 +1,216 @@
+// internal/labels/labels.go
+package labels
+
+import (
+    "fmt"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+
+    "rutorrent-web/internal/services"
+    "rutorrent-web/pkg/fileutil"
+)
+
+// colorPattern accepts CSS hex colors
+var colorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// iconPattern accepts icon names as used by the templates
+var iconPattern = regexp.MustCompile(`^[a-z0-9-]{1,40}$`)
+
+// Style is the color and icon shown for a label
+type Style struct {
+    Color string `json:"color,omitempty"`
+    Icon  string `json:"icon,omitempty"`
+}
+
+// Node is a label in the hierarchy with totals over its own torrents and
+// every nested label
+type Node struct {
+    Name     string  `json:"name"` // last level, "tv/anime" -> "anime"
+    Path     string  `json:"path"` // full label
+    Style    Style   `json:"style"`
+    Count    int     `json:"count"`
+    Own      int     `json:"own"` // torrents labelled exactly Path
+    Size     int64   `json:"size"`
+    DownRate int64   `json:"down_rate"`
+    UpRate   int64   `json:"up_rate"`
+    Children []*Node `json:"children,omitempty"`
+}
+
+// Store keeps label styles per user. Styles follow labels through renames
+// and merges made with the store's methods.
+type Store struct {
+    path   func(user string) string
+    styles map[string]map[string]Style // styles by user and label
+    mu     sync.Mutex
+}
+
+// NewStore creates a store that keeps label styles in labels.json of each
+// user's settings directory
+func NewStore() *Store {
+    return &Store{
+        path: func(user string) string {
+            return filepath.Join(fileutil.GetSettingsPathEx(user), "labels.json")
+        },
+        styles: make(map[string]map[string]Style),
+    }
+}
+
+// Styles returns the label styles of a user
+func (s *Store) Styles(user string) (map[string]Style, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.load(user)
+}
+
+// SetStyle sets the color and icon of a label. An empty style removes it.
+func (s *Store) SetStyle(user, label string, style Style) error {
+    label = services.NormalizeLabel(label)
+    if label == "" {
+        return fmt.Errorf("label name must not be empty")
+    }
+    if style.Color != "" && !colorPattern.MatchString(style.Color) {
+        return fmt.Errorf("invalid color: %s", style.Color)
+    }
+    if style.Icon != "" && !iconPattern.MatchString(style.Icon) {
+        return fmt.Errorf("invalid icon: %s", style.Icon)
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    styles, err := s.load(user)
+    if err != nil {
+        return err
+    }
+    if style == (Style{}) {
+        delete(styles, label)
+    } else {
+        styles[label] = style
+    }
+    return s.store(user, styles)
+}
+
+// Move carries the styles of a label and its nested labels over to a new
+// name after a rename or merge. Existing styles of the target win.
+func (s *Store) Move(user, from, to string) error {
+    from, to = services.NormalizeLabel(from), services.NormalizeLabel(to)
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    styles, err := s.load(user)
+    if err != nil {
+        return err
+    }
+    for label, style := range styles {
+        if !services.LabelWithin(label, from) {
+            continue
+        }
+        delete(styles, label)
+        moved := to + label[len(from):]
+        if _, ok := styles[moved]; !ok {
+            styles[moved] = style
+        }
+    }
+    return s.store(user, styles)
+}
+
+// Forget removes the styles of a label and its nested labels
+func (s *Store) Forget(user, label string) error {
+    label = services.NormalizeLabel(label)
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    styles, err := s.load(user)
+    if err != nil {
+        return err
+    }
+    for l := range styles {
+        if services.LabelWithin(l, label) {
+            delete(styles, l)
+        }
+    }
+    return s.store(user, styles)
+}
+
+// load returns a copy of the user's styles. Callers hold s.mu.
+func (s *Store) load(user string) (map[string]Style, error) {
+    styles, ok := s.styles[user]
+    if !ok {
+        if err := fileutil.ReadJSON(s.path(user), &styles); err != nil {
+            return nil, err
+        }
+        s.styles[user] = styles
+    }
+
+    copied := make(map[string]Style, len(styles))
+    for k, v := range styles {
+        copied[k] = v
+    }
+    return copied, nil
+}
+
+// store writes the user's styles. Callers hold s.mu.
+func (s *Store) store(user string, styles map[string]Style) error {
+    if err := fileutil.WriteJSON(s.path(user), styles); err != nil {
+        return err
+    }
+    s.styles[user] = styles
+    return nil
+}
+
+// Tree builds the label hierarchy of the torrent snapshot. Nodes are sorted
+// by name; counts, sizes and speeds of a node include its nested labels.
+// Unlabelled torrents aren't part of the tree.
+func Tree(torrents []*services.Torrent, styles map[string]Style) []*Node {
+    nodes := make(map[string]*Node)
+    var roots []*Node
+
+    var node func(path string) *Node
+    node = func(path string) *Node {
+        if n, ok := nodes[path]; ok {
+            return n
+        }
+        n := &Node{Path: path, Name: path, Style: styles[path]}
+        nodes[path] = n
+        if parent := services.LabelParent(path); parent != "" {
+            n.Name = path[len(parent)+1:]
+            p := node(parent)
+            p.Children = append(p.Children, n)
+        } else {
+            roots = append(roots, n)
+        }
+        return n
+    }
+
+    for _, t := range torrents {
+        label := services.NormalizeLabel(t.Label)
+        if label == "" {
+            continue
+        }
+        node(label).Own++
+        for path := label; path != ""; path = services.LabelParent(path) {
+            n := node(path)
+            n.Count++
+            n.Size += t.Size
+            n.DownRate += t.DownloadRate
+            n.UpRate += t.UploadRate
+        }
+    }
+
+    sortNodes(roots)
+    return roots
+}
+
+func sortNodes(nodes []*Node) {
+    sort.Slice(nodes, func(i, j int) bool {
+        return strings.ToLower(nodes[i].Name) < strings.ToLower(nodes[j].Name)
+    })
+    for _, n := range nodes {
+        sortNodes(n.Children)
+    }
+}
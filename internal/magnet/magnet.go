@@ -0,0 +1,336 @@
+// internal/magnet/magnet.go
+package magnet
+
+import (
+    "encoding/base32"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "net"
+    "net/url"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// maxSelected bounds the file indices a so= parameter can expand to
+const maxSelected = 100000
+
+var (
+    // ErrNotMagnet is returned for URIs that aren't magnet links
+    ErrNotMagnet = errors.New("not a magnet link")
+    // ErrNoInfoHash is returned for magnet links without a BitTorrent
+    // exact topic (xt=urn:btih: or xt=urn:btmh:)
+    ErrNoInfoHash = errors.New("magnet link has no BitTorrent info hash")
+)
+
+// Magnet is a parsed BitTorrent magnet link (BEP 9, BEP 53, BEP 52 btmh)
+type Magnet struct {
+    InfoHash   string   // v1 info hash, upper-case hex; empty for v2-only links
+    InfoHashV2 string   // v2 info hash (SHA-256), lower-case hex
+    Name       string   // dn
+    Length     int64    // xl, 0 if absent
+    Trackers   []string // tr, in order, without duplicates
+    WebSeeds   []string // ws
+    Peers      []string // x.pe, host:port
+    SelectOnly []int    // so, sorted file indices; nil selects everything
+}
+
+// Error is a problem with one parameter of a magnet link
+type Error struct {
+    Param string
+    Value string
+    Err   string
+}
+
+func (e *Error) Error() string {
+    return fmt.Sprintf("invalid magnet %s %q: %s", e.Param, e.Value, e.Err)
+}
+
+// Parse parses and validates a magnet link. Indexed parameters such as
+// xt.1 and tr.2 are accepted. A link may carry a v1 hash, a v2 hash or
+// both (hybrid torrents), but not two different hashes of the same kind.
+func Parse(uri string) (*Magnet, error) {
+    uri = strings.TrimSpace(uri)
+    if len(uri) < 8 || !strings.EqualFold(uri[:8], "magnet:?") {
+        return nil, ErrNotMagnet
+    }
+    params, err := url.ParseQuery(uri[8:])
+    if err != nil {
+        return nil, fmt.Errorf("malformed magnet link: %w", err)
+    }
+
+    m := &Magnet{}
+    // Sorted so errors and repeated values come out the same every time,
+    // indexed keys by their number after the bare key: tr, tr.1, tr.2, tr.10
+    keys := make([]string, 0, len(params))
+    for key := range params {
+        keys = append(keys, key)
+    }
+    sort.Slice(keys, func(i, j int) bool {
+        bi, ni := splitKey(keys[i])
+        bj, nj := splitKey(keys[j])
+        if bi != bj {
+            return bi < bj
+        }
+        if ni != nj {
+            return ni < nj
+        }
+        return keys[i] < keys[j]
+    })
+
+    for _, key := range keys {
+        for _, value := range params[key] {
+            if err := m.set(baseKey(key), key, value); err != nil {
+                return nil, err
+            }
+        }
+    }
+    if m.InfoHash == "" && m.InfoHashV2 == "" {
+        return nil, ErrNoInfoHash
+    }
+    return m, nil
+}
+
+// set applies one parameter
+func (m *Magnet) set(param, key, value string) error {
+    invalid := func(format string, args ...interface{}) error {
+        return &Error{Param: key, Value: value, Err: fmt.Sprintf(format, args...)}
+    }
+
+    switch param {
+    case "xt":
+        lower := strings.ToLower(value)
+        switch {
+        case strings.HasPrefix(lower, "urn:btih:"):
+            hash, err := parseV1(value[len("urn:btih:"):])
+            if err != nil {
+                return invalid("%v", err)
+            }
+            if m.InfoHash != "" && m.InfoHash != hash {
+                return invalid("conflicts with info hash %s", m.InfoHash)
+            }
+            m.InfoHash = hash
+        case strings.HasPrefix(lower, "urn:btmh:"):
+            hash, err := parseV2(value[len("urn:btmh:"):])
+            if err != nil {
+                return invalid("%v", err)
+            }
+            if m.InfoHashV2 != "" && m.InfoHashV2 != hash {
+                return invalid("conflicts with v2 info hash %s", m.InfoHashV2)
+            }
+            m.InfoHashV2 = hash
+        }
+        // Other exact topics (ed2k, sha1, ...) belong to other networks
+    case "dn":
+        m.Name = strings.TrimSpace(value)
+    case "xl":
+        n, err := strconv.ParseInt(value, 10, 64)
+        if err != nil || n < 0 {
+            return invalid("not a length")
+        }
+        m.Length = n
+    case "tr":
+        if err := checkURL(value, "http", "https", "udp", "ws", "wss"); err != nil {
+            return invalid("%v", err)
+        }
+        m.Trackers = appendUnique(m.Trackers, value)
+    case "ws":
+        if err := checkURL(value, "http", "https"); err != nil {
+            return invalid("%v", err)
+        }
+        m.WebSeeds = appendUnique(m.WebSeeds, value)
+    case "x.pe":
+        host, port, err := net.SplitHostPort(value)
+        if err != nil || host == "" {
+            return invalid("not a host:port peer address")
+        }
+        if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+            return invalid("invalid port")
+        }
+        m.Peers = appendUnique(m.Peers, value)
+    case "so":
+        indices, err := parseSelect(value)
+        if err != nil {
+            return invalid("%v", err)
+        }
+        merged := mergeIndices(m.SelectOnly, indices)
+        if len(merged) > maxSelected {
+            return invalid("selects more than %d files", maxSelected)
+        }
+        m.SelectOnly = merged
+    }
+    return nil
+}
+
+// String formats the link with its parameters in a fixed order
+func (m *Magnet) String() string {
+    var parts []string
+    if m.InfoHash != "" {
+        parts = append(parts, "xt=urn:btih:"+m.InfoHash)
+    }
+    if m.InfoHashV2 != "" {
+        parts = append(parts, "xt=urn:btmh:1220"+m.InfoHashV2)
+    }
+    if m.Name != "" {
+        parts = append(parts, "dn="+url.QueryEscape(m.Name))
+    }
+    if m.Length > 0 {
+        parts = append(parts, "xl="+strconv.FormatInt(m.Length, 10))
+    }
+    for _, tr := range m.Trackers {
+        parts = append(parts, "tr="+url.QueryEscape(tr))
+    }
+    for _, ws := range m.WebSeeds {
+        parts = append(parts, "ws="+url.QueryEscape(ws))
+    }
+    for _, pe := range m.Peers {
+        parts = append(parts, "x.pe="+url.QueryEscape(pe))
+    }
+    if len(m.SelectOnly) > 0 {
+        parts = append(parts, "so="+formatSelect(m.SelectOnly))
+    }
+    return "magnet:?" + strings.Join(parts, "&")
+}
+
+// Selected reports whether so= selects file index i
+func (m *Magnet) Selected(i int) bool {
+    if m.SelectOnly == nil {
+        return true
+    }
+    n := sort.SearchInts(m.SelectOnly, i)
+    return n < len(m.SelectOnly) && m.SelectOnly[n] == i
+}
+
+// baseKey strips the index of parameters like xt.1; x.pe is kept whole
+func baseKey(key string) string {
+    base, _ := splitKey(key)
+    return base
+}
+
+// splitKey splits a parameter like tr.2 into its lower-case base and
+// index; the index is -1 for a bare key
+func splitKey(key string) (string, int) {
+    if i := strings.LastIndexByte(key, '.'); i > 0 {
+        if n, err := strconv.Atoi(key[i+1:]); err == nil && n >= 0 {
+            return strings.ToLower(key[:i]), n
+        }
+    }
+    return strings.ToLower(key), -1
+}
+
+// parseV1 parses a v1 info hash given as 40 hex or 32 base32 characters
+func parseV1(s string) (string, error) {
+    switch len(s) {
+    case 40:
+        b, err := hex.DecodeString(s)
+        if err != nil {
+            return "", errors.New("info hash is not valid hex")
+        }
+        return strings.ToUpper(hex.EncodeToString(b)), nil
+    case 32:
+        b, err := base32.StdEncoding.DecodeString(strings.ToUpper(s))
+        if err != nil {
+            return "", errors.New("info hash is not valid base32")
+        }
+        return strings.ToUpper(hex.EncodeToString(b)), nil
+    }
+    return "", fmt.Errorf("info hash must be 40 hex or 32 base32 characters, got %d", len(s))
+}
+
+// parseV2 parses a v2 info hash: a hex multihash of a SHA-256 digest,
+// code 0x12 and length 0x20
+func parseV2(s string) (string, error) {
+    b, err := hex.DecodeString(s)
+    if err != nil {
+        return "", errors.New("multihash is not valid hex")
+    }
+    if len(b) != 34 || b[0] != 0x12 || b[1] != 0x20 {
+        return "", errors.New("multihash is not a 32 byte SHA-256 digest")
+    }
+    return hex.EncodeToString(b[2:]), nil
+}
+
+// parseSelect expands a so= list such as "0,2,4-6" into sorted indices
+func parseSelect(s string) ([]int, error) {
+    var indices []int
+    for _, part := range strings.Split(s, ",") {
+        from, to, isRange := strings.Cut(part, "-")
+        first, err := strconv.Atoi(from)
+        if err != nil || first < 0 {
+            return nil, fmt.Errorf("%q is not a file index", part)
+        }
+        last := first
+        if isRange {
+            if last, err = strconv.Atoi(to); err != nil || last < first {
+                return nil, fmt.Errorf("%q is not a file index range", part)
+            }
+        }
+        // Checked before adding so huge ranges can't overflow
+        if last-first >= maxSelected-len(indices) {
+            return nil, fmt.Errorf("selects more than %d files", maxSelected)
+        }
+        // Counted so last can be the largest int without i overflowing
+        for n := 0; n <= last-first; n++ {
+            indices = append(indices, first+n)
+        }
+    }
+    return mergeIndices(nil, indices), nil
+}
+
+// formatSelect compresses sorted indices back into ranges
+func formatSelect(indices []int) string {
+    var parts []string
+    for i := 0; i < len(indices); {
+        j := i
+        for j+1 < len(indices) && indices[j+1] == indices[j]+1 {
+            j++
+        }
+        if j == i {
+            parts = append(parts, strconv.Itoa(indices[i]))
+        } else {
+            parts = append(parts, strconv.Itoa(indices[i])+"-"+strconv.Itoa(indices[j]))
+        }
+        i = j + 1
+    }
+    return strings.Join(parts, ",")
+}
+
+// mergeIndices returns the sorted union of two index lists
+func mergeIndices(a, b []int) []int {
+    all := append(append([]int(nil), a...), b...)
+    sort.Ints(all)
+    out := all[:0]
+    for i, n := range all {
+        if i == 0 || n != all[i-1] {
+            out = append(out, n)
+        }
+    }
+    return out
+}
+
+// checkURL checks that s is an absolute URL with one of the schemes
+func checkURL(s string, schemes ...string) error {
+    u, err := url.Parse(s)
+    if err != nil {
+        return errors.New("not a URL")
+    }
+    if u.Host == "" {
+        return errors.New("URL has no host")
+    }
+    for _, scheme := range schemes {
+        if strings.EqualFold(u.Scheme, scheme) {
+            return nil
+        }
+    }
+    return fmt.Errorf("unsupported scheme %q", u.Scheme)
+}
+
+func appendUnique(list []string, v string) []string {
+    for _, s := range list {
+        if s == v {
+            return list
+        }
+    }
+    return append(list, v)
+}
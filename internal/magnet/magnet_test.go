@@ -0,0 +1,124 @@
+// internal/magnet/magnet_test.go
+package magnet
+
+import (
+    "reflect"
+    "testing"
+)
+
+const testHash = "c12fe1c06bba254a9dc9f519b335aa7c1367a88a"
+
+func TestParseSelect(t *testing.T) {
+    tests := []struct {
+        name    string
+        so      string
+        want    []int
+        wantErr bool
+    }{
+        {name: "single", so: "3", want: []int{3}},
+        {name: "list and range", so: "0,2,4-6", want: []int{0, 2, 4, 5, 6}},
+        {name: "overlapping", so: "4-6,5,1", want: []int{1, 4, 5, 6}},
+        {name: "at the limit", so: "0-99999", want: nil},
+        {name: "over the limit", so: "0-100000", wantErr: true},
+        {name: "limit across parts", so: "0-50000,60000-110000", wantErr: true},
+        {name: "range to max int", so: "0-9223372036854775807", wantErr: true},
+        {name: "range near max int", so: "9223372036854775806-9223372036854775807", want: []int{9223372036854775806, 9223372036854775807}},
+        {name: "reversed range", so: "5-2", wantErr: true},
+        {name: "negative", so: "-1", wantErr: true},
+        {name: "not a number", so: "a", wantErr: true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := parseSelect(tt.so)
+            if tt.wantErr {
+                if err == nil {
+                    t.Fatalf("parseSelect(%q) = %d indices, want an error", tt.so, len(got))
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("parseSelect(%q): %v", tt.so, err)
+            }
+            if tt.want == nil {
+                return
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("parseSelect(%q) = %v, want %v", tt.so, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestParseSelectOnly(t *testing.T) {
+    tests := []struct {
+        name    string
+        uri     string
+        want    []int
+        wantErr bool
+    }{
+        {name: "so", uri: "magnet:?xt=urn:btih:" + testHash + "&so=0,2-3", want: []int{0, 2, 3}},
+        {name: "indexed so merged", uri: "magnet:?xt=urn:btih:" + testHash + "&so.1=4&so.2=1", want: []int{1, 4}},
+        {name: "limit across parameters", uri: "magnet:?xt=urn:btih:" + testHash + "&so.1=0-60000&so.2=60001-120000", wantErr: true},
+        {name: "huge range", uri: "magnet:?xt=urn:btih:" + testHash + "&so=0-9223372036854775807", wantErr: true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            m, err := Parse(tt.uri)
+            if tt.wantErr {
+                if err == nil {
+                    t.Fatalf("Parse(%q) succeeded, want an error", tt.uri)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("Parse(%q): %v", tt.uri, err)
+            }
+            if !reflect.DeepEqual(m.SelectOnly, tt.want) {
+                t.Errorf("SelectOnly = %v, want %v", m.SelectOnly, tt.want)
+            }
+        })
+    }
+}
+
+func TestParseTrackerOrder(t *testing.T) {
+    tests := []struct {
+        name string
+        uri  string
+        want []string
+    }{
+        {
+            name: "repeated tr",
+            uri:  "magnet:?xt=urn:btih:" + testHash + "&tr=udp://a.example:1&tr=udp://b.example:1",
+            want: []string{"udp://a.example:1", "udp://b.example:1"},
+        },
+        {
+            name: "indexed tr numerically",
+            uri: "magnet:?xt=urn:btih:" + testHash +
+                "&tr.10=udp://j.example:1&tr.2=udp://b.example:1&tr.1=udp://a.example:1",
+            want: []string{"udp://a.example:1", "udp://b.example:1", "udp://j.example:1"},
+        },
+        {
+            name: "bare tr first",
+            uri: "magnet:?xt=urn:btih:" + testHash +
+                "&tr.1=udp://b.example:1&tr=udp://a.example:1&tr.0=udp://c.example:1",
+            want: []string{"udp://a.example:1", "udp://c.example:1", "udp://b.example:1"},
+        },
+        {
+            name: "duplicates keep the first position",
+            uri: "magnet:?xt=urn:btih:" + testHash +
+                "&tr.2=udp://a.example:1&tr.1=udp://b.example:1&tr.3=udp://b.example:1",
+            want: []string{"udp://b.example:1", "udp://a.example:1"},
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            m, err := Parse(tt.uri)
+            if err != nil {
+                t.Fatalf("Parse(%q): %v", tt.uri, err)
+            }
+            if !reflect.DeepEqual(m.Trackers, tt.want) {
+                t.Errorf("Trackers = %v, want %v", m.Trackers, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,335 @@
+// internal/ratio/ratio.go
+package ratio
+
+import (
+    "errors"
+    "fmt"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+
+    "rutorrent-web/internal/services"
+    "rutorrent-web/pkg/fileutil"
+)
+
+const (
+    // MaxGroups is the number of ratio groups that can be defined
+    MaxGroups = 8
+    // DefaultInterval is checkTimesInterval when none is set, in minutes
+    DefaultInterval = 5
+    // maxLog bounds the actions kept in the log
+    maxLog = 500
+    // groupKey is the d.custom key of explicit group assignments
+    groupKey = "ratiogroup"
+)
+
+// Action is what happens to a torrent that reached its group's limits
+type Action string
+
+const (
+    ActionStop       Action = "stop"
+    ActionRemove     Action = "remove"
+    ActionRemoveData Action = "remove_data"
+    ActionMoveGroup  Action = "move_group" // Target is the new group
+    ActionThrottle   Action = "throttle"   // Target is the throttle channel
+)
+
+// ErrNoGroup is returned when assigning torrents to an unknown group
+var ErrNoGroup = errors.New("no such ratio group")
+
+// Group is a named set of seeding limits. A torrent reaches them once its
+// ratio is at least MaxRatio, or at least MinRatio with Upload bytes
+// uploaded, or once it has seeded TimeLimit hours. Zero values disable a
+// limit.
+type Group struct {
+    Name      string   `json:"name"`
+    MinRatio  float64  `json:"min_ratio"`
+    MaxRatio  float64  `json:"max_ratio"`
+    Upload    int64    `json:"upload"`     // bytes uploaded, together with MinRatio
+    TimeLimit int      `json:"time_limit"` // hours since the torrent finished
+    Action    Action   `json:"action"`
+    Target    string   `json:"target,omitempty"`
+    Labels    []string `json:"labels,omitempty"` // torrents with these labels belong to the group unless assigned elsewhere
+}
+
+// Reached tells which limit a torrent has reached, empty if none
+func (g *Group) Reached(t *services.Torrent, now time.Time) string {
+    switch {
+    case g.MaxRatio > 0 && t.Ratio >= g.MaxRatio:
+        return fmt.Sprintf("ratio %.2f reached the maximum %.2f", t.Ratio, g.MaxRatio)
+    case g.MinRatio > 0 && t.Ratio >= g.MinRatio && t.UpTotal >= g.Upload:
+        return fmt.Sprintf("ratio %.2f reached the minimum %.2f with %d bytes uploaded", t.Ratio, g.MinRatio, t.UpTotal)
+    case g.TimeLimit > 0 && !t.FinishedAt.IsZero() && now.Sub(t.FinishedAt) >= time.Duration(g.TimeLimit)*time.Hour:
+        return fmt.Sprintf("seeded for %d hours", g.TimeLimit)
+    }
+    return ""
+}
+
+// Settings are the ratio groups and how often they are checked
+type Settings struct {
+    Interval int     `json:"interval"` // checkTimesInterval, minutes
+    Groups   []Group `json:"groups"`
+}
+
+// Entry is an action taken on a torrent
+type Entry struct {
+    Time   time.Time `json:"time"`
+    Hash   string    `json:"hash"`
+    Name   string    `json:"name"`
+    Group  string    `json:"group"`
+    Action Action    `json:"action"`
+    Target string    `json:"target,omitempty"`
+    Reason string    `json:"reason"`
+    Error  string    `json:"error,omitempty"`
+}
+
+// Manager keeps the ratio groups in ratio.json and applies them
+type Manager struct {
+    torrents *services.TorrentService
+    path     string
+    settings Settings
+    log      []Entry
+    stop     chan struct{}
+    once     sync.Once
+    mu       sync.Mutex
+}
+
+// NewManager loads the ratio groups kept in dir
+func NewManager(torrents *services.TorrentService, dir string) (*Manager, error) {
+    m := &Manager{
+        torrents: torrents,
+        path:     filepath.Join(dir, "ratio.json"),
+        stop:     make(chan struct{}),
+    }
+    if err := fileutil.ReadJSON(m.path, &m.settings); err != nil {
+        return nil, err
+    }
+    return m, nil
+}
+
+// Settings returns the groups and check interval
+func (m *Manager) Settings() Settings {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    s := m.settings
+    s.Groups = append([]Group(nil), m.settings.Groups...)
+    if s.Interval <= 0 {
+        s.Interval = DefaultInterval
+    }
+    return s
+}
+
+// SetSettings validates and stores new settings. Torrents assigned to a
+// group that no longer exists fall back to their label's group.
+func (m *Manager) SetSettings(s Settings) error {
+    if err := validate(&s); err != nil {
+        return err
+    }
+
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    if err := fileutil.WriteJSON(m.path, s); err != nil {
+        return err
+    }
+    m.settings = s
+    return nil
+}
+
+// validate checks settings and normalizes group names and labels
+func validate(s *Settings) error {
+    if s.Interval < 0 || s.Interval > 24*60 {
+        return fmt.Errorf("check interval must be between 1 and %d minutes", 24*60)
+    }
+    if len(s.Groups) > MaxGroups {
+        return fmt.Errorf("too many ratio groups (maximum %d)", MaxGroups)
+    }
+
+    names := make(map[string]bool, len(s.Groups))
+    for i := range s.Groups {
+        g := &s.Groups[i]
+        g.Name = strings.TrimSpace(g.Name)
+        if g.Name == "" {
+            return fmt.Errorf("ratio group %d has no name", i+1)
+        }
+        if names[strings.ToLower(g.Name)] {
+            return fmt.Errorf("duplicate ratio group %q", g.Name)
+        }
+        names[strings.ToLower(g.Name)] = true
+        if g.MinRatio < 0 || g.MaxRatio < 0 || g.Upload < 0 || g.TimeLimit < 0 {
+            return fmt.Errorf("ratio group %q: limits can't be negative", g.Name)
+        }
+        if g.MaxRatio > 0 && g.MinRatio > g.MaxRatio {
+            return fmt.Errorf("ratio group %q: minimum ratio is above the maximum", g.Name)
+        }
+        if g.MinRatio == 0 && g.MaxRatio == 0 && g.TimeLimit == 0 {
+            return fmt.Errorf("ratio group %q has no limits", g.Name)
+        }
+        for j, label := range g.Labels {
+            g.Labels[j] = services.NormalizeLabel(label)
+        }
+
+        switch g.Action {
+        case ActionStop, ActionRemove, ActionRemoveData:
+            g.Target = ""
+        case ActionThrottle:
+            if g.Target == "" {
+                return fmt.Errorf("ratio group %q: no throttle channel", g.Name)
+            }
+        case ActionMoveGroup:
+        default:
+            return fmt.Errorf("ratio group %q: unknown action %q", g.Name, g.Action)
+        }
+    }
+    for _, g := range s.Groups {
+        if g.Action == ActionMoveGroup && (!names[strings.ToLower(g.Target)] || strings.EqualFold(g.Target, g.Name)) {
+            return fmt.Errorf("ratio group %q: %q is not another ratio group", g.Name, g.Target)
+        }
+    }
+    return nil
+}
+
+// Assign puts torrents in a group, or back on their label's group when
+// group is empty
+func (m *Manager) Assign(hashes []string, group string) error {
+    if group != "" {
+        g := m.group(m.Settings(), group)
+        if g == nil {
+            return ErrNoGroup
+        }
+        group = g.Name
+    }
+    return m.torrents.SetCustom(hashes, groupKey, group)
+}
+
+// GroupOf returns the group a torrent belongs to, nil if none
+func (m *Manager) GroupOf(t *services.Torrent) *Group {
+    return m.groupOf(m.Settings(), t)
+}
+
+func (m *Manager) groupOf(s Settings, t *services.Torrent) *Group {
+    if t.RatioGroup != "" {
+        if g := m.group(s, t.RatioGroup); g != nil {
+            return g
+        }
+    }
+    for i := range s.Groups {
+        for _, label := range s.Groups[i].Labels {
+            if label != "" && label == t.Label {
+                return &s.Groups[i]
+            }
+        }
+    }
+    return nil
+}
+
+func (m *Manager) group(s Settings, name string) *Group {
+    for i := range s.Groups {
+        if strings.EqualFold(s.Groups[i].Name, name) {
+            return &s.Groups[i]
+        }
+    }
+    return nil
+}
+
+// Start checks the groups every Interval minutes until Stop is called
+func (m *Manager) Start() {
+    go func() {
+        for {
+            timer := time.NewTimer(time.Duration(m.Settings().Interval) * time.Minute)
+            select {
+            case <-timer.C:
+                m.Check()
+            case <-m.stop:
+                timer.Stop()
+                return
+            }
+        }
+    }()
+}
+
+// Stop ends the scheduled checks
+func (m *Manager) Stop() {
+    m.once.Do(func() { close(m.stop) })
+}
+
+// Check applies the action of every complete torrent that reached its
+// group's limits. Stopped torrents are left alone so that stopping is
+// taken once.
+func (m *Manager) Check() []Entry {
+    s := m.Settings()
+    now := time.Now()
+
+    var entries []Entry
+    for _, t := range m.torrents.Snapshot() {
+        if !t.Complete || t.State == 0 {
+            continue
+        }
+        g := m.groupOf(s, t)
+        if g == nil {
+            continue
+        }
+        reason := g.Reached(t, now)
+        if reason == "" {
+            continue
+        }
+        if g.Action == ActionThrottle && t.Throttle == g.Target {
+            continue
+        }
+
+        e := Entry{Time: now, Hash: t.Hash, Name: t.Name, Group: g.Name, Action: g.Action, Target: g.Target, Reason: reason}
+        if err := m.act(t, g); err != nil {
+            e.Error = err.Error()
+            services.Warnf("ratio: %s (%s) failed on %s: %v", g.Action, g.Name, t.Name, err)
+        } else {
+            services.Infof("ratio: %s (%s) on %s: %s", g.Action, g.Name, t.Name, reason)
+        }
+        entries = append(entries, e)
+    }
+    m.record(entries)
+    return entries
+}
+
+func (m *Manager) act(t *services.Torrent, g *Group) error {
+    hashes := []string{t.Hash}
+    switch g.Action {
+    case ActionStop:
+        return m.torrents.StopTorrents(hashes)
+    case ActionRemove:
+        return m.torrents.EraseTorrents(hashes, false)
+    case ActionRemoveData:
+        return m.torrents.EraseTorrents(hashes, true)
+    case ActionMoveGroup:
+        return m.torrents.SetCustom(hashes, groupKey, g.Target)
+    case ActionThrottle:
+        return m.torrents.SetThrottle(hashes, g.Target)
+    }
+    return fmt.Errorf("unknown action %q", g.Action)
+}
+
+func (m *Manager) record(entries []Entry) {
+    if len(entries) == 0 {
+        return
+    }
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    m.log = append(m.log, entries...)
+    if len(m.log) > maxLog {
+        m.log = m.log[len(m.log)-maxLog:]
+    }
+}
+
+// Log returns the actions taken, newest first
+func (m *Manager) Log() []Entry {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    entries := make([]Entry, len(m.log))
+    for i, e := range m.log {
+        entries[len(m.log)-1-i] = e
+    }
+    return entries
+}
@@ -0,0 +1,456 @@
+// internal/rtorrent/values.go
+
+package rtorrent
+
+import (
+    "bytes"
+    "encoding/base64"
+    "encoding/xml"
+    "fmt"
+    "io"
+    "net/http"
+    "strconv"
+    "strings"
+)
+
+// Fault is returned when rTorrent answers a call with an XML-RPC fault
+type Fault struct {
+    Code    int64
+    Message string
+}
+
+func (f *Fault) Error() string {
+    return fmt.Sprintf("rTorrent error %d: %s", f.Code, f.Message)
+}
+
+// CallValues makes an XML-RPC request and decodes the result into plain Go
+// values: string, int64, float64, bool, []byte, []interface{} and
+// map[string]interface{}.
+func (c *Client) CallValues(method string, args ...interface{}) (interface{}, error) {
+    body, err := encodeRequest(method, args)
+    if err != nil {
+        return nil, err
+    }
+
+    httpReq, err := http.NewRequest("POST", c.endpoint, bytes.NewReader(body))
+    if err != nil {
+        return nil, fmt.Errorf("error creating request: %w", err)
+    }
+    httpReq.Header.Set("Content-Type", "text/xml")
+
+    resp, err := c.client.Do(httpReq)
+    if err != nil {
+        return nil, fmt.Errorf("error sending request: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("unexpected status from rTorrent: %s", resp.Status)
+    }
+
+    return decodeResponse(resp.Body)
+}
+
+// Multicall runs d.multicall2 over a view and returns one row per torrent,
+// with the columns in the same order as fields.
+func (c *Client) Multicall(view string, fields ...string) ([][]interface{}, error) {
+    args := make([]interface{}, 0, len(fields)+2)
+    args = append(args, "", view)
+    for _, f := range fields {
+        args = append(args, f)
+    }
+    return c.rows(c.CallValues("d.multicall2", args...))
+}
+
+// FileMulticall runs f.multicall for a single torrent
+func (c *Client) FileMulticall(hash string, fields ...string) ([][]interface{}, error) {
+    args := make([]interface{}, 0, len(fields)+2)
+    args = append(args, hash, "")
+    for _, f := range fields {
+        args = append(args, f)
+    }
+    return c.rows(c.CallValues("f.multicall", args...))
+}
+
+// TrackerMulticall runs t.multicall for a single torrent
+func (c *Client) TrackerMulticall(hash string, fields ...string) ([][]interface{}, error) {
+    args := make([]interface{}, 0, len(fields)+2)
+    args = append(args, hash, "")
+    for _, f := range fields {
+        args = append(args, f)
+    }
+    return c.rows(c.CallValues("t.multicall", args...))
+}
+
+// PeerMulticall runs p.multicall for a single torrent
+func (c *Client) PeerMulticall(hash string, fields ...string) ([][]interface{}, error) {
+    args := make([]interface{}, 0, len(fields)+2)
+    args = append(args, hash, "")
+    for _, f := range fields {
+        args = append(args, f)
+    }
+    return c.rows(c.CallValues("p.multicall", args...))
+}
+
+// MethodCall is a single call inside a system.multicall batch
+type MethodCall struct {
+    Method string
+    Params []interface{}
+}
+
+// SystemMulticall sends several calls in one round trip. The result slice
+// has one entry per call; calls that failed are returned as *Fault values.
+func (c *Client) SystemMulticall(calls []MethodCall) ([]interface{}, error) {
+    if len(calls) == 0 {
+        return nil, nil
+    }
+
+    batch := make([]interface{}, len(calls))
+    for i, call := range calls {
+        params := call.Params
+        if params == nil {
+            params = []interface{}{}
+        }
+        batch[i] = map[string]interface{}{
+            "methodName": call.Method,
+            "params":     params,
+        }
+    }
+
+    result, err := c.CallValues("system.multicall", batch)
+    if err != nil {
+        return nil, err
+    }
+    list, ok := result.([]interface{})
+    if !ok || len(list) != len(calls) {
+        return nil, fmt.Errorf("unexpected system.multicall result: %T", result)
+    }
+
+    out := make([]interface{}, len(list))
+    for i, item := range list {
+        switch v := item.(type) {
+        case []interface{}:
+            if len(v) > 0 {
+                out[i] = v[0]
+            }
+        case map[string]interface{}:
+            out[i] = &Fault{Code: AsInt(v["faultCode"]), Message: AsString(v["faultString"])}
+        }
+    }
+    return out, nil
+}
+
+// FirstError returns the first fault in a system.multicall result
+func FirstError(results []interface{}) error {
+    for _, r := range results {
+        if f, ok := r.(*Fault); ok {
+            return f
+        }
+    }
+    return nil
+}
+
+// rows converts a multicall result into a slice of rows
+func (c *Client) rows(result interface{}, err error) ([][]interface{}, error) {
+    if err != nil {
+        return nil, err
+    }
+    list, ok := result.([]interface{})
+    if !ok {
+        return nil, fmt.Errorf("unexpected multicall result: %T", result)
+    }
+    rows := make([][]interface{}, 0, len(list))
+    for _, item := range list {
+        row, ok := item.([]interface{})
+        if !ok {
+            return nil, fmt.Errorf("unexpected multicall row: %T", item)
+        }
+        rows = append(rows, row)
+    }
+    return rows, nil
+}
+
+// AsString converts a decoded value to a string
+func AsString(v interface{}) string {
+    switch t := v.(type) {
+    case string:
+        return t
+    case []byte:
+        return string(t)
+    case nil:
+        return ""
+    }
+    return fmt.Sprint(v)
+}
+
+// AsInt converts a decoded value to an int64. rTorrent returns some numeric
+// values (d.custom=addtime for example) as strings, so those are parsed too.
+func AsInt(v interface{}) int64 {
+    switch t := v.(type) {
+    case int64:
+        return t
+    case float64:
+        return int64(t)
+    case bool:
+        if t {
+            return 1
+        }
+        return 0
+    case string:
+        n, _ := strconv.ParseInt(strings.TrimSpace(t), 10, 64)
+        return n
+    }
+    return 0
+}
+
+// AsBool converts a decoded value to a bool
+func AsBool(v interface{}) bool {
+    return AsInt(v) != 0
+}
+
+// encodeRequest builds the XML body for a method call
+func encodeRequest(method string, args []interface{}) ([]byte, error) {
+    var buf bytes.Buffer
+    buf.WriteString(`<?xml version="1.0"?><methodCall><methodName>`)
+    xml.EscapeText(&buf, []byte(method))
+    buf.WriteString(`</methodName><params>`)
+    for _, arg := range args {
+        buf.WriteString(`<param>`)
+        if err := encodeValue(&buf, arg); err != nil {
+            return nil, err
+        }
+        buf.WriteString(`</param>`)
+    }
+    buf.WriteString(`</params></methodCall>`)
+    return buf.Bytes(), nil
+}
+
+func encodeValue(buf *bytes.Buffer, arg interface{}) error {
+    buf.WriteString(`<value>`)
+    switch v := arg.(type) {
+    case string:
+        buf.WriteString(`<string>`)
+        xml.EscapeText(buf, []byte(v))
+        buf.WriteString(`</string>`)
+    case int:
+        fmt.Fprintf(buf, `<i8>%d</i8>`, v)
+    case int64:
+        fmt.Fprintf(buf, `<i8>%d</i8>`, v)
+    case float64:
+        fmt.Fprintf(buf, `<double>%s</double>`, strconv.FormatFloat(v, 'f', -1, 64))
+    case bool:
+        if v {
+            buf.WriteString(`<boolean>1</boolean>`)
+        } else {
+            buf.WriteString(`<boolean>0</boolean>`)
+        }
+    case []byte:
+        buf.WriteString(`<base64>`)
+        buf.WriteString(base64.StdEncoding.EncodeToString(v))
+        buf.WriteString(`</base64>`)
+    case []string:
+        buf.WriteString(`<array><data>`)
+        for _, s := range v {
+            if err := encodeValue(buf, s); err != nil {
+                return err
+            }
+        }
+        buf.WriteString(`</data></array>`)
+    case []interface{}:
+        buf.WriteString(`<array><data>`)
+        for _, item := range v {
+            if err := encodeValue(buf, item); err != nil {
+                return err
+            }
+        }
+        buf.WriteString(`</data></array>`)
+    case map[string]interface{}:
+        buf.WriteString(`<struct>`)
+        for name, item := range v {
+            buf.WriteString(`<member><name>`)
+            xml.EscapeText(buf, []byte(name))
+            buf.WriteString(`</name>`)
+            if err := encodeValue(buf, item); err != nil {
+                return err
+            }
+            buf.WriteString(`</member>`)
+        }
+        buf.WriteString(`</struct>`)
+    default:
+        return fmt.Errorf("unsupported argument type: %T", arg)
+    }
+    buf.WriteString(`</value>`)
+    return nil
+}
+
+// decodeResponse parses a methodResponse body
+func decodeResponse(r io.Reader) (interface{}, error) {
+    d := xml.NewDecoder(r)
+    for {
+        tok, err := d.Token()
+        if err != nil {
+            if err == io.EOF {
+                return nil, fmt.Errorf("error parsing response: empty response")
+            }
+            return nil, fmt.Errorf("error parsing response: %w", err)
+        }
+        start, ok := tok.(xml.StartElement)
+        if !ok {
+            continue
+        }
+        switch start.Name.Local {
+        case "value":
+            return decodeValue(d)
+        case "fault":
+            return nil, decodeFault(d)
+        }
+    }
+}
+
+func decodeFault(d *xml.Decoder) error {
+    for {
+        tok, err := d.Token()
+        if err != nil {
+            return fmt.Errorf("error parsing fault: %w", err)
+        }
+        if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "value" {
+            v, err := decodeValue(d)
+            if err != nil {
+                return err
+            }
+            fault := &Fault{}
+            if m, ok := v.(map[string]interface{}); ok {
+                fault.Code = AsInt(m["faultCode"])
+                fault.Message = AsString(m["faultString"])
+            }
+            return fault
+        }
+    }
+}
+
+// decodeValue decodes the contents of a <value> element. The opening tag has
+// already been consumed; the closing tag is consumed before returning.
+func decodeValue(d *xml.Decoder) (interface{}, error) {
+    var text strings.Builder
+    var result interface{}
+    typed := false
+
+    for {
+        tok, err := d.Token()
+        if err != nil {
+            return nil, fmt.Errorf("error parsing value: %w", err)
+        }
+        switch t := tok.(type) {
+        case xml.CharData:
+            text.Write(t)
+        case xml.StartElement:
+            typed = true
+            if result, err = decodeTyped(d, t); err != nil {
+                return nil, err
+            }
+        case xml.EndElement:
+            if !typed {
+                // Untyped values default to string
+                return text.String(), nil
+            }
+            return result, nil
+        }
+    }
+}
+
+func decodeTyped(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+    kind := start.Name.Local
+    switch kind {
+    case "array":
+        return decodeArray(d)
+    case "struct":
+        return decodeStruct(d)
+    }
+
+    var text string
+    if err := d.DecodeElement(&text, &start); err != nil {
+        return nil, fmt.Errorf("error parsing %s: %w", kind, err)
+    }
+
+    switch kind {
+    case "string":
+        return text, nil
+    case "i4", "i8", "int":
+        n, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+        if err != nil {
+            return nil, fmt.Errorf("invalid integer %q: %w", text, err)
+        }
+        return n, nil
+    case "boolean":
+        return strings.TrimSpace(text) == "1", nil
+    case "double":
+        f, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+        if err != nil {
+            return nil, fmt.Errorf("invalid double %q: %w", text, err)
+        }
+        return f, nil
+    case "base64":
+        b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+        if err != nil {
+            return nil, fmt.Errorf("invalid base64: %w", err)
+        }
+        return b, nil
+    case "nil":
+        return nil, nil
+    }
+    return text, nil
+}
+
+func decodeArray(d *xml.Decoder) ([]interface{}, error) {
+    items := make([]interface{}, 0)
+    for {
+        tok, err := d.Token()
+        if err != nil {
+            return nil, fmt.Errorf("error parsing array: %w", err)
+        }
+        switch t := tok.(type) {
+        case xml.StartElement:
+            if t.Name.Local == "value" {
+                v, err := decodeValue(d)
+                if err != nil {
+                    return nil, err
+                }
+                items = append(items, v)
+            }
+        case xml.EndElement:
+            if t.Name.Local == "array" {
+                return items, nil
+            }
+        }
+    }
+}
+
+func decodeStruct(d *xml.Decoder) (map[string]interface{}, error) {
+    members := make(map[string]interface{})
+    var name string
+    for {
+        tok, err := d.Token()
+        if err != nil {
+            return nil, fmt.Errorf("error parsing struct: %w", err)
+        }
+        switch t := tok.(type) {
+        case xml.StartElement:
+            switch t.Name.Local {
+            case "name":
+                if err := d.DecodeElement(&name, &t); err != nil {
+                    return nil, fmt.Errorf("error parsing member name: %w", err)
+                }
+            case "value":
+                v, err := decodeValue(d)
+                if err != nil {
+                    return nil, err
+                }
+                members[name] = v
+            }
+        case xml.EndElement:
+            if t.Name.Local == "struct" {
+                return members, nil
+            }
+        }
+    }
+}
@@ -0,0 +1,121 @@
+// internal/rules/apply.go
+package rules
+
+import (
+    "path/filepath"
+    "strings"
+
+    "rutorrent-web/internal/services"
+)
+
+// Result is what a rule did, or would do, to a loaded torrent
+type Result struct {
+    Hash      string `json:"hash"`
+    Name      string `json:"name"`
+    Rule      int    `json:"rule"` // index of the matching rule, -1 for none
+    RuleName  string `json:"rule_name,omitempty"`
+    Label     string `json:"label,omitempty"`     // new label
+    Priority  int    `json:"priority,omitempty"`  // new priority
+    Throttle  string `json:"throttle,omitempty"`  // new throttle channel
+    Directory string `json:"directory,omitempty"` // new data location, only when moving
+    Error     string `json:"error,omitempty"`
+}
+
+// Info describes a loaded torrent for matching. Files are only fetched when
+// withFiles is set since that takes a call to rTorrent.
+func Info(torrents *services.TorrentService, t *services.Torrent, withFiles bool) (*services.AddInfo, error) {
+    info := &services.AddInfo{Name: t.Name, Size: t.Size, Trackers: t.Trackers}
+    if withFiles {
+        files, err := torrents.GetTorrentFiles(t.Hash)
+        if err != nil {
+            return nil, err
+        }
+        for _, f := range files {
+            info.Files = append(info.Files, f.Path)
+        }
+    }
+    return info, nil
+}
+
+// ApplyExisting runs the rules over loaded torrents, all of them when
+// hashes is empty. Labels, priorities and throttle channels are changed as
+// they would be at add time; directories only when move is set, by moving
+// the data. With dryRun nothing is changed.
+func (s *Store) ApplyExisting(torrents *services.TorrentService, hashes []string, move, dryRun bool) []Result {
+    var list []*services.Torrent
+    if len(hashes) == 0 {
+        list = torrents.Snapshot()
+    } else {
+        for _, hash := range hashes {
+            if t, ok := torrents.GetTorrent(strings.ToUpper(hash)); ok {
+                list = append(list, t)
+            }
+        }
+    }
+
+    withFiles := s.NeedsFiles()
+    results := make([]Result, 0, len(list))
+    for _, t := range list {
+        res := Result{Hash: t.Hash, Name: t.Name, Rule: -1}
+        info, err := Info(torrents, t, withFiles)
+        if err != nil {
+            res.Error = err.Error()
+            results = append(results, res)
+            continue
+        }
+        index, rule := s.Match(info)
+        if rule == nil {
+            results = append(results, res)
+            continue
+        }
+        res.Rule, res.RuleName = index, rule.Name
+
+        opts := &services.AddTorrentOptions{Label: t.Label, Priority: t.Priority, Throttle: t.Throttle}
+        rule.Apply(opts)
+        if opts.Label != t.Label {
+            res.Label = opts.Label
+        }
+        if opts.Priority != t.Priority {
+            res.Priority = opts.Priority
+        }
+        if opts.Throttle != t.Throttle {
+            res.Throttle = opts.Throttle
+        }
+        if move && rule.Directory != "" && (rule.Override || t.Directory == "") {
+            if dest := filepath.Join(rule.Directory, t.Name); t.BasePath != "" && dest != t.BasePath {
+                res.Directory = dest
+            }
+        }
+        if !dryRun {
+            res.Error = apply(torrents, t.Hash, &res)
+        }
+        results = append(results, res)
+    }
+    return results
+}
+
+// apply makes the changes of a result, returning the first error
+func apply(torrents *services.TorrentService, hash string, res *Result) string {
+    hashes := []string{hash}
+    if res.Label != "" {
+        if err := torrents.SetLabel(hashes, res.Label); err != nil {
+            return err.Error()
+        }
+    }
+    if res.Priority != 0 {
+        if err := torrents.SetPriority(hashes, res.Priority); err != nil {
+            return err.Error()
+        }
+    }
+    if res.Throttle != "" {
+        if err := torrents.SetThrottle(hashes, res.Throttle); err != nil {
+            return err.Error()
+        }
+    }
+    if res.Directory != "" {
+        if err := torrents.MoveData(hash, res.Directory); err != nil {
+            return err.Error()
+        }
+    }
+    return ""
+}
@@ -0,0 +1,253 @@
+// internal/rules/rules.go
+package rules
+
+import (
+    "fmt"
+    "path/filepath"
+    "regexp"
+    "strings"
+    "sync"
+
+    "rutorrent-web/internal/services"
+    "rutorrent-web/pkg/fileutil"
+)
+
+// maxRules bounds the number of rules
+const maxRules = 200
+
+// Rule assigns settings to torrents matching all of its conditions. Unset
+// conditions match anything; conditions on values a torrent doesn't have
+// yet, such as the files of a magnet link, don't match.
+type Rule struct {
+    Name    string `json:"name"`
+    Enabled bool   `json:"enabled"`
+
+    Trackers    []string `json:"trackers,omitempty"`     // registered tracker domains, any of them
+    NamePattern string   `json:"name_pattern,omitempty"` // regular expression on the torrent name
+    Extensions  []string `json:"extensions,omitempty"`   // some file has one of these
+    MinSize     int64    `json:"min_size,omitempty"`     // bytes
+    MaxSize     int64    `json:"max_size,omitempty"`     // bytes, no limit if 0
+    WatchDir    string   `json:"watch_dir,omitempty"`    // watch directory the torrent came from
+
+    Label     string `json:"label,omitempty"`
+    Directory string `json:"directory,omitempty"`
+    Priority  int    `json:"priority,omitempty"` // 1 low, 2 normal, 3 high
+    Throttle  string `json:"throttle,omitempty"` // throttle channel
+    // Override replaces values chosen when adding; otherwise the rule only
+    // fills in what was left empty
+    Override bool `json:"override"`
+}
+
+// NeedsFiles reports whether the rule looks at file names
+func (r *Rule) NeedsFiles() bool {
+    return len(r.Extensions) > 0
+}
+
+// Apply sets the rule's values on add options
+func (r *Rule) Apply(opts *services.AddTorrentOptions) {
+    set := func(dst *string, v string) {
+        if v != "" && (r.Override || *dst == "") {
+            *dst = v
+        }
+    }
+    set(&opts.Label, r.Label)
+    set(&opts.Directory, r.Directory)
+    set(&opts.Throttle, r.Throttle)
+    if r.Priority > 0 && (r.Override || opts.Priority == 0) {
+        opts.Priority = r.Priority
+    }
+}
+
+// matcher is a validated rule
+type matcher struct {
+    rule    Rule
+    pattern *regexp.Regexp
+}
+
+func (m *matcher) match(info *services.AddInfo) bool {
+    r := &m.rule
+    if !r.Enabled {
+        return false
+    }
+    if m.pattern != nil && !m.pattern.MatchString(info.Name) {
+        return false
+    }
+    if r.MinSize > 0 && info.Size < r.MinSize {
+        return false
+    }
+    if r.MaxSize > 0 && (info.Size == 0 || info.Size > r.MaxSize) {
+        return false
+    }
+    if r.WatchDir != "" && filepath.Clean(r.WatchDir) != filepath.Clean(info.Source) {
+        return false
+    }
+    if len(r.Trackers) > 0 && !matchTrackers(r.Trackers, info.Trackers) {
+        return false
+    }
+    if len(r.Extensions) > 0 && !matchExtensions(r.Extensions, info.Files) {
+        return false
+    }
+    return true
+}
+
+func matchTrackers(domains, announces []string) bool {
+    for _, announce := range announces {
+        host := services.TrackerHost(announce)
+        domain := services.TrackerDomain(announce)
+        for _, d := range domains {
+            if strings.EqualFold(d, domain) || strings.EqualFold(d, host) {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+func matchExtensions(exts, files []string) bool {
+    for _, f := range files {
+        ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(f)), ".")
+        for _, e := range exts {
+            if ext != "" && ext == e {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// Store keeps the ordered rules shared by all users in rules.json
+type Store struct {
+    path     string
+    matchers []*matcher
+    mu       sync.RWMutex
+}
+
+// NewStore opens the rules kept in dir
+func NewStore(dir string) (*Store, error) {
+    s := &Store{path: filepath.Join(dir, "rules.json")}
+    var rules []Rule
+    if err := fileutil.ReadJSON(s.path, &rules); err != nil {
+        return nil, err
+    }
+    matchers, err := compile(rules)
+    if err != nil {
+        return nil, fmt.Errorf("invalid rules in %s: %w", s.path, err)
+    }
+    s.matchers = matchers
+    return s, nil
+}
+
+// List returns the rules in order
+func (s *Store) List() []Rule {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    rules := make([]Rule, len(s.matchers))
+    for i, m := range s.matchers {
+        rules[i] = m.rule
+    }
+    return rules
+}
+
+// Set replaces all rules, keeping their order
+func (s *Store) Set(rules []Rule) error {
+    matchers, err := compile(rules)
+    if err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    normalized := make([]Rule, len(matchers))
+    for i, m := range matchers {
+        normalized[i] = m.rule
+    }
+    if err := fileutil.WriteJSON(s.path, normalized); err != nil {
+        return err
+    }
+    s.matchers = matchers
+    return nil
+}
+
+// Match returns the index of the first enabled rule matching info and the
+// rule, or -1 and nil
+func (s *Store) Match(info *services.AddInfo) (int, *Rule) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    for i, m := range s.matchers {
+        if m.match(info) {
+            rule := m.rule
+            return i, &rule
+        }
+    }
+    return -1, nil
+}
+
+// NeedsFiles reports whether any enabled rule looks at file names
+func (s *Store) NeedsFiles() bool {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    for _, m := range s.matchers {
+        if m.rule.Enabled && m.rule.NeedsFiles() {
+            return true
+        }
+    }
+    return false
+}
+
+// Filter applies the first matching rule to a torrent being added. It is
+// meant for services.TorrentService.SetAddFilter.
+func (s *Store) Filter(info *services.AddInfo, opts *services.AddTorrentOptions) {
+    if _, rule := s.Match(info); rule != nil {
+        rule.Apply(opts)
+    }
+}
+
+// compile validates rules and normalizes their conditions
+func compile(rules []Rule) ([]*matcher, error) {
+    if len(rules) > maxRules {
+        return nil, fmt.Errorf("too many rules (maximum %d)", maxRules)
+    }
+    matchers := make([]*matcher, 0, len(rules))
+    for i, r := range rules {
+        invalid := func(format string, args ...interface{}) error {
+            return fmt.Errorf("rule %d (%s): %s", i+1, r.Name, fmt.Sprintf(format, args...))
+        }
+
+        m := &matcher{rule: r}
+        if r.NamePattern != "" {
+            re, err := regexp.Compile(r.NamePattern)
+            if err != nil {
+                return nil, invalid("invalid name pattern: %v", err)
+            }
+            m.pattern = re
+        }
+        if r.MinSize < 0 || r.MaxSize < 0 || (r.MaxSize > 0 && r.MinSize > r.MaxSize) {
+            return nil, invalid("invalid size range")
+        }
+        if r.Priority < 0 || r.Priority > 3 {
+            return nil, invalid("priority must be 1 (low), 2 (normal) or 3 (high)")
+        }
+        if r.Directory != "" && !filepath.IsAbs(r.Directory) {
+            return nil, invalid("directory must be an absolute path")
+        }
+        m.rule.Label = services.NormalizeLabel(r.Label)
+        m.rule.Extensions = nil
+        for _, ext := range r.Extensions {
+            if ext = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(ext)), "."); ext != "" {
+                m.rule.Extensions = append(m.rule.Extensions, ext)
+            }
+        }
+        m.rule.Trackers = nil
+        for _, d := range r.Trackers {
+            if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+                m.rule.Trackers = append(m.rule.Trackers, d)
+            }
+        }
+        matchers = append(matchers, m)
+    }
+    return matchers, nil
+}
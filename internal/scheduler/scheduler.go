@@ -0,0 +1,428 @@
+// internal/scheduler/scheduler.go
+package scheduler
+
+import (
+    "fmt"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "rutorrent-web/internal/services"
+    "rutorrent-web/pkg/fileutil"
+)
+
+// Slots is the number of hours in the weekly grid. Slot 0 is Monday
+// 00:00-01:00, slot 167 Sunday 23:00-24:00.
+const Slots = 7 * 24
+
+// Mode is the behavior of one hour of the week
+type Mode string
+
+const (
+    ModeFast      Mode = "fast"      // the default limits, SCH_DEF_UL/DL
+    ModeSlow      Mode = "slow"      // the slow limits
+    ModeStop      Mode = "stop"      // every torrent stopped
+    ModeSeed      Mode = "seed"      // only complete torrents run
+    ModeDownload  Mode = "download"  // only incomplete torrents run
+    ModeUnlimited Mode = "unlimited" // no limits
+)
+
+// Modes lists the behaviors in the order the grid cycles through them
+var Modes = []Mode{ModeFast, ModeSlow, ModeStop, ModeSeed, ModeDownload, ModeUnlimited}
+
+func (m Mode) valid() bool {
+    for _, mode := range Modes {
+        if m == mode {
+            return true
+        }
+    }
+    return false
+}
+
+// Settings are the weekly grid and the limits it refers to. Limits are in
+// KiB/s, 0 is unlimited.
+type Settings struct {
+    Enabled  bool   `json:"enabled"`
+    Timezone string `json:"timezone,omitempty"` // IANA name, the server's if empty
+    Week     []Mode `json:"week"`               // Slots entries, fast where missing
+    DefUp    int64  `json:"def_up"`             // SCH_DEF_UL
+    DefDown  int64  `json:"def_down"`           // SCH_DEF_DL
+    SlowUp   int64  `json:"slow_up"`
+    SlowDown int64  `json:"slow_down"`
+}
+
+// Override replaces the grid until the next slot starts
+type Override struct {
+    Mode  Mode      `json:"mode"`
+    Until time.Time `json:"until"`
+}
+
+// Status is the behavior in force
+type Status struct {
+    Enabled  bool      `json:"enabled"`
+    Slot     int       `json:"slot"`
+    Mode     Mode      `json:"mode"` // the grid's, or the override's
+    Override *Override `json:"override,omitempty"`
+    Stopped  int       `json:"stopped"` // torrents the scheduler stopped
+}
+
+// state is what survives a restart besides the settings
+type state struct {
+    Settings Settings  `json:"settings"`
+    Override *Override `json:"override,omitempty"`
+    Stopped  []string  `json:"stopped,omitempty"` // hashes to start again
+    Limited  bool      `json:"limited,omitempty"` // limits set by a mode, reset to the defaults when disabled
+}
+
+// Scheduler applies the weekly grid. Torrents are only stopped when a slot
+// starts, so torrents started by hand during a stop slot keep running until
+// the next change.
+type Scheduler struct {
+    torrents *services.TorrentService
+    path     string
+    state    state
+    applied  Mode // mode applied last, empty before the first
+    wake     chan struct{}
+    stop     chan struct{}
+    once     sync.Once
+    mu       sync.Mutex
+}
+
+// New loads the scheduler kept in dir
+func New(torrents *services.TorrentService, dir string) (*Scheduler, error) {
+    s := &Scheduler{
+        torrents: torrents,
+        path:     filepath.Join(dir, "scheduler.json"),
+        wake:     make(chan struct{}, 1),
+        stop:     make(chan struct{}),
+    }
+    if err := fileutil.ReadJSON(s.path, &s.state); err != nil {
+        return nil, err
+    }
+    s.state.Settings.Week = fillWeek(s.state.Settings.Week)
+    return s, nil
+}
+
+// fillWeek pads or trims a grid to Slots entries
+func fillWeek(week []Mode) []Mode {
+    full := make([]Mode, Slots)
+    for i := range full {
+        full[i] = ModeFast
+        if i < len(week) && week[i].valid() {
+            full[i] = week[i]
+        }
+    }
+    return full
+}
+
+// Settings returns the grid and limits
+func (s *Scheduler) Settings() Settings {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    settings := s.state.Settings
+    settings.Week = append([]Mode(nil), settings.Week...)
+    return settings
+}
+
+// SetSettings validates and stores new settings and applies them at once
+func (s *Scheduler) SetSettings(settings Settings) error {
+    if len(settings.Week) != Slots {
+        return fmt.Errorf("the week needs %d hourly slots, got %d", Slots, len(settings.Week))
+    }
+    for i, m := range settings.Week {
+        if !m.valid() {
+            return fmt.Errorf("slot %d: unknown mode %q", i, m)
+        }
+    }
+    if _, err := loadLocation(settings.Timezone); err != nil {
+        return err
+    }
+    if settings.DefUp < 0 || settings.DefDown < 0 || settings.SlowUp < 0 || settings.SlowDown < 0 {
+        return fmt.Errorf("limits can't be negative")
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    previous := s.state.Settings
+    s.state.Settings = settings
+    if err := s.save(); err != nil {
+        s.state.Settings = previous
+        return err
+    }
+    s.applied = "" // limits may have changed
+    s.poke()
+    return nil
+}
+
+// SetOverride replaces the grid with mode until the next slot starts
+func (s *Scheduler) SetOverride(mode Mode) (*Override, error) {
+    if !mode.valid() {
+        return nil, fmt.Errorf("unknown mode %q", mode)
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    loc, err := loadLocation(s.state.Settings.Timezone)
+    if err != nil {
+        return nil, err
+    }
+    now := time.Now().In(loc)
+    next := time.Date(now.Year(), now.Month(), now.Day(), now.Hour()+1, 0, 0, 0, loc)
+    o := &Override{Mode: mode, Until: next}
+    s.state.Override = o
+    if err := s.save(); err != nil {
+        s.state.Override = nil
+        return nil, err
+    }
+    s.poke()
+    copied := *o
+    return &copied, nil
+}
+
+// ClearOverride returns to the grid
+func (s *Scheduler) ClearOverride() error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.state.Override = nil
+    if err := s.save(); err != nil {
+        return err
+    }
+    s.poke()
+    return nil
+}
+
+// Status returns the slot and behavior in force now
+func (s *Scheduler) Status() Status {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    slot, mode, override := s.current(time.Now())
+    st := Status{
+        Enabled: s.state.Settings.Enabled,
+        Slot:    slot,
+        Mode:    mode,
+        Stopped: len(s.state.Stopped),
+    }
+    if override != nil {
+        copied := *override
+        st.Override = &copied
+    }
+    return st
+}
+
+// current returns the slot at now and the mode in force
+func (s *Scheduler) current(now time.Time) (int, Mode, *Override) {
+    loc, err := loadLocation(s.state.Settings.Timezone)
+    if err != nil {
+        loc = time.Local
+    }
+    now = now.In(loc)
+    // Monday first
+    day := (int(now.Weekday()) + 6) % 7
+    slot := day*24 + now.Hour()
+
+    if o := s.state.Override; o != nil && now.Before(o.Until) {
+        return slot, o.Mode, o
+    }
+    return slot, s.state.Settings.Week[slot], nil
+}
+
+func (s *Scheduler) poke() {
+    select {
+    case s.wake <- struct{}{}:
+    default:
+    }
+}
+
+// Start applies the grid in the background until Stop is called. Slots
+// change on the hour, so the grid is looked at every minute.
+func (s *Scheduler) Start() {
+    go func() {
+        s.tick()
+        for {
+            now := time.Now()
+            timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+            select {
+            case <-timer.C:
+            case <-s.wake:
+                timer.Stop()
+            case <-s.stop:
+                timer.Stop()
+                return
+            }
+            s.tick()
+        }
+    }()
+}
+
+// Stop ends the scheduler; torrents and limits are left as they are
+func (s *Scheduler) Stop() {
+    s.once.Do(func() { close(s.stop) })
+}
+
+// tick applies the mode in force if it changed since the last tick
+func (s *Scheduler) tick() {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if o := s.state.Override; o != nil && !time.Now().Before(o.Until) {
+        s.state.Override = nil
+        if err := s.save(); err != nil {
+            services.Warnf("scheduler: %v", err)
+        }
+    }
+
+    if !s.state.Settings.Enabled {
+        if s.state.Limited {
+            s.restoreLimits()
+        }
+        if s.applied != "" || len(s.state.Stopped) > 0 {
+            // Disabled: give back what the scheduler stopped
+            s.release(nil)
+            s.applied = ""
+        }
+        return
+    }
+
+    _, mode, _ := s.current(time.Now())
+    if mode == s.applied {
+        return
+    }
+    if err := s.apply(mode); err != nil {
+        services.Warnf("scheduler: failed to apply %s: %v", mode, err)
+        return
+    }
+    services.Infof("scheduler: %s", mode)
+    s.applied = mode
+}
+
+// apply sets the limits of a mode and stops or starts torrents for it
+func (s *Scheduler) apply(mode Mode) error {
+    cfg := s.state.Settings
+    up, down := cfg.DefUp, cfg.DefDown
+    switch mode {
+    case ModeSlow:
+        up, down = cfg.SlowUp, cfg.SlowDown
+    case ModeUnlimited:
+        up, down = 0, 0
+    }
+    if err := s.torrents.SetTransferLimits(down*1024, up*1024); err != nil {
+        return err
+    }
+    if !s.state.Limited {
+        s.state.Limited = true
+        if err := s.save(); err != nil {
+            return err
+        }
+    }
+
+    var halt func(t *services.Torrent) bool
+    switch mode {
+    case ModeStop:
+        halt = func(t *services.Torrent) bool { return true }
+    case ModeSeed:
+        halt = func(t *services.Torrent) bool { return !t.Complete }
+    case ModeDownload:
+        halt = func(t *services.Torrent) bool { return t.Complete }
+    }
+    s.release(halt)
+
+    if halt == nil {
+        return nil
+    }
+    var stopping []string
+    for _, t := range s.torrents.Snapshot() {
+        if t.State != 0 && !t.Hashing && halt(t) {
+            stopping = append(stopping, t.Hash)
+        }
+    }
+    if len(stopping) == 0 {
+        return nil
+    }
+    if err := s.torrents.StopTorrents(stopping); err != nil {
+        return err
+    }
+    s.state.Stopped = mergeHashes(s.state.Stopped, stopping)
+    return s.save()
+}
+
+// restoreLimits puts back the default limits after the scheduler was
+// disabled, so a slow or unlimited slot doesn't outlive it. It is tried
+// again on the next tick when rTorrent can't be reached.
+func (s *Scheduler) restoreLimits() {
+    cfg := s.state.Settings
+    if err := s.torrents.SetTransferLimits(cfg.DefDown*1024, cfg.DefUp*1024); err != nil {
+        services.Warnf("scheduler: failed to restore default limits: %v", err)
+        return
+    }
+    s.state.Limited = false
+    if err := s.save(); err != nil {
+        services.Warnf("scheduler: %v", err)
+    }
+}
+
+// release starts the torrents the scheduler stopped that halt no longer
+// holds back; halt nil releases all of them
+func (s *Scheduler) release(halt func(t *services.Torrent) bool) {
+    if len(s.state.Stopped) == 0 {
+        return
+    }
+    var starting, kept []string
+    for _, hash := range s.state.Stopped {
+        t, ok := s.torrents.GetTorrent(hash)
+        switch {
+        case !ok, t.State != 0:
+            // Removed, or started by hand
+        case halt != nil && halt(t):
+            kept = append(kept, hash)
+        default:
+            starting = append(starting, hash)
+        }
+    }
+    if len(starting) > 0 {
+        if err := s.torrents.StartTorrents(starting); err != nil {
+            services.Warnf("scheduler: failed to restart torrents: %v", err)
+            return
+        }
+    }
+    s.state.Stopped = kept
+    if err := s.save(); err != nil {
+        services.Warnf("scheduler: %v", err)
+    }
+}
+
+func (s *Scheduler) save() error {
+    return fileutil.WriteJSON(s.path, s.state)
+}
+
+func mergeHashes(a, b []string) []string {
+    seen := make(map[string]bool, len(a)+len(b))
+    var out []string
+    for _, h := range append(append([]string(nil), a...), b...) {
+        h = strings.ToUpper(h)
+        if !seen[h] {
+            seen[h] = true
+            out = append(out, h)
+        }
+    }
+    sort.Strings(out)
+    return out
+}
+
+// loadLocation resolves a timezone name, the server's zone if empty
+func loadLocation(name string) (*time.Location, error) {
+    if name == "" {
+        return time.Local, nil
+    }
+    loc, err := time.LoadLocation(name)
+    if err != nil {
+        return nil, fmt.Errorf("unknown timezone %q", name)
+    }
+    return loc, nil
+}
@@ -0,0 +1,261 @@
+// internal/services/add.go
+package services
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+
+    "rutorrent-web/internal/magnet"
+    "rutorrent-web/internal/rtorrent"
+)
+
+// AddTorrentOptions controls how a new torrent is loaded into rTorrent
+type AddTorrentOptions struct {
+    Start     bool
+    SkipCheck bool // raw data only: take existing content as complete
+    Directory string
+    Label     string
+    Priority  int // d.priority: 1 low, 2 normal, 3 high; 0 keeps rTorrent's default
+    Throttle  string // throttle channel, d.throttle_name
+    Source    string // watch directory the torrent came from, for add filters
+    // VerifyPieces is the number of random pieces hashed before SkipCheck
+    // trusts the existing content
+    VerifyPieces int
+}
+
+// AddTorrentFile loads a .torrent file from disk
+func (s *TorrentService) AddTorrentFile(path string, opts *AddTorrentOptions) error {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return fmt.Errorf("failed to read torrent file: %w", err)
+    }
+    return s.AddTorrentData(data, opts)
+}
+
+// AddTorrentData loads raw .torrent data
+func (s *TorrentService) AddTorrentData(data []byte, opts *AddTorrentOptions) error {
+    if opts == nil {
+        opts = &AddTorrentOptions{}
+    }
+    opts = s.filterOptions(dataInfo(data), opts)
+
+    if opts.SkipCheck {
+        var err error
+        if data, err = s.withResume(data, opts); err != nil {
+            return err
+        }
+    }
+
+    method := "load.raw"
+    if opts.Start {
+        method = "load.raw_start"
+    }
+
+    args := []interface{}{"", data}
+    args = append(args, loadCommands(opts)...)
+    if _, err := s.client.CallValues(method, args...); err != nil {
+        return fmt.Errorf("error adding torrent: %w", err)
+    }
+    s.Refresh()
+    return nil
+}
+
+// AddTorrentSelected loads raw .torrent data with only some of its files
+// wanted. The torrent is loaded stopped so nothing is downloaded before the
+// other files are turned off, and started afterwards if opts.Start is set.
+// hash is the torrent's info hash, files its file count and selected the
+// indices to download.
+func (s *TorrentService) AddTorrentSelected(data []byte, hash string, files int, selected []int, opts *AddTorrentOptions) error {
+    if opts == nil {
+        opts = &AddTorrentOptions{}
+    }
+    hash = strings.ToUpper(hash)
+    if _, ok := s.GetTorrent(hash); ok {
+        return ErrTorrentExists
+    }
+    opts = s.filterOptions(dataInfo(data), opts)
+    if opts.SkipCheck {
+        var err error
+        if data, err = s.withResume(data, opts); err != nil {
+            return err
+        }
+    }
+
+    stopped := *opts
+    stopped.Start = false
+    args := append([]interface{}{"", data}, loadCommands(&stopped)...)
+    if _, err := s.client.CallValues("load.raw", args...); err != nil {
+        return fmt.Errorf("error adding torrent: %w", err)
+    }
+    s.Refresh()
+
+    wanted := make(map[int]bool, len(selected))
+    for _, i := range selected {
+        wanted[i] = true
+    }
+    var off []int
+    for i := 0; i < files; i++ {
+        if !wanted[i] {
+            off = append(off, i)
+        }
+    }
+    if err := s.SetFilePriorities(hash, off, 0); err != nil {
+        return fmt.Errorf("torrent added stopped, but setting file priorities failed: %w", err)
+    }
+    if opts.Start {
+        return s.StartTorrents([]string{hash})
+    }
+    return nil
+}
+
+// AddMagnet loads a magnet URI or a remote .torrent URL
+func (s *TorrentService) AddMagnet(uri string, opts *AddTorrentOptions) error {
+    if opts == nil {
+        opts = &AddTorrentOptions{}
+    }
+    var info *AddInfo
+    if m, err := magnet.Parse(uri); err == nil {
+        info = magnetInfo(m)
+    }
+    opts = s.filterOptions(info, opts)
+
+    method := "load.normal"
+    if opts.Start {
+        method = "load.start"
+    }
+
+    args := []interface{}{"", uri}
+    args = append(args, loadCommands(opts)...)
+    if _, err := s.client.CallValues(method, args...); err != nil {
+        return fmt.Errorf("error adding magnet: %w", err)
+    }
+    s.Refresh()
+    return nil
+}
+
+// AddSource is one torrent of a batch: a parsed magnet link, a URI handed
+// to rTorrent as it is (a remote .torrent URL for example), or raw .torrent
+// data
+type AddSource struct {
+    Magnet *magnet.Magnet
+    URI    string
+    Data   []byte
+}
+
+// AddTorrents loads a batch of torrents in one system.multicall. The
+// returned slice holds the outcome of each source, nil when it was loaded;
+// the error is set when the batch as a whole failed. Magnet links are
+// checked like AddMagnetLink does.
+func (s *TorrentService) AddTorrents(sources []AddSource, opts *AddTorrentOptions) ([]error, error) {
+    if opts == nil {
+        opts = &AddTorrentOptions{}
+    }
+
+    errs := make([]error, len(sources))
+    calls := make([]rtorrent.MethodCall, 0, len(sources))
+    sent := make([]int, 0, len(sources)) // source index of each call
+    hashes := make(map[string]bool)
+    for i, src := range sources {
+        var info *AddInfo
+        switch {
+        case src.Magnet != nil:
+            info = magnetInfo(src.Magnet)
+        case src.Data != nil:
+            info = dataInfo(src.Data)
+        }
+        opts := s.filterOptions(info, opts)
+
+        method, value := "load.normal", interface{}(src.URI)
+        if opts.Start {
+            method = "load.start"
+        }
+        switch {
+        case src.Magnet != nil:
+            uri, err := s.checkMagnet(src.Magnet)
+            if err == nil && hashes[src.Magnet.InfoHash] {
+                err = ErrTorrentExists
+            }
+            if err != nil {
+                errs[i] = err
+                continue
+            }
+            hashes[src.Magnet.InfoHash] = true
+            value = uri
+        case src.Data != nil:
+            method, value = "load.raw", src.Data
+            if opts.Start {
+                method = "load.raw_start"
+            }
+        }
+        args := append([]interface{}{"", value}, loadCommands(opts)...)
+        calls = append(calls, rtorrent.MethodCall{Method: method, Params: args})
+        sent = append(sent, i)
+    }
+    if len(calls) == 0 {
+        return errs, nil
+    }
+
+    results, err := s.client.SystemMulticall(calls)
+    if err != nil {
+        return nil, fmt.Errorf("error adding torrents: %w", err)
+    }
+    for n, i := range sent {
+        if n >= len(results) {
+            errs[i] = fmt.Errorf("no result from rTorrent")
+            continue
+        }
+        if f, ok := results[n].(*rtorrent.Fault); ok {
+            errs[i] = f
+            continue
+        }
+        if m := sources[i].Magnet; m != nil && m.SelectOnly != nil {
+            s.selections.add(m.InfoHash, m.SelectOnly)
+        }
+    }
+    s.Refresh()
+    return errs, nil
+}
+
+// ValidateDirectory checks that a directory can be used as a download target
+func (s *TorrentService) ValidateDirectory(dir string) error {
+    if !filepath.IsAbs(dir) {
+        return fmt.Errorf("directory must be an absolute path: %s", dir)
+    }
+    info, err := os.Stat(dir)
+    if err != nil {
+        return fmt.Errorf("invalid directory: %w", err)
+    }
+    if !info.IsDir() {
+        return fmt.Errorf("not a directory: %s", dir)
+    }
+    return nil
+}
+
+// loadCommands builds the commands rTorrent runs on a torrent as it is loaded
+func loadCommands(opts *AddTorrentOptions) []interface{} {
+    cmds := []interface{}{
+        "d.custom.set=addtime," + strconv.FormatInt(time.Now().Unix(), 10),
+    }
+    if opts.Directory != "" {
+        cmds = append(cmds, "d.directory.set="+quoteCommandArg(opts.Directory))
+    }
+    if opts.Label != "" {
+        cmds = append(cmds, "d.custom1.set="+quoteCommandArg(EncodeLabel(opts.Label)))
+    }
+    if opts.Priority > 0 {
+        cmds = append(cmds, "d.priority.set="+strconv.Itoa(opts.Priority))
+    }
+    if opts.Throttle != "" {
+        cmds = append(cmds, "d.throttle_name.set="+quoteCommandArg(opts.Throttle))
+    }
+    return cmds
+}
+
+// quoteCommandArg quotes a value for use inside an rTorrent command string
+func quoteCommandArg(v string) string {
+    return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+}
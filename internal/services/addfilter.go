@@ -0,0 +1,62 @@
+// internal/services/addfilter.go
+package services
+
+import (
+    "rutorrent-web/internal/magnet"
+    "rutorrent-web/internal/torrentfile"
+)
+
+// AddInfo is what is known about a torrent before it is loaded. Magnet
+// links carry no file list, and only a name and size if the link has them.
+type AddInfo struct {
+    Name     string
+    Size     int64    // 0 if unknown
+    Trackers []string // announce URLs
+    Files    []string // paths relative to the torrent, nil if unknown
+    Source   string   // watch directory the torrent came from, if any
+}
+
+// AddFilter adjusts the options of a torrent about to be loaded
+type AddFilter func(info *AddInfo, opts *AddTorrentOptions)
+
+// SetAddFilter installs a filter that runs on every torrent added through
+// the service. nil removes it.
+func (s *TorrentService) SetAddFilter(f AddFilter) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.addFilter = f
+}
+
+// filterOptions returns the options a torrent is loaded with after the
+// filter ran. opts itself is left alone since it may be shared by a batch.
+func (s *TorrentService) filterOptions(info *AddInfo, opts *AddTorrentOptions) *AddTorrentOptions {
+    s.mu.RLock()
+    f := s.addFilter
+    s.mu.RUnlock()
+
+    filtered := *opts
+    if f == nil || info == nil {
+        return &filtered
+    }
+    info.Source = opts.Source
+    f(info, &filtered)
+    return &filtered
+}
+
+// dataInfo describes raw .torrent data, nil if it can't be parsed
+func dataInfo(data []byte) *AddInfo {
+    t, err := torrentfile.NewFromBytes(data)
+    if err != nil {
+        return nil
+    }
+    info := &AddInfo{Name: t.GetName(), Size: t.GetSize(), Trackers: t.GetTrackers()}
+    for _, f := range t.GetFiles() {
+        info.Files = append(info.Files, f.Path)
+    }
+    return info
+}
+
+// magnetInfo describes a magnet link
+func magnetInfo(m *magnet.Magnet) *AddInfo {
+    return &AddInfo{Name: m.Name, Size: m.Length, Trackers: m.Trackers}
+}
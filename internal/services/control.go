@@ -0,0 +1,260 @@
+// internal/services/control.go
+package services
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+
+    "rutorrent-web/internal/rtorrent"
+)
+
+// StartTorrents opens and starts the given torrents
+func (s *TorrentService) StartTorrents(hashes []string) error {
+    return s.eachTorrent(hashes, "d.open", "d.start")
+}
+
+// StopTorrents stops and closes the given torrents
+func (s *TorrentService) StopTorrents(hashes []string) error {
+    return s.eachTorrent(hashes, "d.stop", "d.close")
+}
+
+// PauseTorrents pauses the given torrents without closing them
+func (s *TorrentService) PauseTorrents(hashes []string) error {
+    return s.eachTorrent(hashes, "d.pause")
+}
+
+// ResumeTorrents resumes paused torrents
+func (s *TorrentService) ResumeTorrents(hashes []string) error {
+    return s.eachTorrent(hashes, "d.resume")
+}
+
+// RecheckTorrents starts a hash check on the given torrents
+func (s *TorrentService) RecheckTorrents(hashes []string) error {
+    return s.eachTorrent(hashes, "d.check_hash")
+}
+
+// SetLabel sets d.custom1 on the given torrents. An empty label clears it.
+func (s *TorrentService) SetLabel(hashes []string, label string) error {
+    encoded := EncodeLabel(NormalizeLabel(label))
+    calls := make([]rtorrent.MethodCall, 0, len(hashes))
+    for _, hash := range hashes {
+        calls = append(calls, rtorrent.MethodCall{
+            Method: "d.custom1.set",
+            Params: []interface{}{strings.ToUpper(hash), encoded},
+        })
+    }
+    return s.batch(calls)
+}
+
+// SetCustom sets the custom value key (d.custom.set) of the given torrents
+func (s *TorrentService) SetCustom(hashes []string, key, value string) error {
+    calls := make([]rtorrent.MethodCall, 0, len(hashes))
+    for _, hash := range hashes {
+        calls = append(calls, rtorrent.MethodCall{
+            Method: "d.custom.set",
+            Params: []interface{}{strings.ToUpper(hash), key, value},
+        })
+    }
+    return s.batch(calls)
+}
+
+// SetPriority sets d.priority (0 off, 1 low, 2 normal, 3 high) of the given
+// torrents
+func (s *TorrentService) SetPriority(hashes []string, priority int) error {
+    if priority < 0 || priority > 3 {
+        return fmt.Errorf("invalid torrent priority: %d", priority)
+    }
+    calls := make([]rtorrent.MethodCall, 0, len(hashes))
+    for _, hash := range hashes {
+        calls = append(calls, rtorrent.MethodCall{
+            Method: "d.priority.set",
+            Params: []interface{}{strings.ToUpper(hash), priority},
+        })
+    }
+    return s.batch(calls)
+}
+
+// SetDirectory changes the download directory of the given torrents. rTorrent
+// only accepts a new directory on closed torrents, so active torrents are
+// stopped first and restarted afterwards. Data is not moved.
+func (s *TorrentService) SetDirectory(hashes []string, dir string) error {
+    if !filepath.IsAbs(dir) {
+        return fmt.Errorf("directory must be an absolute path: %s", dir)
+    }
+
+    calls := make([]rtorrent.MethodCall, 0, len(hashes)*5)
+    for _, hash := range hashes {
+        hash = strings.ToUpper(hash)
+        wasActive := false
+        if t, ok := s.GetTorrent(hash); ok {
+            wasActive = t.State != 0
+        }
+
+        calls = append(calls,
+            rtorrent.MethodCall{Method: "d.stop", Params: []interface{}{hash}},
+            rtorrent.MethodCall{Method: "d.close", Params: []interface{}{hash}},
+            rtorrent.MethodCall{Method: "d.directory.set", Params: []interface{}{hash, dir}},
+        )
+        if wasActive {
+            calls = append(calls,
+                rtorrent.MethodCall{Method: "d.open", Params: []interface{}{hash}},
+                rtorrent.MethodCall{Method: "d.start", Params: []interface{}{hash}},
+            )
+        }
+    }
+    return s.batch(calls)
+}
+
+// EraseTorrents removes torrents from rTorrent, optionally deleting their
+// data. Nothing is removed when the remove guard refuses any of them.
+func (s *TorrentService) EraseTorrents(hashes []string, deleteData bool) error {
+    if err := s.checkRemove(hashes, deleteData); err != nil {
+        return err
+    }
+    return s.ForceEraseTorrents(hashes, deleteData)
+}
+
+// ForceEraseTorrents removes torrents like EraseTorrents without asking the
+// remove guard
+func (s *TorrentService) ForceEraseTorrents(hashes []string, deleteData bool) error {
+    // Resolve data paths before the torrents disappear from rTorrent
+    var paths []string
+    if deleteData {
+        for _, hash := range hashes {
+            path, err := s.dataPath(strings.ToUpper(hash))
+            if err != nil {
+                return err
+            }
+            paths = append(paths, path)
+        }
+    }
+
+    if err := s.eachTorrent(hashes, "d.erase"); err != nil {
+        return err
+    }
+
+    for _, path := range paths {
+        if !isSafeDataPath(path) {
+            Warnf("refusing to delete data path %q", path)
+            continue
+        }
+        if err := os.RemoveAll(path); err != nil {
+            return fmt.Errorf("failed to delete %s: %w", path, err)
+        }
+    }
+    return nil
+}
+
+// dataPath returns where a torrent's data is. d.base_path is empty while a
+// torrent is closed, so the path is built from d.directory then, which is
+// the data directory of multi-file torrents and the parent of single files.
+func (s *TorrentService) dataPath(hash string) (string, error) {
+    results, err := s.client.SystemMulticall([]rtorrent.MethodCall{
+        {Method: "d.base_path", Params: []interface{}{hash}},
+        {Method: "d.directory", Params: []interface{}{hash}},
+        {Method: "d.name", Params: []interface{}{hash}},
+        {Method: "d.is_multi_file", Params: []interface{}{hash}},
+    })
+    if err == nil {
+        err = rtorrent.FirstError(results)
+    }
+    if err != nil {
+        return "", fmt.Errorf("failed to get data path for %s: %w", hash, err)
+    }
+
+    if path := rtorrent.AsString(results[0]); path != "" {
+        return path, nil
+    }
+    dir, name := rtorrent.AsString(results[1]), rtorrent.AsString(results[2])
+    switch {
+    case dir == "":
+    case rtorrent.AsBool(results[3]):
+        return dir, nil
+    case name != "":
+        return filepath.Join(dir, name), nil
+    }
+    return "", fmt.Errorf("no data path known for %s", hash)
+}
+
+// eachTorrent runs a list of single-argument commands against every hash in
+// one system.multicall and refreshes the snapshot afterwards
+func (s *TorrentService) eachTorrent(hashes []string, methods ...string) error {
+    calls := make([]rtorrent.MethodCall, 0, len(hashes)*len(methods))
+    for _, hash := range hashes {
+        for _, method := range methods {
+            calls = append(calls, rtorrent.MethodCall{
+                Method: method,
+                Params: []interface{}{strings.ToUpper(hash)},
+            })
+        }
+    }
+    return s.batch(calls)
+}
+
+// batch sends calls in a single round trip and triggers a snapshot refresh
+func (s *TorrentService) batch(calls []rtorrent.MethodCall) error {
+    if len(calls) == 0 {
+        return nil
+    }
+
+    results, err := s.client.SystemMulticall(calls)
+    s.Refresh()
+    if err != nil {
+        return err
+    }
+    return rtorrent.FirstError(results)
+}
+
+// isSafeDataPath guards against deleting filesystem roots when rTorrent
+// reports an unexpected base path
+func isSafeDataPath(path string) bool {
+    clean := filepath.Clean(path)
+    return filepath.IsAbs(clean) && clean != string(filepath.Separator) && filepath.Dir(clean) != clean
+}
+
+// SetFilePriorities sets the priority (0 off, 1 normal, 2 high) of files of a
+// torrent, identified by their index
+func (s *TorrentService) SetFilePriorities(hash string, indexes []int, priority int) error {
+    if priority < 0 || priority > 2 {
+        return fmt.Errorf("invalid file priority: %d", priority)
+    }
+
+    hash = strings.ToUpper(hash)
+    calls := make([]rtorrent.MethodCall, 0, len(indexes)+1)
+    for _, idx := range indexes {
+        calls = append(calls, rtorrent.MethodCall{
+            Method: "f.priority.set",
+            Params: []interface{}{hash + ":f" + strconv.Itoa(idx), priority},
+        })
+    }
+    if len(calls) == 0 {
+        return nil
+    }
+    calls = append(calls, rtorrent.MethodCall{
+        Method: "d.update_priorities",
+        Params: []interface{}{hash},
+    })
+    return s.batch(calls)
+}
+
+// SetTrackersEnabled enables or disables trackers of a torrent, identified
+// by their index
+func (s *TorrentService) SetTrackersEnabled(hash string, indexes []int, enabled bool) error {
+    hash = strings.ToUpper(hash)
+    value := 0
+    if enabled {
+        value = 1
+    }
+
+    calls := make([]rtorrent.MethodCall, 0, len(indexes))
+    for _, idx := range indexes {
+        calls = append(calls, rtorrent.MethodCall{
+            Method: "t.is_enabled.set",
+            Params: []interface{}{hash + ":t" + strconv.Itoa(idx), value},
+        })
+    }
+    return s.batch(calls)
+}
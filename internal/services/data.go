@@ -0,0 +1,130 @@
+// internal/services/data.go
+package services
+
+import (
+    "errors"
+    "path/filepath"
+
+    "rutorrent-web/internal/util/utility"
+)
+
+var (
+    // ErrTorrentNotFound is returned for hashes missing from the snapshot
+    ErrTorrentNotFound = errors.New("torrent not found")
+    // ErrNoSuchFile is returned for a file index the torrent doesn't have
+    ErrNoSuchFile = errors.New("no such file in torrent")
+    // ErrFileIncomplete is returned for files that aren't fully downloaded
+    ErrFileIncomplete = errors.New("file is not completely downloaded")
+    // ErrOutsideTorrent is returned when a file path resolves to a location
+    // outside the torrent's directory
+    ErrOutsideTorrent = errors.New("file is outside the torrent directory")
+)
+
+// DataFile returns the location on disk of a completed torrent file. The
+// path is built from d.directory and f.path and checked, after resolving
+// symlinks, to lie inside the torrent's directory.
+func (s *TorrentService) DataFile(hash string, index int) (string, *TorrentFile, error) {
+    t, ok := s.GetTorrent(hash)
+    if !ok {
+        return "", nil, ErrTorrentNotFound
+    }
+    files, err := s.GetTorrentFiles(t.Hash)
+    if err != nil {
+        return "", nil, err
+    }
+    if index < 0 || index >= len(files) {
+        return "", nil, ErrNoSuchFile
+    }
+    f := &files[index]
+    if !fileComplete(f) {
+        return "", nil, ErrFileIncomplete
+    }
+    path, err := resolveDataFile(t, f)
+    if err != nil {
+        return "", nil, err
+    }
+    return path, f, nil
+}
+
+// DataEntry is a torrent file with its resolved location on disk
+type DataEntry struct {
+    Path string
+    File TorrentFile
+}
+
+// DataFiles resolves several completed files of a torrent like DataFile.
+// Without indexes every completed file is returned and incomplete ones,
+// such as files set to "don't download", are left out; an explicitly
+// requested incomplete file is an error.
+func (s *TorrentService) DataFiles(hash string, indexes []int) (*Torrent, []DataEntry, error) {
+    t, ok := s.GetTorrent(hash)
+    if !ok {
+        return nil, nil, ErrTorrentNotFound
+    }
+    all, err := s.GetTorrentFiles(t.Hash)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    var selected []TorrentFile
+    if len(indexes) == 0 {
+        for _, f := range all {
+            if fileComplete(&f) {
+                selected = append(selected, f)
+            }
+        }
+    } else {
+        seen := make(map[int]bool, len(indexes))
+        for _, index := range indexes {
+            if index < 0 || index >= len(all) {
+                return nil, nil, ErrNoSuchFile
+            }
+            if seen[index] {
+                continue
+            }
+            seen[index] = true
+            if !fileComplete(&all[index]) {
+                return nil, nil, ErrFileIncomplete
+            }
+            selected = append(selected, all[index])
+        }
+    }
+    if len(selected) == 0 {
+        return nil, nil, ErrFileIncomplete
+    }
+
+    entries := make([]DataEntry, 0, len(selected))
+    for i := range selected {
+        path, err := resolveDataFile(t, &selected[i])
+        if err != nil {
+            return nil, nil, err
+        }
+        entries = append(entries, DataEntry{Path: path, File: selected[i]})
+    }
+    return t, entries, nil
+}
+
+// fileComplete reports whether all of a file is on disk. Empty files have
+// no chunks of their own and are complete as soon as they exist.
+func fileComplete(f *TorrentFile) bool {
+    return f.Size == 0 || (f.Chunks > 0 && f.CompletedChunks >= f.Chunks)
+}
+
+// resolveDataFile returns the resolved path of a torrent file and checks
+// that it lies inside the torrent's directory
+func resolveDataFile(t *Torrent, f *TorrentFile) (string, error) {
+    // d.directory is the torrent folder for multi-file torrents and the
+    // folder containing the file otherwise; f.path is relative to it
+    dir, err := filepath.EvalSymlinks(t.Directory)
+    if err != nil {
+        return "", err
+    }
+    path, err := filepath.EvalSymlinks(filepath.Join(dir, filepath.Clean("/"+f.Path)))
+    if err != nil {
+        return "", err
+    }
+    if path == dir || !utility.NewPathUtil(dir).IsSubPath(path) {
+        return "", ErrOutsideTorrent
+    }
+    return path, nil
+}
@@ -0,0 +1,165 @@
+// internal/services/details.go
+package services
+
+import (
+    "strings"
+    "time"
+
+    "rutorrent-web/internal/rtorrent"
+)
+
+// TorrentFile is a single file inside a torrent
+type TorrentFile struct {
+    Index           int
+    Path            string // relative to the torrent base path
+    Size            int64
+    Chunks          int64
+    CompletedChunks int64
+    FirstChunk      int64
+    LastChunk       int64
+    Priority        int // 0 = off, 1 = normal, 2 = high
+    Progress        float64
+}
+
+// Tracker is a single announce URL of a torrent
+type Tracker struct {
+    Index        int
+    URL          string
+    Type         int // 1 = http, 2 = udp, 3 = dht
+    Enabled      bool
+    Group        int
+    Seeders      int
+    Leechers     int
+    Downloaded   int
+    FailedCount  int
+    SuccessCount int
+    LastSuccess  time.Time
+    LastFailure  time.Time
+}
+
+// Peer is a connected peer of a torrent
+type Peer struct {
+    Address   string
+    Port      int
+    Client    string
+    Progress  float64
+    DownSpeed int64
+    UpSpeed   int64
+    Encrypted bool
+    Incoming  bool
+}
+
+// GetTorrentFiles returns the file list of a torrent
+func (s *TorrentService) GetTorrentFiles(hash string) ([]TorrentFile, error) {
+    rows, err := s.client.FileMulticall(strings.ToUpper(hash),
+        "f.path=",
+        "f.size_bytes=",
+        "f.size_chunks=",
+        "f.completed_chunks=",
+        "f.range_first=",
+        "f.range_second=",
+        "f.priority=",
+    )
+    if err != nil {
+        return nil, err
+    }
+
+    files := make([]TorrentFile, 0, len(rows))
+    for i, row := range rows {
+        if len(row) < 7 {
+            continue
+        }
+        f := TorrentFile{
+            Index:           i,
+            Path:            rtorrent.AsString(row[0]),
+            Size:            rtorrent.AsInt(row[1]),
+            Chunks:          rtorrent.AsInt(row[2]),
+            CompletedChunks: rtorrent.AsInt(row[3]),
+            FirstChunk:      rtorrent.AsInt(row[4]),
+            LastChunk:       rtorrent.AsInt(row[5]),
+            Priority:        int(rtorrent.AsInt(row[6])),
+        }
+        if f.Chunks > 0 {
+            f.Progress = float64(f.CompletedChunks) / float64(f.Chunks) * 100
+        }
+        files = append(files, f)
+    }
+    return files, nil
+}
+
+// GetTrackers returns the trackers of a torrent
+func (s *TorrentService) GetTrackers(hash string) ([]Tracker, error) {
+    rows, err := s.client.TrackerMulticall(strings.ToUpper(hash),
+        "t.url=",
+        "t.type=",
+        "t.is_enabled=",
+        "t.group=",
+        "t.scrape_complete=",
+        "t.scrape_incomplete=",
+        "t.scrape_downloaded=",
+        "t.failed_counter=",
+        "t.success_counter=",
+        "t.success_time_last=",
+        "t.failed_time_last=",
+    )
+    if err != nil {
+        return nil, err
+    }
+
+    trackers := make([]Tracker, 0, len(rows))
+    for i, row := range rows {
+        if len(row) < 11 {
+            continue
+        }
+        trackers = append(trackers, Tracker{
+            Index:        i,
+            URL:          rtorrent.AsString(row[0]),
+            Type:         int(rtorrent.AsInt(row[1])),
+            Enabled:      rtorrent.AsBool(row[2]),
+            Group:        int(rtorrent.AsInt(row[3])),
+            Seeders:      int(rtorrent.AsInt(row[4])),
+            Leechers:     int(rtorrent.AsInt(row[5])),
+            Downloaded:   int(rtorrent.AsInt(row[6])),
+            FailedCount:  int(rtorrent.AsInt(row[7])),
+            SuccessCount: int(rtorrent.AsInt(row[8])),
+            LastSuccess:  unixTime(rtorrent.AsInt(row[9])),
+            LastFailure:  unixTime(rtorrent.AsInt(row[10])),
+        })
+    }
+    return trackers, nil
+}
+
+// GetPeers returns the connected peers of a torrent
+func (s *TorrentService) GetPeers(hash string) ([]Peer, error) {
+    rows, err := s.client.PeerMulticall(strings.ToUpper(hash),
+        "p.address=",
+        "p.port=",
+        "p.client_version=",
+        "p.completed_percent=",
+        "p.down_rate=",
+        "p.up_rate=",
+        "p.is_encrypted=",
+        "p.is_incoming=",
+    )
+    if err != nil {
+        return nil, err
+    }
+
+    peers := make([]Peer, 0, len(rows))
+    for _, row := range rows {
+        if len(row) < 8 {
+            continue
+        }
+        peers = append(peers, Peer{
+            Address:   rtorrent.AsString(row[0]),
+            Port:      int(rtorrent.AsInt(row[1])),
+            Client:    rtorrent.AsString(row[2]),
+            Progress:  float64(rtorrent.AsInt(row[3])),
+            DownSpeed: rtorrent.AsInt(row[4]),
+            UpSpeed:   rtorrent.AsInt(row[5]),
+            Encrypted: rtorrent.AsBool(row[6]),
+            Incoming:  rtorrent.AsBool(row[7]),
+        })
+    }
+    return peers, nil
+}
@@ -0,0 +1,157 @@
+// internal/services/errors.go
+package services
+
+import (
+    "strconv"
+    "strings"
+)
+
+// ErrorCategory classifies why a torrent is in an error state
+type ErrorCategory string
+
+const (
+    ErrorNone         ErrorCategory = ""
+    ErrorUnregistered ErrorCategory = "unregistered"  // tracker doesn't know the torrent
+    ErrorTrackerDown  ErrorCategory = "tracker_down"  // trackers unreachable or failing
+    ErrorAuth         ErrorCategory = "auth_failure"  // passkey or account rejected
+    ErrorDiskFull     ErrorCategory = "disk_full"
+    ErrorIO           ErrorCategory = "io_error"
+    ErrorDataMissing  ErrorCategory = "data_missing"  // files gone or hash check failed
+    ErrorOther        ErrorCategory = "other"         // a message we don't recognise
+)
+
+// ErrorCategories lists the categories in display order
+var ErrorCategories = []ErrorCategory{
+    ErrorUnregistered,
+    ErrorTrackerDown,
+    ErrorAuth,
+    ErrorDiskFull,
+    ErrorIO,
+    ErrorDataMissing,
+    ErrorOther,
+}
+
+// Title returns the display name of a category
+func (c ErrorCategory) Title() string {
+    switch c {
+    case ErrorUnregistered:
+        return "Unregistered"
+    case ErrorTrackerDown:
+        return "Tracker Down"
+    case ErrorAuth:
+        return "Authentication Failure"
+    case ErrorDiskFull:
+        return "Disk Full"
+    case ErrorIO:
+        return "I/O Error"
+    case ErrorDataMissing:
+        return "Data Missing"
+    case ErrorOther:
+        return "Error"
+    }
+    return ""
+}
+
+// errorPatterns map lower-cased message fragments to categories. They are
+// checked in order, so the specific tracker replies come before generic
+// network failures: "unregistered torrent" must not end up as tracker down
+// just because the message also says "failure reason".
+var errorPatterns = []struct {
+    category  ErrorCategory
+    fragments []string
+}{
+    {ErrorDiskFull, []string{
+        "no space left", "disk full", "not enough space", "quota exceeded",
+    }},
+    {ErrorDataMissing, []string{
+        "no such file or directory", "hash check on download completion found bad chunks",
+        "file not found", "files missing",
+    }},
+    {ErrorIO, []string{
+        "input/output error", "i/o error", "read-only file system", "permission denied",
+        "storage error", "too many open files", "bad file descriptor",
+    }},
+    {ErrorUnregistered, []string{
+        "unregistered torrent", "torrent not registered", "not registered with this tracker",
+        "torrent not found", "unknown torrent", "info_hash not found", "infohash not found",
+        "torrent does not exist", "torrent has been deleted", "trumped", "dupe", "nuked",
+    }},
+    {ErrorAuth, []string{
+        "passkey", "invalid key", "not authorized", "unauthorized", "access denied",
+        "authentication", "login", "banned", "account disabled", "forbidden", "client not allowed",
+        "client is not allowed",
+    }},
+    {ErrorTrackerDown, []string{
+        "timed out", "timeout", "connection refused", "could not resolve", "couldn't resolve",
+        "couldn't connect", "could not connect", "no route to host", "bad gateway",
+        "service unavailable", "gateway time-out", "internal server error", "maintenance",
+        "try again later", "ssl connect error", "connection reset", "tracker is down",
+        "server returned nothing", "empty reply",
+    }},
+}
+
+// ClassifyMessage maps a d.message text to an error category
+func ClassifyMessage(message string) ErrorCategory {
+    message = strings.ToLower(strings.TrimSpace(message))
+    if message == "" {
+        return ErrorNone
+    }
+    for _, p := range errorPatterns {
+        for _, fragment := range p.fragments {
+            if strings.Contains(message, fragment) {
+                return p.category
+            }
+        }
+    }
+    return ErrorOther
+}
+
+// classifyTorrent determines the error category of a torrent from its
+// message, its trackers and its hash check result
+func classifyTorrent(t *Torrent, trackersFailing bool) ErrorCategory {
+    if category := ClassifyMessage(t.Message); category != ErrorNone && category != ErrorOther {
+        return category
+    }
+
+    switch {
+    case t.HashFailed:
+        return ErrorDataMissing
+    case !t.Complete && !t.Hashing && !t.FinishedAt.IsZero() && t.WantedChunks > 0:
+        // Finished once but a later hash check found chunks of wanted
+        // files missing. Files set to "don't download" aren't counted in
+        // d.wanted_chunks, so partial selections don't end up here.
+        return ErrorDataMissing
+    case trackersFailing && !t.Private:
+        // Public torrents keep working through DHT and PEX
+        return ClassifyMessage(t.Message)
+    case trackersFailing:
+        return ErrorTrackerDown
+    }
+    return ClassifyMessage(t.Message)
+}
+
+// trackersFailing reports whether every enabled tracker failed its last
+// announce. failed is the "enabled:type:failed_counter" list built by the
+// multicall; rTorrent resets the counter on a successful announce. DHT
+// entries are skipped, a torrent without trackers is never failing.
+func trackersFailing(failed string) bool {
+    enabled := 0
+    for _, entry := range strings.Split(failed, "#") {
+        parts := strings.Split(strings.TrimSpace(entry), ":")
+        if len(parts) != 3 || parts[0] != "1" || parts[1] == "3" {
+            continue
+        }
+        enabled++
+        if n, err := strconv.Atoi(parts[2]); err != nil || n == 0 {
+            return false
+        }
+    }
+    return enabled > 0
+}
+
+func init() {
+    for _, c := range ErrorCategories {
+        category := c
+        RegisterListFilter(string(category), func(t *Torrent) bool { return t.Error == category })
+    }
+}
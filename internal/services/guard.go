@@ -0,0 +1,50 @@
+// internal/services/guard.go
+package services
+
+import (
+    "fmt"
+    "strings"
+)
+
+// ProtectedError is returned when a remove guard refuses to remove a torrent
+type ProtectedError struct {
+    Hash   string
+    Name   string
+    Reason string
+}
+
+func (e *ProtectedError) Error() string {
+    return fmt.Sprintf("%s is protected: %s", e.Name, e.Reason)
+}
+
+// RemoveGuard returns an error, usually a *ProtectedError, when a torrent
+// must not be removed. deleteData tells whether its data would go too.
+type RemoveGuard func(t *Torrent, deleteData bool) error
+
+// SetRemoveGuard installs a guard consulted by EraseTorrents. nil removes it.
+func (s *TorrentService) SetRemoveGuard(g RemoveGuard) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.removeGuard = g
+}
+
+// checkRemove runs the remove guard over hashes, returning the first refusal
+func (s *TorrentService) checkRemove(hashes []string, deleteData bool) error {
+    s.mu.RLock()
+    g := s.removeGuard
+    s.mu.RUnlock()
+    if g == nil {
+        return nil
+    }
+
+    for _, hash := range hashes {
+        t, ok := s.GetTorrent(strings.ToUpper(hash))
+        if !ok {
+            continue
+        }
+        if err := g(t, deleteData); err != nil {
+            return err
+        }
+    }
+    return nil
+}
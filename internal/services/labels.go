@@ -0,0 +1,154 @@
+// internal/services/labels.go
+package services
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+
+    "rutorrent-web/internal/rtorrent"
+)
+
+// LabelSeparator separates the levels of nested labels such as "tv/anime"
+const LabelSeparator = "/"
+
+// NormalizeLabel trims whitespace around every level of a label and drops
+// empty levels, so " tv / anime/" becomes "tv/anime"
+func NormalizeLabel(label string) string {
+    parts := strings.Split(label, LabelSeparator)
+    clean := parts[:0]
+    for _, p := range parts {
+        if p = strings.TrimSpace(p); p != "" {
+            clean = append(clean, p)
+        }
+    }
+    return strings.Join(clean, LabelSeparator)
+}
+
+// LabelParent returns the parent of a nested label, "" for top-level labels
+func LabelParent(label string) string {
+    if i := strings.LastIndex(label, LabelSeparator); i >= 0 {
+        return label[:i]
+    }
+    return ""
+}
+
+// LabelWithin reports whether label is parent or nested below it
+func LabelWithin(label, parent string) bool {
+    return label == parent || strings.HasPrefix(label, parent+LabelSeparator)
+}
+
+// GetLabels returns every label in use together with the parents of nested
+// labels, sorted
+func (s *TorrentService) GetLabels() ([]string, error) {
+    seen := make(map[string]bool)
+    for _, t := range s.Snapshot() {
+        for label := NormalizeLabel(t.Label); label != ""; label = LabelParent(label) {
+            seen[label] = true
+        }
+    }
+
+    labels := make([]string, 0, len(seen))
+    for label := range seen {
+        labels = append(labels, label)
+    }
+    sort.Slice(labels, func(i, j int) bool {
+        return compareFold(labels[i], labels[j]) < 0
+    })
+    return labels, nil
+}
+
+// RenameLabel moves a label, including everything nested below it, to a new
+// name in one batch. Renaming onto an existing label merges the two.
+func (s *TorrentService) RenameLabel(from, to string) (int, error) {
+    from, to = NormalizeLabel(from), NormalizeLabel(to)
+    if from == "" || to == "" {
+        return 0, fmt.Errorf("label names must not be empty")
+    }
+    if from == to {
+        return 0, nil
+    }
+    if LabelWithin(to, from) {
+        return 0, fmt.Errorf("cannot move label %q below itself", from)
+    }
+
+    return s.relabel(func(label string) (string, bool) {
+        if !LabelWithin(label, from) {
+            return "", false
+        }
+        return to + label[len(from):], true
+    })
+}
+
+// MergeLabels moves the torrents of several labels, and their nested labels,
+// into target
+func (s *TorrentService) MergeLabels(sources []string, target string) (int, error) {
+    target = NormalizeLabel(target)
+    if target == "" {
+        return 0, fmt.Errorf("target label must not be empty")
+    }
+
+    var from []string
+    for _, src := range sources {
+        if src = NormalizeLabel(src); src != "" && src != target {
+            if LabelWithin(target, src) {
+                return 0, fmt.Errorf("cannot merge label %q into its own child %q", src, target)
+            }
+            from = append(from, src)
+        }
+    }
+    if len(from) == 0 {
+        return 0, nil
+    }
+
+    return s.relabel(func(label string) (string, bool) {
+        for _, src := range from {
+            if LabelWithin(label, src) {
+                return target + label[len(src):], true
+            }
+        }
+        return "", false
+    })
+}
+
+// DeleteLabel removes a label and the labels nested below it. Affected
+// torrents fall back to the parent of the deleted label, or no label at all
+// for top-level labels.
+func (s *TorrentService) DeleteLabel(label string) (int, error) {
+    label = NormalizeLabel(label)
+    if label == "" {
+        return 0, fmt.Errorf("label name must not be empty")
+    }
+    parent := LabelParent(label)
+
+    return s.relabel(func(current string) (string, bool) {
+        if !LabelWithin(current, label) {
+            return "", false
+        }
+        return parent, true
+    })
+}
+
+// relabel applies a label mapping to every torrent of the snapshot in a
+// single system.multicall and returns the number of torrents changed
+func (s *TorrentService) relabel(mapping func(label string) (string, bool)) (int, error) {
+    var calls []rtorrent.MethodCall
+    for _, t := range s.Snapshot() {
+        label := NormalizeLabel(t.Label)
+        if label == "" {
+            continue
+        }
+        next, ok := mapping(label)
+        if !ok || next == t.Label {
+            continue
+        }
+        calls = append(calls, rtorrent.MethodCall{
+            Method: "d.custom1.set",
+            Params: []interface{}{t.Hash, EncodeLabel(next)},
+        })
+    }
+    if err := s.batch(calls); err != nil {
+        return 0, err
+    }
+    return len(calls), nil
+}
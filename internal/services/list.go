@@ -0,0 +1,415 @@
+// internal/services/list.go
+package services
+
+import (
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "sort"
+    "strings"
+    "sync"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor can't be resolved,
+// usually because the torrent it points at was removed or filtered out
+var ErrInvalidCursor = errors.New("invalid or expired cursor")
+
+// SortKey is one level of a multi-key sort
+type SortKey struct {
+    Field   string
+    Reverse bool
+}
+
+// ListQuery describes a page of the torrent list
+type ListQuery struct {
+    Filter string    // named filter, see RegisterListFilter
+    Label  string    // label including nested labels, "" for any
+    Search string    // search query, see ParseQuery
+    Sort   []SortKey // primary key first, hash is always the final tiebreaker
+    Offset int
+    Limit  int    // 0 returns everything after Offset
+    Cursor string // continues after the torrent a previous page ended with
+}
+
+// ListPage is an ordered slice of the torrent list
+type ListPage struct {
+    Torrents   []*Torrent
+    Total      int    // torrents in the snapshot
+    Filtered   int    // torrents matching the query
+    Offset     int    // position of Torrents[0] in the filtered list
+    NextCursor string // empty on the last page
+    Revision   int64  // snapshot the page was built from
+}
+
+// ListFilter selects torrents for a named filter
+type ListFilter func(t *Torrent) bool
+
+// listCompare orders two torrents by a single field
+type listCompare func(a, b *Torrent) int
+
+var (
+    listFilters = map[string]ListFilter{
+        "all":         func(t *Torrent) bool { return true },
+        "downloading": func(t *Torrent) bool { return !t.Complete && t.Status() == "downloading" },
+        "seeding":     func(t *Torrent) bool { return t.Complete && t.Status() == "seeding" },
+        "completed":   func(t *Torrent) bool { return t.Complete },
+        "incomplete":  func(t *Torrent) bool { return !t.Complete },
+        "active":      func(t *Torrent) bool { return t.DownloadRate > 0 || t.UploadRate > 0 },
+        "inactive":    func(t *Torrent) bool { return t.DownloadRate == 0 && t.UploadRate == 0 },
+        "stopped":     func(t *Torrent) bool { return t.Status() == "stopped" },
+        "paused":      func(t *Torrent) bool { return t.Status() == "paused" },
+        "checking":    func(t *Torrent) bool { return t.Hashing },
+        "error":       func(t *Torrent) bool { return t.Error != ErrorNone },
+    }
+    listFiltersMu sync.RWMutex
+
+    listSortFields = map[string]listCompare{
+        "name":       func(a, b *Torrent) int { return compareFold(a.Name, b.Name) },
+        "size":       func(a, b *Torrent) int { return compareInt(a.Size, b.Size) },
+        "downloaded": func(a, b *Torrent) int { return compareInt(a.DownTotal, b.DownTotal) },
+        "uploaded":   func(a, b *Torrent) int { return compareInt(a.UpTotal, b.UpTotal) },
+        "progress":   func(a, b *Torrent) int { return compareFloat(a.Progress, b.Progress) },
+        "ratio":      func(a, b *Torrent) int { return compareFloat(a.Ratio, b.Ratio) },
+        "down_rate":  func(a, b *Torrent) int { return compareInt(a.DownloadRate, b.DownloadRate) },
+        "up_rate":    func(a, b *Torrent) int { return compareInt(a.UploadRate, b.UploadRate) },
+        "speed": func(a, b *Torrent) int {
+            return compareInt(a.DownloadRate+a.UploadRate, b.DownloadRate+b.UploadRate)
+        },
+        "seeds":    func(a, b *Torrent) int { return compareInt(int64(a.Seeders), int64(b.Seeders)) },
+        "peers":    func(a, b *Torrent) int { return compareInt(int64(a.Peers), int64(b.Peers)) },
+        "label":    func(a, b *Torrent) int { return compareFold(a.Label, b.Label) },
+        "status":   func(a, b *Torrent) int { return strings.Compare(a.Status(), b.Status()) },
+        "priority": func(a, b *Torrent) int { return compareInt(int64(a.Priority), int64(b.Priority)) },
+        "eta":      func(a, b *Torrent) int { return compareInt(a.eta(), b.eta()) },
+        "added":    func(a, b *Torrent) int { return compareInt(a.AddedAt.Unix(), b.AddedAt.Unix()) },
+        "finished": func(a, b *Torrent) int { return compareInt(a.FinishedAt.Unix(), b.FinishedAt.Unix()) },
+        "created":  func(a, b *Torrent) int { return compareInt(a.CreatedAt.Unix(), b.CreatedAt.Unix()) },
+    }
+)
+
+// RegisterListFilter adds or replaces a named list filter
+func RegisterListFilter(name string, filter ListFilter) {
+    listFiltersMu.Lock()
+    defer listFiltersMu.Unlock()
+    listFilters[name] = filter
+}
+
+// ParseSort parses a comma separated sort specification such as
+// "label,-size": fields in priority order, a leading "-" sorts descending
+func ParseSort(spec string) ([]SortKey, error) {
+    var keys []SortKey
+    for _, part := range strings.Split(spec, ",") {
+        part = strings.TrimSpace(part)
+        if part == "" {
+            continue
+        }
+        key := SortKey{Field: part}
+        if strings.HasPrefix(part, "-") {
+            key = SortKey{Field: part[1:], Reverse: true}
+        }
+        if _, ok := listSortFields[key.Field]; !ok {
+            return nil, fmt.Errorf("unknown sort field: %s", key.Field)
+        }
+        keys = append(keys, key)
+    }
+    return keys, nil
+}
+
+// List filters, sorts and paginates the current snapshot. Sorted results are
+// cached per query until the snapshot changes, so paging through a large
+// list only pays for the sort once.
+func (s *TorrentService) List(q ListQuery) (*ListPage, error) {
+    var filter ListFilter
+    if q.Filter != "" {
+        listFiltersMu.RLock()
+        filter = listFilters[q.Filter]
+        listFiltersMu.RUnlock()
+        if filter == nil {
+            return nil, fmt.Errorf("unknown filter: %s", q.Filter)
+        }
+    }
+    for _, key := range q.Sort {
+        if _, ok := listSortFields[key.Field]; !ok {
+            return nil, fmt.Errorf("unknown sort field: %s", key.Field)
+        }
+    }
+    match, err := ParseQuery(q.Search)
+    if err != nil {
+        return nil, err
+    }
+
+    s.mu.RLock()
+    revision := s.revision
+    total := len(s.torrents)
+    s.mu.RUnlock()
+
+    key := listCacheKey(q)
+    sorted, ok := s.lists.get(key, revision)
+    if !ok {
+        var snapshot []*Torrent
+        snapshot, revision = s.snapshotAt()
+        total = len(snapshot)
+        sorted = filterAndSort(snapshot, q, filter, match)
+        s.lists.put(key, revision, sorted)
+    }
+
+    offset := q.Offset
+    if q.Cursor != "" {
+        hash, err := decodeCursor(q.Cursor)
+        if err != nil {
+            return nil, err
+        }
+        offset = -1
+        for i, t := range sorted {
+            if t.Hash == hash {
+                offset = i + 1
+                break
+            }
+        }
+        if offset < 0 {
+            return nil, ErrInvalidCursor
+        }
+    }
+    if offset < 0 {
+        offset = 0
+    }
+    if offset > len(sorted) {
+        offset = len(sorted)
+    }
+
+    end := len(sorted)
+    if q.Limit > 0 && offset+q.Limit < end {
+        end = offset + q.Limit
+    }
+
+    page := &ListPage{
+        Torrents: sorted[offset:end:end],
+        Total:    total,
+        Filtered: len(sorted),
+        Offset:   offset,
+        Revision: revision,
+    }
+    if end < len(sorted) && end > offset {
+        page.NextCursor = encodeCursor(sorted[end-1].Hash)
+    }
+    return page, nil
+}
+
+// Count returns the number of torrents matching a search query without
+// sorting, for category panels that only show totals. Counts are kept
+// until the snapshot changes, so panels rendered on every page load only
+// evaluate each query once per refresh.
+func (s *TorrentService) Count(query string) (int, error) {
+    s.mu.RLock()
+    revision := s.revision
+    s.mu.RUnlock()
+    if n, ok := s.counts.get(query, revision); ok {
+        return n, nil
+    }
+
+    match, err := ParseQuery(query)
+    if err != nil {
+        return 0, err
+    }
+
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    n := 0
+    for _, t := range s.torrents {
+        if match(t) {
+            n++
+        }
+    }
+    s.counts.put(query, s.revision, n)
+    return n, nil
+}
+
+// snapshotAt returns the snapshot together with its revision
+func (s *TorrentService) snapshotAt() ([]*Torrent, int64) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    torrents := make([]*Torrent, 0, len(s.torrents))
+    for _, t := range s.torrents {
+        torrents = append(torrents, t)
+    }
+    return torrents, s.revision
+}
+
+func filterAndSort(snapshot []*Torrent, q ListQuery, filter ListFilter, match Predicate) []*Torrent {
+    result := make([]*Torrent, 0, len(snapshot))
+    for _, t := range snapshot {
+        if q.Label != "" && !LabelWithin(t.Label, q.Label) {
+            continue
+        }
+        if filter != nil && !filter(t) {
+            continue
+        }
+        if !match(t) {
+            continue
+        }
+        result = append(result, t)
+    }
+
+    compares := make([]listCompare, len(q.Sort))
+    for i, key := range q.Sort {
+        compares[i] = listSortFields[key.Field]
+    }
+
+    // The hash tiebreaker makes the order total, so pages don't shuffle
+    // between requests even though the snapshot map is unordered
+    sort.Slice(result, func(i, j int) bool {
+        a, b := result[i], result[j]
+        for k, cmp := range compares {
+            c := cmp(a, b)
+            if q.Sort[k].Reverse {
+                c = -c
+            }
+            if c != 0 {
+                return c < 0
+            }
+        }
+        return a.Hash < b.Hash
+    })
+    return result
+}
+
+// eta returns the seconds left to download, or max int64 when unknown
+func (t *Torrent) eta() int64 {
+    if t.Complete || t.LeftBytes == 0 {
+        return 0
+    }
+    if t.DownloadRate <= 0 {
+        return 1<<63 - 1
+    }
+    return t.LeftBytes / t.DownloadRate
+}
+
+func compareInt(a, b int64) int {
+    switch {
+    case a < b:
+        return -1
+    case a > b:
+        return 1
+    }
+    return 0
+}
+
+func compareFloat(a, b float64) int {
+    switch {
+    case a < b:
+        return -1
+    case a > b:
+        return 1
+    }
+    return 0
+}
+
+func compareFold(a, b string) int {
+    if c := strings.Compare(strings.ToLower(a), strings.ToLower(b)); c != 0 {
+        return c
+    }
+    return strings.Compare(a, b)
+}
+
+func encodeCursor(hash string) string {
+    return base64.RawURLEncoding.EncodeToString([]byte(hash))
+}
+
+func decodeCursor(cursor string) (string, error) {
+    b, err := base64.RawURLEncoding.DecodeString(cursor)
+    if err != nil || len(b) == 0 {
+        return "", ErrInvalidCursor
+    }
+    return string(b), nil
+}
+
+func listCacheKey(q ListQuery) string {
+    var b strings.Builder
+    b.WriteString(q.Filter)
+    b.WriteByte(0)
+    b.WriteString(q.Label)
+    b.WriteByte(0)
+    b.WriteString(q.Search)
+    for _, key := range q.Sort {
+        b.WriteByte(0)
+        if key.Reverse {
+            b.WriteByte('-')
+        }
+        b.WriteString(key.Field)
+    }
+    return b.String()
+}
+
+// listCacheSize bounds the number of distinct queries kept per revision
+const listCacheSize = 16
+
+// listCache keeps sorted results for the current snapshot revision. Entries
+// of older revisions are dropped on the first lookup after a refresh.
+type listCache struct {
+    revision int64
+    entries  map[string][]*Torrent
+    mu       sync.Mutex
+}
+
+func (c *listCache) get(key string, revision int64) ([]*Torrent, bool) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    if c.revision != revision {
+        return nil, false
+    }
+    result, ok := c.entries[key]
+    return result, ok
+}
+
+func (c *listCache) put(key string, revision int64, result []*Torrent) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    if revision < c.revision {
+        return
+    }
+    if revision != c.revision || c.entries == nil || len(c.entries) >= listCacheSize {
+        c.revision = revision
+        c.entries = make(map[string][]*Torrent)
+    }
+    c.entries[key] = result
+}
+
+// countCacheSize bounds the number of distinct queries counted per revision
+const countCacheSize = 256
+
+// countCache keeps query counts for the current snapshot revision, the way
+// listCache keeps sorted results
+type countCache struct {
+    revision int64
+    counts   map[string]int
+    mu       sync.Mutex
+}
+
+func (c *countCache) get(query string, revision int64) (int, bool) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    if c.revision != revision {
+        return 0, false
+    }
+    n, ok := c.counts[query]
+    return n, ok
+}
+
+func (c *countCache) put(query string, revision int64, n int) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    if revision < c.revision {
+        return
+    }
+    if revision != c.revision || c.counts == nil || len(c.counts) >= countCacheSize {
+        c.revision = revision
+        c.counts = make(map[string]int)
+    }
+    c.counts[query] = n
+}
@@ -0,0 +1,123 @@
+// internal/services/magnet.go
+package services
+
+import (
+    "errors"
+    "sync"
+    "time"
+
+    "rutorrent-web/internal/magnet"
+    "rutorrent-web/internal/rtorrent"
+)
+
+// selectionTimeout drops file selections of magnets that never showed up
+// in the snapshot, or never got their metadata
+const selectionTimeout = 7 * 24 * time.Hour
+
+var (
+    // ErrTorrentExists is returned when adding a torrent that is already
+    // loaded
+    ErrTorrentExists = errors.New("torrent is already loaded")
+    // ErrV2Only is returned for magnet links without a v1 info hash, which
+    // rTorrent can't download
+    ErrV2Only = errors.New("magnet link has only a v2 info hash, rTorrent needs v1")
+)
+
+// AddMagnetLink loads a parsed magnet link. Links of torrents that are
+// already loaded are rejected with ErrTorrentExists; the so= file
+// selection is applied once rTorrent has fetched the metadata.
+func (s *TorrentService) AddMagnetLink(m *magnet.Magnet, opts *AddTorrentOptions) error {
+    errs, err := s.AddTorrents([]AddSource{{Magnet: m}}, opts)
+    if err != nil {
+        return err
+    }
+    return errs[0]
+}
+
+// checkMagnet returns the URI rTorrent loads for a magnet link
+func (s *TorrentService) checkMagnet(m *magnet.Magnet) (string, error) {
+    if m.InfoHash == "" {
+        return "", ErrV2Only
+    }
+    if _, ok := s.GetTorrent(m.InfoHash); ok {
+        return "", ErrTorrentExists
+    }
+    // rTorrent only understands btih
+    v1 := *m
+    v1.InfoHashV2 = ""
+    v1.SelectOnly = nil
+    return v1.String(), nil
+}
+
+// selection is a so= file selection waiting for metadata
+type selection struct {
+    files []int
+    added time.Time
+}
+
+// selections keeps file selections of magnets by info hash until their
+// metadata has arrived. They live in memory only: a restart before the
+// metadata arrives downloads every file.
+type selections struct {
+    pending map[string]selection
+    mu      sync.Mutex
+}
+
+func (c *selections) add(hash string, files []int) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    if c.pending == nil {
+        c.pending = make(map[string]selection)
+    }
+    c.pending[hash] = selection{files: files, added: time.Now()}
+}
+
+// ready removes and returns the selections of torrents that have their
+// metadata
+func (c *selections) ready(torrents map[string]*Torrent) map[string][]int {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    var ready map[string][]int
+    for hash, sel := range c.pending {
+        t, ok := torrents[hash]
+        switch {
+        case ok && !t.Meta:
+            if ready == nil {
+                ready = make(map[string][]int)
+            }
+            ready[hash] = sel.files
+            delete(c.pending, hash)
+        case time.Since(sel.added) > selectionTimeout:
+            delete(c.pending, hash)
+        }
+    }
+    return ready
+}
+
+// applySelections turns off the files a magnet's so= left out once its
+// torrent has metadata
+func (s *TorrentService) applySelections(torrents map[string]*Torrent) {
+    for hash, files := range s.selections.ready(torrents) {
+        count, err := s.client.CallValues("d.size_files", hash)
+        if err != nil {
+            Warnf("failed to apply file selection of %s: %v", hash, err)
+            continue
+        }
+
+        selected := make(map[int]bool, len(files))
+        for _, i := range files {
+            selected[i] = true
+        }
+        var off []int
+        for i := 0; i < int(rtorrent.AsInt(count)); i++ {
+            if !selected[i] {
+                off = append(off, i)
+            }
+        }
+        if err := s.SetFilePriorities(hash, off, 0); err != nil {
+            Warnf("failed to apply file selection of %s: %v", hash, err)
+        }
+    }
+}
@@ -0,0 +1,190 @@
+// internal/services/move.go
+package services
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "errors"
+    "fmt"
+    "io"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "strings"
+    "syscall"
+
+    "rutorrent-web/internal/rtorrent"
+)
+
+// MoveData moves the data of a torrent so that its base path becomes dest
+// and points rTorrent at the new location. For single-file torrents dest is
+// the path of the file and must end in the torrent's name. The torrent is
+// closed during the move and restarted afterwards if it was active. Moves
+// across filesystems copy the data, check the copy and then delete the
+// original.
+func (s *TorrentService) MoveData(hash, dest string) error {
+    hash = strings.ToUpper(hash)
+    dest = filepath.Clean(dest)
+    if !filepath.IsAbs(dest) {
+        return fmt.Errorf("destination must be an absolute path: %s", dest)
+    }
+
+    values, err := s.client.SystemMulticall([]rtorrent.MethodCall{
+        {Method: "d.base_path", Params: []interface{}{hash}},
+        {Method: "d.is_multi_file", Params: []interface{}{hash}},
+        {Method: "d.name", Params: []interface{}{hash}},
+        {Method: "d.state", Params: []interface{}{hash}},
+    })
+    if err != nil {
+        return err
+    }
+    if err := rtorrent.FirstError(values); err != nil {
+        return err
+    }
+    src := rtorrent.AsString(values[0])
+    multi := rtorrent.AsBool(values[1])
+    name := rtorrent.AsString(values[2])
+    active := rtorrent.AsInt(values[3]) != 0
+
+    if src == "" {
+        return fmt.Errorf("torrent %s has no data yet", hash)
+    }
+    if filepath.Clean(src) == dest {
+        return nil
+    }
+    if !multi && filepath.Base(dest) != name {
+        return fmt.Errorf("single-file destination must end in %q", name)
+    }
+    if _, err := os.Lstat(dest); err == nil {
+        return fmt.Errorf("destination already exists: %s", dest)
+    }
+
+    if err := s.eachTorrent([]string{hash}, "d.stop", "d.close"); err != nil {
+        return err
+    }
+    restart := func() {
+        if active {
+            if err := s.StartTorrents([]string{hash}); err != nil {
+                Warnf("failed to restart %s after moving: %v", hash, err)
+            }
+        }
+    }
+
+    if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+        restart()
+        return err
+    }
+    if err := moveTree(src, dest); err != nil {
+        restart()
+        return err
+    }
+
+    // d.directory is the parent of a single file but the base path of a
+    // multi-file torrent; d.directory_base.set takes the latter as is
+    set := rtorrent.MethodCall{Method: "d.directory.set", Params: []interface{}{hash, filepath.Dir(dest)}}
+    if multi {
+        set = rtorrent.MethodCall{Method: "d.directory_base.set", Params: []interface{}{hash, dest}}
+    }
+    if err := s.batch([]rtorrent.MethodCall{set}); err != nil {
+        return fmt.Errorf("data moved to %s, but rTorrent still points at the old location: %w", dest, err)
+    }
+    restart()
+    return nil
+}
+
+// moveTree renames src to dst, falling back to copy, verify and delete when
+// they are on different filesystems
+func moveTree(src, dst string) error {
+    err := os.Rename(src, dst)
+    if err == nil || !errors.Is(err, syscall.EXDEV) {
+        return err
+    }
+
+    if err := copyTree(src, dst); err != nil {
+        os.RemoveAll(dst)
+        return fmt.Errorf("failed to copy %s: %w", src, err)
+    }
+    if !isSafeDataPath(src) {
+        return fmt.Errorf("copied to %s but refusing to delete %q", dst, src)
+    }
+    return os.RemoveAll(src)
+}
+
+// copyTree copies a file or directory, checking every file against the
+// original after it has been written
+func copyTree(src, dst string) error {
+    return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+        if err != nil {
+            return err
+        }
+        rel, err := filepath.Rel(src, path)
+        if err != nil {
+            return err
+        }
+        target := filepath.Join(dst, rel)
+        info, err := d.Info()
+        if err != nil {
+            return err
+        }
+
+        switch {
+        case d.IsDir():
+            return os.MkdirAll(target, info.Mode().Perm()|0700)
+        case info.Mode().IsRegular():
+            if err := copyFile(path, target, info); err != nil {
+                return err
+            }
+            return os.Chtimes(target, info.ModTime(), info.ModTime())
+        }
+        return fmt.Errorf("cannot copy %s: not a regular file", path)
+    })
+}
+
+// copyFile copies one file and reads the copy back to compare checksums
+func copyFile(src, dst string, info fs.FileInfo) error {
+    in, err := os.Open(src)
+    if err != nil {
+        return err
+    }
+    defer in.Close()
+
+    out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+    if err != nil {
+        return err
+    }
+    sum := sha256.New()
+    if _, err := io.Copy(out, io.TeeReader(in, sum)); err != nil {
+        out.Close()
+        return err
+    }
+    if err := out.Sync(); err != nil {
+        out.Close()
+        return err
+    }
+    if err := out.Close(); err != nil {
+        return err
+    }
+
+    written, err := checksum(dst)
+    if err != nil {
+        return err
+    }
+    if !bytes.Equal(written, sum.Sum(nil)) {
+        return fmt.Errorf("copy of %s does not match the original", src)
+    }
+    return nil
+}
+
+func checksum(path string) ([]byte, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    sum := sha256.New()
+    if _, err := io.Copy(sum, f); err != nil {
+        return nil, err
+    }
+    return sum.Sum(nil), nil
+}
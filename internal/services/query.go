@@ -0,0 +1,612 @@
+// internal/services/query.go
+package services
+
+import (
+    "fmt"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+    "unicode"
+)
+
+// The torrent search language. Terms are combined with AND unless separated
+// by OR; AND binds tighter than OR and parentheses group:
+//
+//	ubuntu                   name contains "ubuntu"
+//	"exact phrase"           name contains the phrase
+//	-excluded                negates the following term or group
+//	label:tv                 label is "tv" or nested below it ("tv/shows")
+//	tracker:example.org      a tracker host contains the value (tracker= for
+//	                         the host or registered domain of the primary
+//	                         tracker, as grouped in the tracker panel)
+//	state:seeding            any list filter name (seeding, stopped, error, ...)
+//	state:unregistered       an error category (tracker_down, disk_full, ...)
+//	path:/mnt/a              data lies below the directory
+//	name:foo hash:ABC        substring of the name, prefix of the hash
+//	size>10GiB ratio<1.0     numeric comparison with <, <=, >, >=, =
+//	added:<7d                added less than 7 days ago (also h, m, w, y)
+//	added>2024-01-01         added after the date
+//	private:yes              boolean fields accept yes/no, true/false, 1/0
+//
+// Values containing spaces can be quoted: path:"/mnt/my disk".
+
+// QueryError describes a syntax error in a search query
+type QueryError struct {
+    Pos int    // byte offset into the query
+    Msg string
+}
+
+func (e *QueryError) Error() string {
+    return fmt.Sprintf("query error at position %d: %s", e.Pos+1, e.Msg)
+}
+
+// Predicate reports whether a torrent matches a parsed query
+type Predicate func(t *Torrent) bool
+
+// ParseQuery parses a search query into a predicate. An empty query matches
+// every torrent.
+func ParseQuery(query string) (Predicate, error) {
+    tokens, err := lexQuery(query)
+    if err != nil {
+        return nil, err
+    }
+    if len(tokens) == 0 {
+        return func(*Torrent) bool { return true }, nil
+    }
+
+    p := &queryParser{tokens: tokens, end: len(query)}
+    node, err := p.parseOr()
+    if err != nil {
+        return nil, err
+    }
+    if tok := p.peek(); tok != nil {
+        return nil, &QueryError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+    }
+    return node, nil
+}
+
+type tokenKind int
+
+const (
+    tokTerm tokenKind = iota
+    tokPhrase
+    tokLParen
+    tokRParen
+    tokNot
+    tokOr
+    tokAnd
+)
+
+type queryToken struct {
+    kind tokenKind
+    text string // term or phrase content with quotes removed
+    pos  int
+}
+
+// lexQuery splits a query into tokens. A term may contain a quoted part,
+// as in path:"/mnt/my disk", which is unquoted in place.
+func lexQuery(query string) ([]queryToken, error) {
+    var tokens []queryToken
+    runes := []rune(query)
+    offsets := make([]int, len(runes)+1)
+    for i, n := 0, 0; i < len(runes); i++ {
+        offsets[i] = n
+        n += len(string(runes[i]))
+        offsets[i+1] = n
+    }
+
+    for i := 0; i < len(runes); {
+        r := runes[i]
+        switch {
+        case unicode.IsSpace(r):
+            i++
+            continue
+        case r == '(':
+            tokens = append(tokens, queryToken{kind: tokLParen, text: "(", pos: offsets[i]})
+            i++
+            continue
+        case r == ')':
+            tokens = append(tokens, queryToken{kind: tokRParen, text: ")", pos: offsets[i]})
+            i++
+            continue
+        case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != ')':
+            tokens = append(tokens, queryToken{kind: tokNot, text: "-", pos: offsets[i]})
+            i++
+            continue
+        }
+
+        start := i
+        kind := tokTerm
+        if r == '"' {
+            kind = tokPhrase
+        }
+
+        var b strings.Builder
+        for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+            if runes[i] != '"' {
+                b.WriteRune(runes[i])
+                i++
+                continue
+            }
+            quote := i
+            i++
+            for i < len(runes) && runes[i] != '"' {
+                b.WriteRune(runes[i])
+                i++
+            }
+            if i == len(runes) {
+                return nil, &QueryError{Pos: offsets[quote], Msg: "unterminated quote"}
+            }
+            i++
+            if kind == tokPhrase {
+                break
+            }
+        }
+
+        text := b.String()
+        if kind == tokTerm {
+            switch text {
+            case "OR", "|":
+                kind = tokOr
+            case "AND", "&":
+                kind = tokAnd
+            case "NOT":
+                kind = tokNot
+            }
+        }
+        tokens = append(tokens, queryToken{kind: kind, text: text, pos: offsets[start]})
+    }
+    return tokens, nil
+}
+
+type queryParser struct {
+    tokens []queryToken
+    i      int
+    end    int
+}
+
+func (p *queryParser) peek() *queryToken {
+    if p.i < len(p.tokens) {
+        return &p.tokens[p.i]
+    }
+    return nil
+}
+
+func (p *queryParser) parseOr() (Predicate, error) {
+    left, err := p.parseAnd()
+    if err != nil {
+        return nil, err
+    }
+    for {
+        tok := p.peek()
+        if tok == nil || tok.kind != tokOr {
+            return left, nil
+        }
+        p.i++
+        right, err := p.parseAnd()
+        if err != nil {
+            return nil, err
+        }
+        a, b := left, right
+        left = func(t *Torrent) bool { return a(t) || b(t) }
+    }
+}
+
+func (p *queryParser) parseAnd() (Predicate, error) {
+    left, err := p.parseUnary()
+    if err != nil {
+        return nil, err
+    }
+    for {
+        tok := p.peek()
+        if tok == nil || tok.kind == tokOr || tok.kind == tokRParen {
+            return left, nil
+        }
+        if tok.kind == tokAnd {
+            p.i++
+        }
+        right, err := p.parseUnary()
+        if err != nil {
+            return nil, err
+        }
+        a, b := left, right
+        left = func(t *Torrent) bool { return a(t) && b(t) }
+    }
+}
+
+func (p *queryParser) parseUnary() (Predicate, error) {
+    tok := p.peek()
+    if tok == nil {
+        return nil, &QueryError{Pos: p.end, Msg: "unexpected end of query"}
+    }
+
+    switch tok.kind {
+    case tokNot:
+        p.i++
+        inner, err := p.parseUnary()
+        if err != nil {
+            return nil, err
+        }
+        return func(t *Torrent) bool { return !inner(t) }, nil
+
+    case tokLParen:
+        p.i++
+        inner, err := p.parseOr()
+        if err != nil {
+            return nil, err
+        }
+        if next := p.peek(); next == nil || next.kind != tokRParen {
+            return nil, &QueryError{Pos: tok.pos, Msg: "unclosed parenthesis"}
+        }
+        p.i++
+        return inner, nil
+
+    case tokPhrase:
+        p.i++
+        phrase := strings.ToLower(tok.text)
+        return func(t *Torrent) bool { return strings.Contains(strings.ToLower(t.Name), phrase) }, nil
+
+    case tokTerm:
+        p.i++
+        return parseTerm(tok)
+    }
+    return nil, &QueryError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+}
+
+// queryOps are checked longest first so "<=" isn't read as "<"
+var queryOps = []string{"<=", ">=", "<", ">", "=", ":"}
+
+// parseTerm turns field:value, field<value or a bare word into a predicate
+func parseTerm(tok *queryToken) (Predicate, error) {
+    field, op, value, ok := splitTerm(tok.text)
+    if !ok {
+        word := strings.ToLower(tok.text)
+        return func(t *Torrent) bool { return strings.Contains(strings.ToLower(t.Name), word) }, nil
+    }
+
+    field = strings.ToLower(field)
+    build, known := queryFields[field]
+    if !known {
+        return nil, &QueryError{Pos: tok.pos, Msg: fmt.Sprintf("unknown field %q", field)}
+    }
+    if value == "" {
+        return nil, &QueryError{Pos: tok.pos + len(tok.text), Msg: fmt.Sprintf("missing value for %s", field)}
+    }
+
+    pred, err := build(op, value)
+    if err != nil {
+        return nil, &QueryError{Pos: tok.pos + len(tok.text) - len(value), Msg: err.Error()}
+    }
+    return pred, nil
+}
+
+// splitTerm finds the field and operator of a term. "field:<value" is
+// treated as "field<value".
+func splitTerm(term string) (field, op, value string, ok bool) {
+    idx := -1
+    for _, candidate := range queryOps {
+        if i := strings.Index(term, candidate); i > 0 && (idx < 0 || i < idx) {
+            idx, op = i, candidate
+        }
+    }
+    if idx < 0 {
+        return "", "", "", false
+    }
+
+    field = term[:idx]
+    value = term[idx+len(op):]
+    if !isFieldName(field) {
+        return "", "", "", false
+    }
+    if op == ":" {
+        for _, candidate := range queryOps[:5] {
+            if strings.HasPrefix(value, candidate) {
+                op, value = candidate, value[len(candidate):]
+                break
+            }
+        }
+    }
+    return field, op, value, true
+}
+
+func isFieldName(s string) bool {
+    for _, r := range s {
+        if !unicode.IsLetter(r) && r != '_' {
+            return false
+        }
+    }
+    return s != ""
+}
+
+// fieldBuilder creates the predicate for one field and operator
+type fieldBuilder func(op, value string) (Predicate, error)
+
+var queryFields = map[string]fieldBuilder{
+    "name":      textField(func(t *Torrent) string { return t.Name }),
+    "message":   textField(func(t *Torrent) string { return t.Message }),
+    "label":     labelField,
+    "tracker":   trackerField,
+    "state":     stateField,
+    "status":    stateField,
+    "path":      pathField,
+    "hash":      hashField,
+    "size":      sizeField(func(t *Torrent) int64 { return t.Size }),
+    "done":      sizeField(func(t *Torrent) int64 { return t.Downloaded }),
+    "uploaded":  sizeField(func(t *Torrent) int64 { return t.UpTotal }),
+    "left":      sizeField(func(t *Torrent) int64 { return t.LeftBytes }),
+    "upspeed":   sizeField(func(t *Torrent) int64 { return t.UploadRate }),
+    "downspeed": sizeField(func(t *Torrent) int64 { return t.DownloadRate }),
+    "ratio":     numberField(func(t *Torrent) float64 { return t.Ratio }),
+    "progress":  numberField(func(t *Torrent) float64 { return t.Progress }),
+    "seeds":     numberField(func(t *Torrent) float64 { return float64(t.Seeders) }),
+    "peers":     numberField(func(t *Torrent) float64 { return float64(t.Peers) }),
+    "added":     timeField(func(t *Torrent) time.Time { return t.AddedAt }),
+    "finished":  timeField(func(t *Torrent) time.Time { return t.FinishedAt }),
+    "created":   timeField(func(t *Torrent) time.Time { return t.CreatedAt }),
+    "private":   boolField(func(t *Torrent) bool { return t.Private }),
+    "complete":  boolField(func(t *Torrent) bool { return t.Complete }),
+}
+
+
+func textField(get func(t *Torrent) string) fieldBuilder {
+    return func(op, value string) (Predicate, error) {
+        value = strings.ToLower(value)
+        switch op {
+        case ":":
+            return func(t *Torrent) bool { return strings.Contains(strings.ToLower(get(t)), value) }, nil
+        case "=":
+            return func(t *Torrent) bool { return strings.ToLower(get(t)) == value }, nil
+        }
+        return nil, fmt.Errorf("operator %s not supported for text", op)
+    }
+}
+
+func labelField(op, value string) (Predicate, error) {
+    if op != ":" && op != "=" {
+        return nil, fmt.Errorf("operator %s not supported for label", op)
+    }
+    value = strings.ToLower(strings.Trim(value, "/"))
+    exact := op == "="
+    return func(t *Torrent) bool {
+        label := strings.ToLower(t.Label)
+        return label == value || (!exact && strings.HasPrefix(label, value+"/"))
+    }, nil
+}
+
+func trackerField(op, value string) (Predicate, error) {
+    if op != ":" && op != "=" {
+        return nil, fmt.Errorf("operator %s not supported for tracker", op)
+    }
+    value = strings.ToLower(value)
+    if op == "=" {
+        return func(t *Torrent) bool {
+            for _, announce := range t.Trackers {
+                // The first tracker with a domain, see PrimaryTracker
+                if domain := TrackerDomain(announce); domain != "" {
+                    return domain == value || TrackerHost(announce) == value
+                }
+            }
+            return false
+        }, nil
+    }
+    return func(t *Torrent) bool {
+        for _, announce := range t.Trackers {
+            if strings.Contains(TrackerHost(announce), value) {
+                return true
+            }
+        }
+        return false
+    }, nil
+}
+
+func stateField(op, value string) (Predicate, error) {
+    if op != ":" && op != "=" {
+        return nil, fmt.Errorf("operator %s not supported for state", op)
+    }
+    listFiltersMu.RLock()
+    filter := listFilters[strings.ToLower(value)]
+    listFiltersMu.RUnlock()
+    if filter == nil {
+        return nil, fmt.Errorf("unknown state %q", value)
+    }
+    return Predicate(filter), nil
+}
+
+func pathField(op, value string) (Predicate, error) {
+    if op != ":" && op != "=" {
+        return nil, fmt.Errorf("operator %s not supported for path", op)
+    }
+    dir := filepath.Clean(value)
+    return func(t *Torrent) bool {
+        for _, p := range []string{t.BasePath, t.Directory} {
+            if p == "" {
+                continue
+            }
+            p = filepath.Clean(p)
+            if p == dir || strings.HasPrefix(p, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator)) {
+                return true
+            }
+        }
+        return false
+    }, nil
+}
+
+func hashField(op, value string) (Predicate, error) {
+    if op != ":" && op != "=" {
+        return nil, fmt.Errorf("operator %s not supported for hash", op)
+    }
+    value = strings.ToUpper(value)
+    return func(t *Torrent) bool {
+        if op == "=" {
+            return t.Hash == value
+        }
+        return strings.HasPrefix(t.Hash, value)
+    }, nil
+}
+
+func sizeField(get func(t *Torrent) int64) fieldBuilder {
+    return func(op, value string) (Predicate, error) {
+        n, err := ParseSize(value)
+        if err != nil {
+            return nil, err
+        }
+        return compareOp(op, func(t *Torrent) float64 { return float64(get(t)) }, float64(n))
+    }
+}
+
+func numberField(get func(t *Torrent) float64) fieldBuilder {
+    return func(op, value string) (Predicate, error) {
+        n, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+        if err != nil {
+            return nil, fmt.Errorf("invalid number %q", value)
+        }
+        return compareOp(op, get, n)
+    }
+}
+
+func boolField(get func(t *Torrent) bool) fieldBuilder {
+    return func(op, value string) (Predicate, error) {
+        if op != ":" && op != "=" {
+            return nil, fmt.Errorf("operator %s not supported for yes/no fields", op)
+        }
+        var want bool
+        switch strings.ToLower(value) {
+        case "yes", "true", "1", "y":
+            want = true
+        case "no", "false", "0", "n":
+            want = false
+        default:
+            return nil, fmt.Errorf("expected yes or no, got %q", value)
+        }
+        return func(t *Torrent) bool { return get(t) == want }, nil
+    }
+}
+
+// timeField accepts either an age ("7d": less than 7 days old with <, more
+// with >) or a date ("2024-01-01": before/after the date)
+func timeField(get func(t *Torrent) time.Time) fieldBuilder {
+    return func(op, value string) (Predicate, error) {
+        if age, err := parseAge(value); err == nil {
+            // A younger torrent has a larger timestamp, so flip the operator
+            flipped := map[string]string{"<": ">", "<=": ">=", ">": "<", ">=": "<=", ":": ">=", "=": ">="}[op]
+            return func(t *Torrent) bool {
+                ts := get(t)
+                if ts.IsZero() {
+                    return false
+                }
+                cutoff := time.Now().Add(-age)
+                return compareValues(flipped, float64(ts.Unix()), float64(cutoff.Unix()))
+            }, nil
+        }
+
+        date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+        if err != nil {
+            return nil, fmt.Errorf("invalid age or date %q (use e.g. 7d or 2024-01-01)", value)
+        }
+        day := date.Unix()
+        next := date.AddDate(0, 0, 1).Unix()
+        return func(t *Torrent) bool {
+            ts := get(t)
+            if ts.IsZero() {
+                return false
+            }
+            sec := ts.Unix()
+            switch op {
+            case "<":
+                return sec < day
+            case "<=":
+                return sec < next
+            case ">":
+                return sec >= next
+            case ">=":
+                return sec >= day
+            }
+            return sec >= day && sec < next
+        }, nil
+    }
+}
+
+func compareOp(op string, get func(t *Torrent) float64, n float64) (Predicate, error) {
+    if op == ":" {
+        op = "="
+    }
+    return func(t *Torrent) bool { return compareValues(op, get(t), n) }, nil
+}
+
+func compareValues(op string, a, b float64) bool {
+    switch op {
+    case "<":
+        return a < b
+    case "<=":
+        return a <= b
+    case ">":
+        return a > b
+    case ">=":
+        return a >= b
+    }
+    return a == b
+}
+
+// sizeUnits maps unit suffixes to multipliers. KiB/MiB/... are binary, the
+// bare K/M/G used by the UI are binary too, KB/MB/... are decimal.
+var sizeUnits = map[string]float64{
+    "":    1,
+    "b":   1,
+    "k":   1 << 10,
+    "kib": 1 << 10,
+    "kb":  1e3,
+    "m":   1 << 20,
+    "mib": 1 << 20,
+    "mb":  1e6,
+    "g":   1 << 30,
+    "gib": 1 << 30,
+    "gb":  1e9,
+    "t":   1 << 40,
+    "tib": 1 << 40,
+    "tb":  1e12,
+}
+
+// ParseSize parses sizes such as "10GiB", "1.5g" or "700MB"
+func ParseSize(s string) (int64, error) {
+    s = strings.TrimSpace(s)
+    i := 0
+    for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+        i++
+    }
+    n, err := strconv.ParseFloat(s[:i], 64)
+    if err != nil {
+        return 0, fmt.Errorf("invalid size %q", s)
+    }
+    unit, ok := sizeUnits[strings.ToLower(strings.TrimSuffix(strings.TrimSpace(s[i:]), "/s"))]
+    if !ok {
+        return 0, fmt.Errorf("unknown size unit in %q", s)
+    }
+    return int64(n * unit), nil
+}
+
+// ageUnits are the suffixes accepted by parseAge
+var ageUnits = map[string]time.Duration{
+    "s": time.Second,
+    "m": time.Minute,
+    "h": time.Hour,
+    "d": 24 * time.Hour,
+    "w": 7 * 24 * time.Hour,
+    "y": 365 * 24 * time.Hour,
+}
+
+// parseAge parses ages such as "7d" or "12h"
+func parseAge(s string) (time.Duration, error) {
+    if len(s) < 2 {
+        return 0, fmt.Errorf("invalid age %q", s)
+    }
+    unit, ok := ageUnits[strings.ToLower(s[len(s)-1:])]
+    if !ok {
+        return 0, fmt.Errorf("invalid age %q", s)
+    }
+    n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+    if err != nil || n < 0 {
+        return 0, fmt.Errorf("invalid age %q", s)
+    }
+    return time.Duration(n * float64(unit)), nil
+}
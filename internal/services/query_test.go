@@ -0,0 +1,99 @@
+// internal/services/query_test.go
+package services
+
+import (
+    "reflect"
+    "testing"
+)
+
+var queryTorrents = []*Torrent{
+    {Hash: "A1", Name: "Ubuntu 22.04 Desktop", Label: "linux", Directory: "/mnt/my disk/iso"},
+    {Hash: "B2", Name: "Debian Netinst", Label: "linux", Directory: "/mnt/data"},
+    {Hash: "C3", Name: "Some Show S01", Label: "tv/shows", Directory: "/mnt/my disk/tv"},
+    {Hash: "D4", Name: "Ubuntu Server", Label: "tv", Directory: "/mnt/data"},
+}
+
+func TestParseQuery(t *testing.T) {
+    tests := []struct {
+        name  string
+        query string
+        want  []string // hashes of matching torrents in queryTorrents order
+    }{
+        {name: "empty", query: "", want: []string{"A1", "B2", "C3", "D4"}},
+        {name: "implicit and", query: "ubuntu desktop", want: []string{"A1"}},
+
+        // AND binds tighter than OR
+        {name: "and before or", query: "ubuntu server OR debian", want: []string{"B2", "D4"}},
+        {name: "or before and", query: "debian OR ubuntu server", want: []string{"B2", "D4"}},
+        {name: "explicit and", query: "debian OR ubuntu AND label:tv", want: []string{"B2", "D4"}},
+        {name: "parentheses", query: "(debian OR ubuntu) label:linux", want: []string{"A1", "B2"}},
+        {name: "nested parentheses", query: "((show OR server) label:tv) OR netinst", want: []string{"B2", "C3", "D4"}},
+        {name: "symbols", query: "debian | ubuntu & server", want: []string{"B2", "D4"}},
+
+        // Negation applies to the next term or group only
+        {name: "minus", query: "-ubuntu", want: []string{"B2", "C3"}},
+        {name: "not", query: "NOT ubuntu", want: []string{"B2", "C3"}},
+        {name: "negated field", query: "-label:tv", want: []string{"A1", "B2"}},
+        {name: "negated exact label", query: "label:tv -label=tv", want: []string{"C3"}},
+        {name: "negated group", query: "-(debian OR show)", want: []string{"A1", "D4"}},
+        {name: "negation binds tighter than or", query: "-ubuntu OR server", want: []string{"B2", "C3", "D4"}},
+        {name: "double negation", query: "NOT -debian", want: []string{"B2"}},
+        {name: "lone minus is a word", query: "- debian", want: nil},
+        {name: "hyphen inside a word", query: "22.04-desktop", want: nil},
+
+        // Quotes keep spaces and operators in values
+        {name: "phrase", query: `"ubuntu server"`, want: []string{"D4"}},
+        {name: "phrase with or", query: `"some show" OR "netinst"`, want: []string{"B2", "C3"}},
+        {name: "quoted keyword", query: `"OR"`, want: nil},
+        {name: "negated phrase", query: `-"ubuntu 22"`, want: []string{"B2", "C3", "D4"}},
+        {name: "quoted value", query: `path:"/mnt/my disk"`, want: []string{"A1", "C3"}},
+        {name: "partly quoted value", query: `path:/mnt/"my disk"/tv`, want: []string{"C3"}},
+        {name: "quoted parenthesis", query: `name:"s01)"`, want: nil},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            match, err := ParseQuery(tt.query)
+            if err != nil {
+                t.Fatalf("ParseQuery(%q): %v", tt.query, err)
+            }
+            var got []string
+            for _, torrent := range queryTorrents {
+                if match(torrent) {
+                    got = append(got, torrent.Hash)
+                }
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("ParseQuery(%q) matched %v, want %v", tt.query, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestParseQueryErrors(t *testing.T) {
+    tests := []struct {
+        name  string
+        query string
+        pos   int
+    }{
+        {name: "unterminated quote", query: `name:"foo`, pos: 5},
+        {name: "unclosed parenthesis", query: "a (b OR c", pos: 2},
+        {name: "stray parenthesis", query: "a ) b", pos: 2},
+        {name: "dangling or", query: "a OR", pos: 4},
+        {name: "dangling negation", query: "a NOT", pos: 5},
+        {name: "unknown field", query: "colour:red", pos: 0},
+        {name: "missing value", query: "label:", pos: 6},
+        {name: "bad value", query: "size>lots", pos: 5},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, err := ParseQuery(tt.query)
+            qErr, ok := err.(*QueryError)
+            if !ok {
+                t.Fatalf("ParseQuery(%q) error = %v, want a *QueryError", tt.query, err)
+            }
+            if qErr.Pos != tt.pos {
+                t.Errorf("ParseQuery(%q) error at %d, want %d: %v", tt.query, qErr.Pos, tt.pos, qErr)
+            }
+        })
+    }
+}
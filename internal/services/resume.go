@@ -0,0 +1,33 @@
+// internal/services/resume.go
+package services
+
+import (
+    "fmt"
+
+    "rutorrent-web/internal/rtorrent"
+    "rutorrent-web/internal/torrentfile"
+)
+
+// withResume adds fast-resume data for content that is already in the
+// download directory, so rTorrent seeds it without hashing. Pieces of
+// missing or incomplete files are left for rTorrent to download.
+func (s *TorrentService) withResume(data []byte, opts *AddTorrentOptions) ([]byte, error) {
+    dir := opts.Directory
+    if dir == "" {
+        value, err := s.client.CallValues("directory.default")
+        if err != nil {
+            return nil, fmt.Errorf("failed to get default directory: %w", err)
+        }
+        dir = rtorrent.AsString(value)
+    }
+
+    out, report, err := torrentfile.AddResume(data, dir, torrentfile.ResumeOptions{Verify: opts.VerifyPieces})
+    if err != nil {
+        return nil, fmt.Errorf("failed to build resume data: %w", err)
+    }
+    if len(report.Missing) > 0 {
+        Warnf("fast resume: %d of %d pieces found in %s, %d files missing or incomplete",
+            report.Complete, report.Pieces, dir, len(report.Missing))
+    }
+    return out, nil
+}
@@ -0,0 +1,75 @@
+// internal/services/source.go
+package services
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+
+    "rutorrent-web/internal/rtorrent"
+    "rutorrent-web/internal/torrentfile"
+)
+
+// ErrSourceNotFound is returned when no readable .torrent belongs to a
+// loaded torrent
+var ErrSourceNotFound = errors.New("torrent file not found")
+
+// TorrentSource is the .torrent of a loaded torrent. Path is set when the
+// original file can be sent as it is, otherwise Data holds a file rebuilt
+// from rTorrent's session copy.
+type TorrentSource struct {
+    Name string // torrent name, for the download file name
+    Path string
+    Data []byte
+}
+
+// GetTorrentSource finds the .torrent of a loaded torrent. The file it was
+// loaded from (d.loaded_file, then d.tied_to_file) is preferred; when it is
+// gone or belongs to another torrent the copy in session.path is cleaned of
+// rTorrent's own keys and returned instead.
+func (s *TorrentService) GetTorrentSource(hash string) (*TorrentSource, error) {
+    t, ok := s.GetTorrent(hash)
+    if !ok {
+        return nil, ErrTorrentNotFound
+    }
+
+    results, err := s.client.SystemMulticall([]rtorrent.MethodCall{
+        {Method: "session.path"},
+        {Method: "d.loaded_file", Params: []interface{}{t.Hash}},
+        {Method: "d.tied_to_file", Params: []interface{}{t.Hash}},
+    })
+    if err != nil {
+        return nil, err
+    }
+    if err := rtorrent.FirstError(results); err != nil {
+        return nil, err
+    }
+    session := rtorrent.AsString(results[0])
+
+    for _, path := range []string{rtorrent.AsString(results[1]), rtorrent.AsString(results[2])} {
+        if path == "" || (session != "" && filepath.Dir(path) == filepath.Clean(session)) {
+            // The session copy is handled below, it needs cleaning
+            continue
+        }
+        data, err := os.ReadFile(path)
+        if err != nil {
+            continue
+        }
+        if h, err := torrentfile.InfoHash(data); err == nil && h == t.Hash {
+            return &TorrentSource{Name: t.Name, Path: path}, nil
+        }
+    }
+
+    if session == "" {
+        return nil, ErrSourceNotFound
+    }
+    data, err := os.ReadFile(filepath.Join(session, t.Hash+".torrent"))
+    if err != nil {
+        return nil, ErrSourceNotFound
+    }
+    data, err = torrentfile.Rebuild(data, t.Hash, t.Trackers)
+    if err != nil {
+        return nil, err
+    }
+    return &TorrentSource{Name: t.Name, Data: data}, nil
+}
@@ -0,0 +1,31 @@
+// internal/services/throttle.go
+package services
+
+import (
+    "strings"
+
+    "rutorrent-web/internal/rtorrent"
+)
+
+// SetThrottle puts the given torrents on a throttle channel, or takes them
+// off with an empty name. rTorrent only changes d.throttle_name of stopped
+// torrents, so active ones are stopped around the change.
+func (s *TorrentService) SetThrottle(hashes []string, name string) error {
+    calls := make([]rtorrent.MethodCall, 0, len(hashes)*5)
+    for _, hash := range hashes {
+        hash = strings.ToUpper(hash)
+        wasActive := false
+        if t, ok := s.GetTorrent(hash); ok {
+            wasActive = t.State != 0
+        }
+
+        if wasActive {
+            calls = append(calls, rtorrent.MethodCall{Method: "d.stop", Params: []interface{}{hash}})
+        }
+        calls = append(calls, rtorrent.MethodCall{Method: "d.throttle_name.set", Params: []interface{}{hash, name}})
+        if wasActive {
+            calls = append(calls, rtorrent.MethodCall{Method: "d.start", Params: []interface{}{hash}})
+        }
+    }
+    return s.batch(calls)
+}
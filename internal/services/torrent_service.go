@@ -3,18 +3,30 @@
 package services
 
 import (
-    "your-project/internal/rtorrent"
+    "net/url"
+    "strings"
     "sync"
     "time"
+
+    "rutorrent-web/internal/rtorrent"
 )
 
 type TorrentService struct {
     client      *rtorrent.Client
     updateChan  chan struct{}
     torrents    map[string]*Torrent
+    revision    int64
+    lists       listCache
+    counts      countCache
+    selections  selections
+    addFilter   AddFilter
+    removeGuard RemoveGuard
     mu          sync.RWMutex
 }
 
+// Torrent is a point-in-time view of a single rTorrent download. Values in
+// the snapshot are replaced as a whole on every refresh and never modified
+// afterwards, so they can be shared between goroutines without copying.
 type Torrent struct {
     Hash        string
     Name        string
@@ -24,16 +36,90 @@ type Torrent struct {
     DownloadRate int64
     State       int
     Progress    float64
-    // Add more fields as needed
+
+    Label           string    // d.custom1, URL-decoded
+    Directory       string    // d.directory
+    BasePath        string    // d.base_path, empty until the torrent is opened
+    UpTotal         int64
+    DownTotal       int64
+    Ratio           float64   // d.ratio / 1000
+    Open            bool
+    Active          bool
+    Complete        bool
+    Hashing         bool
+    Message         string
+    Seeders         int       // connected seeds
+    Peers           int       // connected leechers
+    Private         bool
+    MultiFile       bool
+    ChunkSize       int64
+    Chunks          int64
+    CompletedChunks int64
+    LeftBytes       int64
+    Priority        int
+    AddedAt         time.Time // d.custom=addtime, falls back to creation date
+    FinishedAt      time.Time
+    CreatedAt       time.Time
+    Trackers        []string  // announce URLs in tracker order, DHT excluded
+    HashFailed      bool      // d.hashing_failed
+    Error           ErrorCategory
+    Meta            bool      // d.is_meta, a magnet still fetching its metadata
+    RatioGroup      string    // d.custom=ratiogroup, set when assigned to a ratio group explicitly
+    Throttle        string    // d.throttle_name, empty when unthrottled
+    WantedChunks    int64     // d.wanted_chunks, chunks of wanted files not yet completed
+}
+
+// torrentFields lists the d.multicall2 columns used to build a Torrent
+var torrentFields = []string{
+    "d.hash=",
+    "d.name=",
+    "d.size_bytes=",
+    "d.completed_bytes=",
+    "d.up.rate=",
+    "d.down.rate=",
+    "d.state=",
+    "d.custom1=",
+    "d.directory=",
+    "d.base_path=",
+    "d.up.total=",
+    "d.down.total=",
+    "d.ratio=",
+    "d.is_open=",
+    "d.is_active=",
+    "d.complete=",
+    "d.hashing=",
+    "d.message=",
+    "d.peers_complete=",
+    "d.peers_accounted=",
+    "d.is_private=",
+    "d.is_multi_file=",
+    "d.chunk_size=",
+    "d.size_chunks=",
+    "d.completed_chunks=",
+    "d.left_bytes=",
+    "d.priority=",
+    "d.custom=addtime",
+    "d.timestamp.finished=",
+    "d.creation_date=",
+    // All announce URLs joined by "#", the same trick ruTorrent uses to get
+    // per-tracker values without a t.multicall per torrent
+    `cat="$t.multicall=d.hash=,t.url=,cat={#}"`,
+    "d.hashing_failed=",
+    // "enabled:type:failed_counter" per tracker, joined by "#"
+    `cat="$t.multicall=d.hash=,t.is_enabled=,cat={:},t.type=,cat={:},t.failed_counter=,cat={#}"`,
+    "d.is_meta=",
+    "d.custom=ratiogroup",
+    "d.throttle_name=",
+    "d.wanted_chunks=",
 }
 
 func NewTorrentService(client *rtorrent.Client) *TorrentService {
     ts := &TorrentService{
         client:     client,
-        updateChan: make(chan struct{}),
+        updateChan: make(chan struct{}, 1),
         torrents:   make(map[string]*Torrent),
     }
-    
+
     go ts.backgroundUpdater()
     return ts
 }
@@ -53,17 +139,179 @@ func (s *TorrentService) backgroundUpdater() {
 }
 
 func (s *TorrentService) updateTorrents() {
-    torrents, err := s.client.GetTorrents()
+    torrents, err := s.fetchTorrents()
     if err != nil {
-        // Handle error, maybe log it
+        Warnf("failed to refresh torrent list: %v", err)
         return
     }
 
     s.mu.Lock()
-    defer s.mu.Unlock()
-
     // Update torrents map
     s.torrents = torrents
+    s.revision++
+    s.mu.Unlock()
+
+    s.applySelections(torrents)
+}
+
+// fetchTorrents loads every torrent in the main view in a single multicall
+func (s *TorrentService) fetchTorrents() (map[string]*Torrent, error) {
+    rows, err := s.client.Multicall("main", torrentFields...)
+    if err != nil {
+        return nil, err
+    }
+
+    torrents := make(map[string]*Torrent, len(rows))
+    for _, row := range rows {
+        if len(row) < len(torrentFields) {
+            continue
+        }
+        t := parseTorrent(row)
+        torrents[t.Hash] = t
+    }
+    return torrents, nil
+}
+
+// parseTorrent converts a multicall row ordered as torrentFields
+func parseTorrent(row []interface{}) *Torrent {
+    t := &Torrent{
+        Hash:            rtorrent.AsString(row[0]),
+        Name:            rtorrent.AsString(row[1]),
+        Size:            rtorrent.AsInt(row[2]),
+        Downloaded:      rtorrent.AsInt(row[3]),
+        UploadRate:      rtorrent.AsInt(row[4]),
+        DownloadRate:    rtorrent.AsInt(row[5]),
+        State:           int(rtorrent.AsInt(row[6])),
+        Label:           DecodeLabel(rtorrent.AsString(row[7])),
+        Directory:       rtorrent.AsString(row[8]),
+        BasePath:        rtorrent.AsString(row[9]),
+        UpTotal:         rtorrent.AsInt(row[10]),
+        DownTotal:       rtorrent.AsInt(row[11]),
+        Ratio:           float64(rtorrent.AsInt(row[12])) / 1000,
+        Open:            rtorrent.AsBool(row[13]),
+        Active:          rtorrent.AsBool(row[14]),
+        Complete:        rtorrent.AsBool(row[15]),
+        Hashing:         rtorrent.AsBool(row[16]),
+        Message:         rtorrent.AsString(row[17]),
+        Seeders:         int(rtorrent.AsInt(row[18])),
+        Peers:           int(rtorrent.AsInt(row[19])),
+        Private:         rtorrent.AsBool(row[20]),
+        MultiFile:       rtorrent.AsBool(row[21]),
+        ChunkSize:       rtorrent.AsInt(row[22]),
+        Chunks:          rtorrent.AsInt(row[23]),
+        CompletedChunks: rtorrent.AsInt(row[24]),
+        LeftBytes:       rtorrent.AsInt(row[25]),
+        Priority:        int(rtorrent.AsInt(row[26])),
+        FinishedAt:      unixTime(rtorrent.AsInt(row[28])),
+        CreatedAt:       unixTime(rtorrent.AsInt(row[29])),
+        Trackers:        splitTrackers(rtorrent.AsString(row[30])),
+        HashFailed:      rtorrent.AsBool(row[31]),
+        Meta:            rtorrent.AsBool(row[33]),
+        RatioGroup:      rtorrent.AsString(row[34]),
+        Throttle:        rtorrent.AsString(row[35]),
+        WantedChunks:    rtorrent.AsInt(row[36]),
+    }
+
+    t.AddedAt = unixTime(rtorrent.AsInt(row[27]))
+    if t.AddedAt.IsZero() {
+        t.AddedAt = t.CreatedAt
+    }
+
+    if t.Size > 0 {
+        t.Progress = float64(t.Downloaded) / float64(t.Size) * 100
+    }
+    t.Error = classifyTorrent(t, trackersFailing(rtorrent.AsString(row[32])))
+    return t
+}
+
+// Status returns a simple status string for the torrent
+func (t *Torrent) Status() string {
+    switch {
+    case t.Hashing:
+        return "checking"
+    case t.State == 0:
+        return "stopped"
+    case !t.Active:
+        return "paused"
+    case t.Complete:
+        return "seeding"
+    default:
+        return "downloading"
+    }
+}
+
+// Snapshot returns all torrents from the last refresh in no particular order
+func (s *TorrentService) Snapshot() []*Torrent {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    torrents := make([]*Torrent, 0, len(s.torrents))
+    for _, t := range s.torrents {
+        torrents = append(torrents, t)
+    }
+    return torrents
+}
+
+// GetTorrent returns a torrent from the last refresh by hash
+func (s *TorrentService) GetTorrent(hash string) (*Torrent, bool) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    t, ok := s.torrents[strings.ToUpper(hash)]
+    return t, ok
+}
+
+// Revision returns a counter that increases every time the snapshot changes
+func (s *TorrentService) Revision() int64 {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    return s.revision
+}
+
+// Refresh asks the background updater to reload the snapshot now
+func (s *TorrentService) Refresh() {
+    select {
+    case s.updateChan <- struct{}{}:
+    default:
+        // A refresh is already pending
+    }
+}
+
+// Client returns the underlying rTorrent client
+func (s *TorrentService) Client() *rtorrent.Client {
+    return s.client
+}
+
+// DecodeLabel decodes a label stored in d.custom1. ruTorrent stores labels
+// URL-encoded; values that don't decode are returned unchanged.
+func DecodeLabel(raw string) string {
+    if label, err := url.PathUnescape(raw); err == nil {
+        return strings.TrimSpace(label)
+    }
+    return strings.TrimSpace(raw)
+}
+
+// EncodeLabel encodes a label for d.custom1 the same way ruTorrent does
+func EncodeLabel(label string) string {
+    return url.PathEscape(strings.TrimSpace(label))
+}
+
+// splitTrackers splits the "#" joined announce URL list
+func splitTrackers(joined string) []string {
+    var trackers []string
+    for _, u := range strings.Split(joined, "#") {
+        if u = strings.TrimSpace(u); u != "" && !strings.HasPrefix(u, "dht://") {
+            trackers = append(trackers, u)
+        }
+    }
+    return trackers
+}
+
+func unixTime(sec int64) time.Time {
+    if sec <= 0 {
+        return time.Time{}
+    }
+    return time.Unix(sec, 0)
 }
 
 // two versions need to be combined
@@ -77,7 +325,7 @@ type Speeds struct {
 
 func (s *TorrentService) GetTotalSpeeds() (Speeds, error) {
     speeds := Speeds{}
-    
+
     // Calculate total speeds from all torrents
     s.mu.RLock()
     for _, torrent := range s.torrents {
@@ -85,6 +333,6 @@ func (s *TorrentService) GetTotalSpeeds() (Speeds, error) {
         speeds.Upload += torrent.UploadRate
     }
     s.mu.RUnlock()
-    
+
     return speeds, nil
-}
\ No newline at end of file
+}
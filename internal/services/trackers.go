@@ -0,0 +1,61 @@
+// internal/services/trackers.go
+package services
+
+import (
+    "net"
+    "net/url"
+    "strings"
+
+    "golang.org/x/net/publicsuffix"
+)
+
+// TrackerHost returns the lower-cased host name of an announce URL
+func TrackerHost(announce string) string {
+    u, err := url.Parse(strings.TrimSpace(announce))
+    if err != nil || u.Host == "" {
+        return ""
+    }
+    return strings.ToLower(u.Hostname())
+}
+
+// TrackerDomain returns the registered domain of an announce URL, so that
+// "https://tracker.example.co.uk/a1b2c3/announce" and
+// "udp://udp.example.co.uk:6969" both become "example.co.uk". Passkeys in
+// the path or query never end up in the result. IP addresses are returned
+// as they are.
+func TrackerDomain(announce string) string {
+    host := TrackerHost(announce)
+    if host == "" || net.ParseIP(host) != nil {
+        return host
+    }
+    if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+        return domain
+    }
+    return host
+}
+
+// TrackerDomains returns the distinct registered domains of the torrent's
+// trackers in tracker order
+func (t *Torrent) TrackerDomains() []string {
+    var domains []string
+    seen := make(map[string]bool, len(t.Trackers))
+    for _, announce := range t.Trackers {
+        domain := TrackerDomain(announce)
+        if domain == "" || seen[domain] {
+            continue
+        }
+        seen[domain] = true
+        domains = append(domains, domain)
+    }
+    return domains
+}
+
+// PrimaryTracker returns the registered domain of the first tracker
+func (t *Torrent) PrimaryTracker() string {
+    for _, announce := range t.Trackers {
+        if domain := TrackerDomain(announce); domain != "" {
+            return domain
+        }
+    }
+    return ""
+}
@@ -0,0 +1,51 @@
+// internal/services/transfer.go
+package services
+
+import (
+    "rutorrent-web/internal/rtorrent"
+)
+
+// TransferInfo holds global transfer statistics
+type TransferInfo struct {
+    DownRate  int64
+    UpRate    int64
+    DownTotal int64
+    UpTotal   int64
+    DownLimit int64 // 0 = unlimited
+    UpLimit   int64 // 0 = unlimited
+}
+
+// GetTransferInfo returns global rates, totals and limits
+func (s *TorrentService) GetTransferInfo() (TransferInfo, error) {
+    results, err := s.client.SystemMulticall([]rtorrent.MethodCall{
+        {Method: "throttle.global_down.rate"},
+        {Method: "throttle.global_up.rate"},
+        {Method: "throttle.global_down.total"},
+        {Method: "throttle.global_up.total"},
+        {Method: "throttle.global_down.max_rate"},
+        {Method: "throttle.global_up.max_rate"},
+    })
+    if err != nil {
+        return TransferInfo{}, err
+    }
+    if err := rtorrent.FirstError(results); err != nil {
+        return TransferInfo{}, err
+    }
+
+    return TransferInfo{
+        DownRate:  rtorrent.AsInt(results[0]),
+        UpRate:    rtorrent.AsInt(results[1]),
+        DownTotal: rtorrent.AsInt(results[2]),
+        UpTotal:   rtorrent.AsInt(results[3]),
+        DownLimit: rtorrent.AsInt(results[4]),
+        UpLimit:   rtorrent.AsInt(results[5]),
+    }, nil
+}
+
+// SetTransferLimits sets the global rate limits in bytes/s, 0 for unlimited
+func (s *TorrentService) SetTransferLimits(down, up int64) error {
+    return s.batch([]rtorrent.MethodCall{
+        {Method: "throttle.global_down.max_rate.set", Params: []interface{}{"", down}},
+        {Method: "throttle.global_up.max_rate.set", Params: []interface{}{"", up}},
+    })
+}
@@ -2,8 +2,10 @@
 package user
 
 import (
+    "context"
     "fmt"
     "net"
+    "net/http"
     "os"
     "regexp"
     "strings"
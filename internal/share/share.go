@@ -0,0 +1,369 @@
+// internal/share/share.go
+package share
+
+import (
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "net/url"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "golang.org/x/crypto/bcrypt"
+
+    "rutorrent-web/pkg/fileutil"
+)
+
+// WholeTorrent is the File value of links that share every file of a
+// torrent as an archive
+const WholeTorrent = -1
+
+const (
+    // DefaultTTL is used for links created without an expiry
+    DefaultTTL = 7 * 24 * time.Hour
+    // MaxTTL bounds how long a link can stay valid
+    MaxTTL = 365 * 24 * time.Hour
+    // maxLinks bounds the links per user
+    maxLinks = 500
+    // freeAttempts is the number of wrong passwords a link takes before
+    // further tries have to wait
+    freeAttempts = 5
+    // maxLockout bounds that wait, which doubles with every wrong password
+    maxLockout = 15 * time.Minute
+)
+
+var (
+    // ErrInvalidLink is returned for links that are unknown, revoked,
+    // expired or carry a bad signature. The cases aren't told apart so a
+    // link can't be probed.
+    ErrInvalidLink = errors.New("invalid or expired link")
+    // ErrNotFound is returned when managing a link that doesn't exist
+    ErrNotFound = errors.New("link not found")
+    // ErrWrongPassword is returned by CheckPassword for a wrong password
+    ErrWrongPassword = errors.New("wrong password")
+)
+
+// LockedError is returned by CheckPassword while a link refuses passwords
+// after too many wrong ones
+type LockedError struct {
+    Until time.Time
+}
+
+func (e *LockedError) Error() string {
+    return "too many wrong passwords, try again later"
+}
+
+// Link is a shared file or torrent
+type Link struct {
+    ID           string    `json:"id"`
+    Owner        string    `json:"owner"`
+    Hash         string    `json:"hash"`
+    File         int       `json:"file"`             // file index, WholeTorrent for an archive
+    Format       string    `json:"format,omitempty"` // archive format of whole torrent links
+    Name         string    `json:"name"`             // shown in the management page
+    Created      time.Time `json:"created"`
+    Expires      time.Time `json:"expires"`
+    Password     string    `json:"password,omitempty"` // bcrypt hash
+    Downloads    int       `json:"downloads"`
+    LastDownload time.Time `json:"last_download,omitempty"`
+}
+
+// Protected reports whether the link needs a password
+func (l *Link) Protected() bool {
+    return l.Password != ""
+}
+
+// Expired reports whether the link is past its expiry
+func (l *Link) Expired() bool {
+    return !time.Now().Before(l.Expires)
+}
+
+// Store keeps share links of all users in one file, since links are
+// resolved without knowing the user, and signs them with a key kept next
+// to it
+type Store struct {
+    path     string
+    secret   []byte
+    links    map[string]*Link
+    attempts map[string]*attempts // wrong passwords by link ID, not persisted
+    mu       sync.Mutex
+}
+
+// attempts counts the wrong passwords entered for a link since the last
+// right one
+type attempts struct {
+    failed int
+    until  time.Time // no password is checked before then
+}
+
+// NewStore opens the link store in dir, creating the signing key on first
+// use
+func NewStore(dir string) (*Store, error) {
+    secret, err := loadSecret(filepath.Join(dir, "share.key"))
+    if err != nil {
+        return nil, err
+    }
+
+    s := &Store{
+        path:     filepath.Join(dir, "share.json"),
+        secret:   secret,
+        links:    make(map[string]*Link),
+        attempts: make(map[string]*attempts),
+    }
+    var links []*Link
+    if err := fileutil.ReadJSON(s.path, &links); err != nil {
+        return nil, err
+    }
+    for _, l := range links {
+        s.links[l.ID] = l
+    }
+    return s, nil
+}
+
+// Create adds a link to a torrent file, or to the whole torrent when file is
+// WholeTorrent. A zero ttl uses DefaultTTL; an empty password leaves the
+// link open to anyone who has it.
+func (s *Store) Create(owner, hash string, file int, format, name string, ttl time.Duration, password string) (*Link, error) {
+    switch {
+    case ttl == 0:
+        ttl = DefaultTTL
+    case ttl < time.Minute || ttl > MaxTTL:
+        return nil, fmt.Errorf("link lifetime must be between 1 minute and %d days", MaxTTL/(24*time.Hour))
+    }
+    if file < WholeTorrent {
+        return nil, fmt.Errorf("invalid file index: %d", file)
+    }
+    if file != WholeTorrent {
+        format = ""
+    }
+
+    l := &Link{
+        ID:      newID(),
+        Owner:   owner,
+        Hash:    strings.ToUpper(hash),
+        File:    file,
+        Format:  format,
+        Name:    strings.TrimSpace(name),
+        Created: time.Now().Truncate(time.Second),
+    }
+    l.Expires = l.Created.Add(ttl)
+    if password != "" {
+        hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+        if err != nil {
+            return nil, err
+        }
+        l.Password = string(hashed)
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.prune()
+    if len(s.list(owner)) >= maxLinks {
+        return nil, fmt.Errorf("too many share links (maximum %d)", maxLinks)
+    }
+    s.links[l.ID] = l
+    if err := s.store(); err != nil {
+        delete(s.links, l.ID)
+        return nil, err
+    }
+    copied := *l
+    return &copied, nil
+}
+
+// List returns the unexpired links of a user, newest first
+func (s *Store) List(owner string) []Link {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.list(owner)
+}
+
+// Revoke deletes a link of a user
+func (s *Store) Revoke(owner, id string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    l, ok := s.links[id]
+    if !ok || l.Owner != owner {
+        return ErrNotFound
+    }
+    delete(s.links, id)
+    return s.store()
+}
+
+// URL returns the signed path of a link, relative to the server root
+func (s *Store) URL(l *Link) string {
+    expires := strconv.FormatInt(l.Expires.Unix(), 10)
+    q := url.Values{"e": {expires}, "sig": {s.sign(l, expires)}}
+    return "/s/" + l.ID + "?" + q.Encode()
+}
+
+// Resolve checks a link's signature and expiry and returns it
+func (s *Store) Resolve(id, expires, sig string) (*Link, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    l, ok := s.links[id]
+    if !ok || l.Expired() || expires != strconv.FormatInt(l.Expires.Unix(), 10) {
+        return nil, ErrInvalidLink
+    }
+    if !hmac.Equal([]byte(sig), []byte(s.sign(l, expires))) {
+        return nil, ErrInvalidLink
+    }
+    copied := *l
+    return &copied, nil
+}
+
+// CheckPassword returns nil when password unlocks a link. After
+// freeAttempts wrong passwords the link refuses to check any for a while,
+// doubling the wait with every further failure, and returns a LockedError.
+// Tries are counted before checking, so parallel guesses count too.
+func (s *Store) CheckPassword(l *Link, password string) error {
+    if !l.Protected() {
+        return nil
+    }
+
+    s.mu.Lock()
+    a, ok := s.attempts[l.ID]
+    if !ok {
+        a = &attempts{}
+        s.attempts[l.ID] = a
+    }
+    now := time.Now()
+    if now.Before(a.until) {
+        until := a.until
+        s.mu.Unlock()
+        return &LockedError{Until: until}
+    }
+    a.failed++
+    if n := a.failed - freeAttempts; n >= 0 {
+        wait := maxLockout
+        if n < 20 {
+            wait = min(time.Second<<n, maxLockout)
+        }
+        a.until = now.Add(wait)
+    }
+    s.mu.Unlock()
+
+    // bcrypt is slow on purpose, so it runs without holding the lock
+    if bcrypt.CompareHashAndPassword([]byte(l.Password), []byte(password)) != nil {
+        return ErrWrongPassword
+    }
+    s.mu.Lock()
+    delete(s.attempts, l.ID)
+    s.mu.Unlock()
+    return nil
+}
+
+// UnlockToken returns the value that proves a password was entered for a
+// link, kept in a cookie so resumed downloads don't ask again
+func (s *Store) UnlockToken(l *Link) string {
+    return s.mac("unlock", l.ID, strconv.FormatInt(l.Expires.Unix(), 10), l.Password)
+}
+
+// Unlocked reports whether token was issued by UnlockToken for the link
+func (s *Store) Unlocked(l *Link, token string) bool {
+    return !l.Protected() || hmac.Equal([]byte(token), []byte(s.UnlockToken(l)))
+}
+
+// CountDownload records a download of a link
+func (s *Store) CountDownload(id string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if l, ok := s.links[id]; ok {
+        l.Downloads++
+        l.LastDownload = time.Now()
+        s.store()
+    }
+}
+
+// sign returns the signature of a link: an HMAC over what it points to and
+// when it expires
+func (s *Store) sign(l *Link, expires string) string {
+    return s.mac("link", l.ID, l.Hash+"/"+strconv.Itoa(l.File)+"/"+l.Format, expires)
+}
+
+func (s *Store) mac(parts ...string) string {
+    m := hmac.New(sha256.New, s.secret)
+    m.Write([]byte(strings.Join(parts, "\n")))
+    return hex.EncodeToString(m.Sum(nil))
+}
+
+// list returns the unexpired links of a user. Callers hold s.mu.
+func (s *Store) list(owner string) []Link {
+    var links []Link
+    for _, l := range s.links {
+        if l.Owner == owner && !l.Expired() {
+            links = append(links, *l)
+        }
+    }
+    sort.Slice(links, func(i, j int) bool {
+        if !links[i].Created.Equal(links[j].Created) {
+            return links[i].Created.After(links[j].Created)
+        }
+        return links[i].ID < links[j].ID
+    })
+    return links
+}
+
+// prune drops expired links. Callers hold s.mu.
+func (s *Store) prune() {
+    for id, l := range s.links {
+        if l.Expired() {
+            delete(s.links, id)
+        }
+    }
+    for id := range s.attempts {
+        if _, ok := s.links[id]; !ok {
+            delete(s.attempts, id)
+        }
+    }
+}
+
+// store writes all links. Callers hold s.mu.
+func (s *Store) store() error {
+    links := make([]*Link, 0, len(s.links))
+    for _, l := range s.links {
+        links = append(links, l)
+    }
+    sort.Slice(links, func(i, j int) bool { return links[i].ID < links[j].ID })
+    return fileutil.WriteJSON(s.path, links)
+}
+
+// loadSecret reads the signing key, generating it when missing
+func loadSecret(path string) ([]byte, error) {
+    secret, err := os.ReadFile(path)
+    if err == nil && len(secret) >= 32 {
+        return secret, nil
+    }
+    if err != nil && !os.IsNotExist(err) {
+        return nil, fmt.Errorf("failed to read %s: %w", path, err)
+    }
+
+    secret = make([]byte, 32)
+    if _, err := rand.Read(secret); err != nil {
+        return nil, err
+    }
+    if err := fileutil.MakeDirectory(filepath.Dir(path)); err != nil {
+        return nil, err
+    }
+    if err := os.WriteFile(path, secret, 0600); err != nil {
+        return nil, fmt.Errorf("failed to write %s: %w", path, err)
+    }
+    return secret, nil
+}
+
+func newID() string {
+    b := make([]byte, 12)
+    rand.Read(b)
+    return hex.EncodeToString(b)
+}
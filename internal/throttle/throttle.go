@@ -0,0 +1,217 @@
+// internal/throttle/throttle.go
+package throttle
+
+import (
+    "errors"
+    "fmt"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strconv"
+    "sync"
+
+    "rutorrent-web/internal/rtorrent"
+    "rutorrent-web/internal/services"
+    "rutorrent-web/pkg/fileutil"
+)
+
+// maxChannels bounds the channels that can be defined
+const maxChannels = 32
+
+// namePattern restricts channel names to what rTorrent commands take
+// without quoting
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// ErrNoChannel is returned when assigning torrents to an unknown channel
+var ErrNoChannel = errors.New("no such throttle channel")
+
+// Channel is a named rTorrent throttle with upload and download limits in
+// KiB/s; 0 is unlimited
+type Channel struct {
+    Name string `json:"name"`
+    Up   int64  `json:"up"`
+    Down int64  `json:"down"`
+}
+
+// Settings are the channels and the labels bound to them
+type Settings struct {
+    Channels []Channel         `json:"channels"`
+    Labels   map[string]string `json:"labels,omitempty"` // label to channel for new torrents
+}
+
+// Usage is a channel with the torrents on it and their current rates
+type Usage struct {
+    Channel
+    Torrents int   `json:"torrents"`
+    UpRate   int64 `json:"up_rate"`   // bytes/s
+    DownRate int64 `json:"down_rate"` // bytes/s
+}
+
+// Store keeps the throttle channels in throttle.json and sets them up in
+// rTorrent, which forgets them on restart
+type Store struct {
+    torrents *services.TorrentService
+    path     string
+    settings Settings
+    mu       sync.RWMutex
+}
+
+// NewStore loads the channels kept in dir
+func NewStore(torrents *services.TorrentService, dir string) (*Store, error) {
+    s := &Store{torrents: torrents, path: filepath.Join(dir, "throttle.json")}
+    if err := fileutil.ReadJSON(s.path, &s.settings); err != nil {
+        return nil, err
+    }
+    return s, nil
+}
+
+// Settings returns the channels and label bindings
+func (s *Store) Settings() Settings {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    settings := Settings{
+        Channels: append([]Channel{}, s.settings.Channels...),
+        Labels:   make(map[string]string, len(s.settings.Labels)),
+    }
+    for label, channel := range s.settings.Labels {
+        settings.Labels[label] = channel
+    }
+    return settings
+}
+
+// SetSettings validates and stores new settings and applies the limits.
+// rTorrent can't delete throttles, so channels that were removed are set
+// to unlimited.
+func (s *Store) SetSettings(settings Settings) error {
+    if len(settings.Channels) > maxChannels {
+        return fmt.Errorf("too many throttle channels (maximum %d)", maxChannels)
+    }
+    names := make(map[string]bool, len(settings.Channels))
+    for _, c := range settings.Channels {
+        if !namePattern.MatchString(c.Name) {
+            return fmt.Errorf("invalid channel name %q: use letters, digits, _ and -", c.Name)
+        }
+        if names[c.Name] {
+            return fmt.Errorf("duplicate channel %q", c.Name)
+        }
+        names[c.Name] = true
+        if c.Up < 0 || c.Down < 0 {
+            return fmt.Errorf("channel %q: limits can't be negative", c.Name)
+        }
+    }
+    labels := make(map[string]string, len(settings.Labels))
+    for label, channel := range settings.Labels {
+        if !names[channel] {
+            return fmt.Errorf("label %q is bound to unknown channel %q", label, channel)
+        }
+        labels[services.NormalizeLabel(label)] = channel
+    }
+    settings.Labels = labels
+
+    s.mu.Lock()
+    var removed []Channel
+    for _, c := range s.settings.Channels {
+        if !names[c.Name] {
+            removed = append(removed, Channel{Name: c.Name})
+        }
+    }
+    if err := fileutil.WriteJSON(s.path, settings); err != nil {
+        s.mu.Unlock()
+        return err
+    }
+    s.settings = settings
+    s.mu.Unlock()
+
+    return s.apply(append(removed, settings.Channels...))
+}
+
+// Apply sets up every channel in rTorrent
+func (s *Store) Apply() error {
+    return s.apply(s.Settings().Channels)
+}
+
+func (s *Store) apply(channels []Channel) error {
+    calls := make([]rtorrent.MethodCall, 0, len(channels)*2)
+    for _, c := range channels {
+        calls = append(calls,
+            rtorrent.MethodCall{Method: "throttle.up", Params: []interface{}{"", c.Name, strconv.FormatInt(c.Up, 10)}},
+            rtorrent.MethodCall{Method: "throttle.down", Params: []interface{}{"", c.Name, strconv.FormatInt(c.Down, 10)}},
+        )
+    }
+    if len(calls) == 0 {
+        return nil
+    }
+    results, err := s.torrents.Client().SystemMulticall(calls)
+    if err != nil {
+        return err
+    }
+    return rtorrent.FirstError(results)
+}
+
+// Assign puts torrents on a channel, or takes them off with an empty name
+func (s *Store) Assign(hashes []string, channel string) error {
+    if channel != "" && s.channel(channel) == nil {
+        return ErrNoChannel
+    }
+    return s.torrents.SetThrottle(hashes, channel)
+}
+
+func (s *Store) channel(name string) *Channel {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    for i := range s.settings.Channels {
+        if s.settings.Channels[i].Name == name {
+            c := s.settings.Channels[i]
+            return &c
+        }
+    }
+    return nil
+}
+
+// Usage returns every channel with the torrents on it and their summed
+// rates, in the configured order
+func (s *Store) Usage() []Usage {
+    channels := s.Settings().Channels
+    usage := make([]Usage, len(channels))
+    index := make(map[string]int, len(channels))
+    for i, c := range channels {
+        usage[i].Channel = c
+        index[c.Name] = i
+    }
+    for _, t := range s.torrents.Snapshot() {
+        i, ok := index[t.Throttle]
+        if !ok {
+            continue
+        }
+        usage[i].Torrents++
+        usage[i].UpRate += t.UploadRate
+        usage[i].DownRate += t.DownloadRate
+    }
+    return usage
+}
+
+// Filter puts new torrents on the channel bound to their label, a parent
+// label's otherwise, unless a channel was already chosen. It is meant to
+// run as part of the service's add filter.
+func (s *Store) Filter(info *services.AddInfo, opts *services.AddTorrentOptions) {
+    if opts.Throttle != "" || opts.Label == "" {
+        return
+    }
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    // The longest bound label that is the torrent's or one of its parents
+    var labels []string
+    for label := range s.settings.Labels {
+        labels = append(labels, label)
+    }
+    sort.Slice(labels, func(i, j int) bool { return len(labels[i]) > len(labels[j]) })
+    for _, label := range labels {
+        if opts.Label == label || (len(opts.Label) > len(label) && opts.Label[:len(label)+1] == label+"/") {
+            opts.Throttle = s.settings.Labels[label]
+            return
+        }
+    }
+}
@@ -0,0 +1,234 @@
+// internal/torrentfile/resume.go
+package torrentfile
+
+import (
+    "bytes"
+    "crypto/sha1"
+    "errors"
+    "fmt"
+    "io"
+    "math/rand"
+    "os"
+    "path/filepath"
+
+    "github.com/anacrolix/torrent/bencode"
+    "github.com/anacrolix/torrent/metainfo"
+)
+
+// ErrVerifyFailed is returned when a sampled piece doesn't match its hash
+var ErrVerifyFailed = errors.New("existing data does not match the torrent")
+
+// ResumeOptions controls fast-resume generation
+type ResumeOptions struct {
+    // Verify is the number of random complete pieces hashed before the data
+    // is trusted; 0 trusts file sizes alone
+    Verify int
+}
+
+// ResumeReport tells how much of a torrent was found on disk
+type ResumeReport struct {
+    Pieces   int      // pieces in the torrent
+    Complete int      // pieces marked complete
+    Missing  []string // files missing or of the wrong size
+    Verified int      // pieces hashed
+}
+
+// resumeFile is a file entry of libtorrent_resume
+type resumeFile struct {
+    Priority  int   `bencode:"priority"`
+    Mtime     int64 `bencode:"mtime"`
+    Completed int64 `bencode:"completed"`
+}
+
+// AddResume adds libtorrent_resume data to a .torrent so rTorrent takes the
+// content already in dir as downloaded instead of hashing it. dir is the
+// download directory as given to rTorrent: the content is expected at
+// dir/<name>. Files that are missing or have the wrong size leave their
+// pieces incomplete; rTorrent downloads those.
+func AddResume(data []byte, dir string, opts ResumeOptions) ([]byte, *ResumeReport, error) {
+    var dict map[string]bencode.Bytes
+    if err := bencode.Unmarshal(data, &dict); err != nil {
+        return nil, nil, fmt.Errorf("failed to decode torrent: %w", err)
+    }
+    raw, ok := dict["info"]
+    if !ok {
+        return nil, nil, errors.New("torrent has no info dictionary")
+    }
+    var info metainfo.Info
+    if err := bencode.Unmarshal(raw, &info); err != nil {
+        return nil, nil, fmt.Errorf("failed to decode info: %w", err)
+    }
+    if info.PieceLength <= 0 || len(info.Pieces)%sha1.Size != 0 {
+        return nil, nil, errors.New("torrent has an invalid piece layout")
+    }
+
+    files := layout(&info, dir)
+    pieces := len(info.Pieces) / sha1.Size
+    report := &ResumeReport{Pieces: pieces}
+
+    // A piece is complete when every file it touches is present with the
+    // right size
+    complete := make([]bool, pieces)
+    for i := range complete {
+        complete[i] = true
+    }
+    entries := make([]resumeFile, len(files))
+    for i, f := range files {
+        entries[i] = resumeFile{Priority: 1}
+        first, last := f.pieces(info.PieceLength)
+        st, err := os.Stat(f.path)
+        if err != nil || !st.Mode().IsRegular() || st.Size() != f.length {
+            report.Missing = append(report.Missing, f.path)
+            for p := first; p <= last && p < pieces; p++ {
+                complete[p] = false
+            }
+            continue
+        }
+        entries[i].Mtime = st.ModTime().Unix()
+    }
+    for i, f := range files {
+        first, last := f.pieces(info.PieceLength)
+        for p := first; p <= last && p < pieces; p++ {
+            if complete[p] {
+                entries[i].Completed++
+            }
+        }
+    }
+    for _, c := range complete {
+        if c {
+            report.Complete++
+        }
+    }
+
+    if opts.Verify > 0 && report.Complete > 0 {
+        verified, err := verify(&info, files, complete, opts.Verify)
+        report.Verified = verified
+        if err != nil {
+            return nil, report, err
+        }
+    }
+
+    resume := map[string]interface{}{"files": entries}
+    if report.Complete == pieces {
+        // A number means every piece is done
+        resume["bitfield"] = pieces
+    } else {
+        resume["bitfield"] = bitfield(complete)
+    }
+    encoded, err := bencode.Marshal(resume)
+    if err != nil {
+        return nil, report, err
+    }
+    dict["libtorrent_resume"] = encoded
+
+    out, err := bencode.Marshal(dict)
+    return out, report, err
+}
+
+// fileSpan is a torrent file with its location on disk and in the torrent
+type fileSpan struct {
+    path   string
+    offset int64
+    length int64
+}
+
+// pieces returns the first and last piece a file touches. Empty files
+// touch the piece at their offset.
+func (f fileSpan) pieces(pieceLength int64) (int, int) {
+    first := int(f.offset / pieceLength)
+    if f.length == 0 {
+        return first, first
+    }
+    return first, int((f.offset + f.length - 1) / pieceLength)
+}
+
+// layout returns the files of a torrent in order with their disk paths
+func layout(info *metainfo.Info, dir string) []fileSpan {
+    name := info.BestName()
+    if !info.IsDir() {
+        return []fileSpan{{path: filepath.Join(dir, name), length: info.Length}}
+    }
+
+    files := make([]fileSpan, 0, len(info.Files))
+    var offset int64
+    for _, f := range info.Files {
+        parts := append([]string{dir, name}, f.BestPath()...)
+        files = append(files, fileSpan{path: filepath.Join(parts...), offset: offset, length: f.Length})
+        offset += f.Length
+    }
+    return files
+}
+
+// verify hashes up to n random complete pieces
+func verify(info *metainfo.Info, files []fileSpan, complete []bool, n int) (int, error) {
+    var candidates []int
+    for i, c := range complete {
+        if c {
+            candidates = append(candidates, i)
+        }
+    }
+    rand.Shuffle(len(candidates), func(i, j int) {
+        candidates[i], candidates[j] = candidates[j], candidates[i]
+    })
+    if n > len(candidates) {
+        n = len(candidates)
+    }
+
+    total := info.TotalLength()
+    buf := make([]byte, info.PieceLength)
+    for done, p := range candidates[:n] {
+        start := int64(p) * info.PieceLength
+        length := info.PieceLength
+        if start+length > total {
+            length = total - start
+        }
+        if err := readAt(files, buf[:length], start); err != nil {
+            return done, err
+        }
+        sum := sha1.Sum(buf[:length])
+        if !bytes.Equal(sum[:], info.Pieces[p*sha1.Size:(p+1)*sha1.Size]) {
+            return done + 1, fmt.Errorf("%w: piece %d", ErrVerifyFailed, p)
+        }
+    }
+    return n, nil
+}
+
+// readAt fills buf with the torrent's content starting at offset
+func readAt(files []fileSpan, buf []byte, offset int64) error {
+    for _, f := range files {
+        if len(buf) == 0 {
+            return nil
+        }
+        end := f.offset + f.length
+        if offset >= end || f.length == 0 {
+            continue
+        }
+        n := end - offset
+        if n > int64(len(buf)) {
+            n = int64(len(buf))
+        }
+        file, err := os.Open(f.path)
+        if err != nil {
+            return err
+        }
+        _, err = file.ReadAt(buf[:n], offset-f.offset)
+        file.Close()
+        if err != nil && err != io.EOF {
+            return err
+        }
+        buf = buf[n:]
+        offset += n
+    }
+    return nil
+}
+
+// bitfield packs piece states, most significant bit first
+func bitfield(complete []bool) string {
+    b := make([]byte, (len(complete)+7)/8)
+    for i, c := range complete {
+        if c {
+            b[i/8] |= 0x80 >> (i % 8)
+        }
+    }
+    return string(b)
+}
@@ -0,0 +1,197 @@
+// internal/torrentfile/resume_test.go
+package torrentfile
+
+import (
+    "crypto/sha1"
+    "errors"
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+
+    "github.com/anacrolix/torrent/bencode"
+    "github.com/anacrolix/torrent/metainfo"
+)
+
+// testPieceLength is small so a few bytes give several pieces
+const testPieceLength = 16
+
+type testFile struct {
+    path string
+    size int64
+}
+
+// makeTorrent writes files below dir/name and returns a .torrent of them.
+// A single file without a path makes a single-file torrent.
+func makeTorrent(t *testing.T, dir, name string, files []testFile) []byte {
+    t.Helper()
+    var content []byte
+    info := metainfo.Info{Name: name, PieceLength: testPieceLength}
+    for i, f := range files {
+        data := make([]byte, f.size)
+        for j := range data {
+            data[j] = byte(j*7 + i)
+        }
+        content = append(content, data...)
+
+        path := filepath.Join(dir, name)
+        if f.path != "" {
+            path = filepath.Join(dir, name, filepath.FromSlash(f.path))
+            info.Files = append(info.Files, metainfo.FileInfo{Path: []string{f.path}, Length: f.size})
+        } else {
+            info.Length = f.size
+        }
+        if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+            t.Fatal(err)
+        }
+        if err := os.WriteFile(path, data, 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    for off := 0; off < len(content); off += testPieceLength {
+        sum := sha1.Sum(content[off:min(off+testPieceLength, len(content))])
+        info.Pieces = append(info.Pieces, sum[:]...)
+    }
+
+    infoBytes, err := bencode.Marshal(info)
+    if err != nil {
+        t.Fatal(err)
+    }
+    data, err := bencode.Marshal(map[string]bencode.Bytes{"info": infoBytes})
+    if err != nil {
+        t.Fatal(err)
+    }
+    return data
+}
+
+func TestAddResume(t *testing.T) {
+    // 37 bytes in pieces of 16: a covers pieces 0-1, b piece 1, c pieces
+    // 1-2, and piece 2 is 5 bytes long
+    multi := []testFile{{path: "a", size: 20}, {path: "b", size: 10}, {path: "c", size: 7}}
+
+    tests := []struct {
+        name          string
+        files         []testFile
+        change        func(root string) error // applied to the data before resuming
+        verify        int
+        wantComplete  int
+        wantMissing   []string
+        wantCompleted []int64 // completed pieces per file
+        wantBitfield  interface{}
+        wantVerified  int
+        wantErr       error
+    }{
+        {
+            name:          "all present",
+            files:         multi,
+            verify:        3,
+            wantComplete:  3,
+            wantCompleted: []int64{2, 1, 2},
+            wantBitfield:  int64(3),
+            wantVerified:  3,
+        },
+        {
+            name:          "one missing file",
+            files:         multi,
+            change:        func(root string) error { return os.Remove(filepath.Join(root, "b")) },
+            verify:        3,
+            wantComplete:  2,
+            wantMissing:   []string{"b"},
+            wantCompleted: []int64{1, 0, 1},
+            wantBitfield:  "\xa0",
+            wantVerified:  2,
+        },
+        {
+            name:          "last file missing",
+            files:         multi,
+            change:        func(root string) error { return os.Remove(filepath.Join(root, "c")) },
+            wantComplete:  1,
+            wantMissing:   []string{"c"},
+            wantCompleted: []int64{1, 0, 0},
+            wantBitfield:  "\x80",
+        },
+        {
+            name:          "wrong size",
+            files:         multi,
+            change:        func(root string) error { return os.Truncate(filepath.Join(root, "a"), 19) },
+            wantComplete:  1,
+            wantMissing:   []string{"a"},
+            wantCompleted: []int64{0, 0, 1},
+            wantBitfield:  "\x20",
+        },
+        {
+            name:          "short last piece of a single file",
+            files:         []testFile{{size: 37}},
+            verify:        3,
+            wantComplete:  3,
+            wantCompleted: []int64{3},
+            wantBitfield:  int64(3),
+            wantVerified:  3,
+        },
+        {
+            name:  "corrupt short last piece",
+            files: multi,
+            change: func(root string) error {
+                return os.WriteFile(filepath.Join(root, "c"), make([]byte, 7), 0644)
+            },
+            verify:  3,
+            wantErr: ErrVerifyFailed,
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            dir := t.TempDir()
+            data := makeTorrent(t, dir, "content", tt.files)
+            root := filepath.Join(dir, "content")
+            if tt.change != nil {
+                if err := tt.change(root); err != nil {
+                    t.Fatal(err)
+                }
+            }
+
+            out, report, err := AddResume(data, dir, ResumeOptions{Verify: tt.verify})
+            if tt.wantErr != nil {
+                if !errors.Is(err, tt.wantErr) {
+                    t.Fatalf("AddResume error = %v, want %v", err, tt.wantErr)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("AddResume: %v", err)
+            }
+
+            if report.Pieces != 3 || report.Complete != tt.wantComplete || report.Verified != tt.wantVerified {
+                t.Errorf("report = %d/%d complete, %d verified, want %d/3, %d",
+                    report.Complete, report.Pieces, report.Verified, tt.wantComplete, tt.wantVerified)
+            }
+            var missing []string
+            for _, p := range report.Missing {
+                rel, _ := filepath.Rel(root, p)
+                missing = append(missing, filepath.ToSlash(rel))
+            }
+            if !reflect.DeepEqual(missing, tt.wantMissing) {
+                t.Errorf("missing = %v, want %v", missing, tt.wantMissing)
+            }
+
+            var torrent struct {
+                Resume struct {
+                    Bitfield interface{}  `bencode:"bitfield"`
+                    Files    []resumeFile `bencode:"files"`
+                } `bencode:"libtorrent_resume"`
+            }
+            if err := bencode.Unmarshal(out, &torrent); err != nil {
+                t.Fatal(err)
+            }
+            if !reflect.DeepEqual(torrent.Resume.Bitfield, tt.wantBitfield) {
+                t.Errorf("bitfield = %q, want %q", torrent.Resume.Bitfield, tt.wantBitfield)
+            }
+            var completed []int64
+            for _, f := range torrent.Resume.Files {
+                completed = append(completed, f.Completed)
+            }
+            if !reflect.DeepEqual(completed, tt.wantCompleted) {
+                t.Errorf("completed pieces per file = %v, want %v", completed, tt.wantCompleted)
+            }
+        })
+    }
+}
@@ -0,0 +1,82 @@
+// internal/torrentfile/source.go
+package torrentfile
+
+import (
+    "crypto/sha1"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "strings"
+
+    "github.com/anacrolix/torrent/bencode"
+)
+
+// ErrHashMismatch is returned when a file's info dictionary doesn't belong
+// to the expected torrent
+var ErrHashMismatch = errors.New("info hash does not match")
+
+// sessionKeys are added by rTorrent to the copies in its session directory
+// and have no place in a .torrent handed to other clients
+var sessionKeys = []string{"rtorrent", "libtorrent_resume", "rtorrent_meta_download"}
+
+// InfoHash returns the upper-case hex v1 info hash of raw .torrent data,
+// hashing the info dictionary exactly as stored
+func InfoHash(data []byte) (string, error) {
+    var dict map[string]bencode.Bytes
+    if err := bencode.Unmarshal(data, &dict); err != nil {
+        return "", fmt.Errorf("failed to decode torrent: %w", err)
+    }
+    info, ok := dict["info"]
+    if !ok {
+        return "", errors.New("torrent has no info dictionary")
+    }
+    sum := sha1.Sum(info)
+    return strings.ToUpper(hex.EncodeToString(sum[:])), nil
+}
+
+// Rebuild regenerates a clean .torrent around the info dictionary of data,
+// usually a session copy: rTorrent's own keys are dropped and, when the
+// file has no announce URLs, trackers are filled in. The info dictionary is
+// kept byte for byte so the result has the same info hash, which must
+// equal hash.
+func Rebuild(data []byte, hash string, trackers []string) ([]byte, error) {
+    var dict map[string]bencode.Bytes
+    if err := bencode.Unmarshal(data, &dict); err != nil {
+        return nil, fmt.Errorf("failed to decode torrent: %w", err)
+    }
+    info, ok := dict["info"]
+    if !ok {
+        return nil, errors.New("torrent has no info dictionary")
+    }
+    sum := sha1.Sum(info)
+    if !strings.EqualFold(hex.EncodeToString(sum[:]), hash) {
+        return nil, ErrHashMismatch
+    }
+
+    for _, key := range sessionKeys {
+        delete(dict, key)
+    }
+
+    _, hasAnnounce := dict["announce"]
+    _, hasList := dict["announce-list"]
+    if !hasAnnounce && !hasList && len(trackers) > 0 {
+        announce, err := bencode.Marshal(trackers[0])
+        if err != nil {
+            return nil, err
+        }
+        dict["announce"] = announce
+        if len(trackers) > 1 {
+            tiers := make([][]string, 0, len(trackers))
+            for _, t := range trackers {
+                tiers = append(tiers, []string{t})
+            }
+            list, err := bencode.Marshal(tiers)
+            if err != nil {
+                return nil, err
+            }
+            dict["announce-list"] = list
+        }
+    }
+
+    return bencode.Marshal(dict)
+}
@@ -6,7 +6,6 @@ import (
     "crypto/sha1"
     "encoding/hex"
     "fmt"
-    "io"
     "os"
     "path/filepath"
     "time"
@@ -63,52 +62,39 @@ func NewFromBytes(data []byte) (*Torrent, error) {
 // CreateFromPath creates a new torrent from a file or directory
 func CreateFromPath(path string, opts CreateOptions) (*Torrent, error) {
     // Validate path
-    info, err := os.Stat(path)
-    if err != nil {
+    if _, err := os.Stat(path); err != nil {
         return nil, fmt.Errorf("failed to stat path: %w", err)
     }
 
-    // Create builder
-    builder := metainfo.Builder{}
-    if info.IsDir() {
-        if err := builder.AddDir(path); err != nil {
-            return nil, fmt.Errorf("failed to add directory: %w", err)
-        }
-    } else {
-        if err := builder.AddFile(path); err != nil {
-            return nil, fmt.Errorf("failed to add file: %w", err)
-        }
+    // Hash the content
+    info := metainfo.Info{PieceLength: opts.PieceLength}
+    if info.PieceLength == 0 {
+        info.PieceLength = 256 << 10
     }
-
-    // Set options
-    builder.PieceLength = opts.PieceLength
     if opts.Private {
-        builder.Private = true
+        private := true
+        info.Private = &private
     }
-
-    // Build metainfo
-    mi, err := builder.Submit()
-    if err != nil {
+    if err := info.BuildFromFilePath(path); err != nil {
         return nil, fmt.Errorf("failed to build torrent: %w", err)
     }
+    infoBytes, err := bencode.Marshal(info)
+    if err != nil {
+        return nil, fmt.Errorf("failed to encode info: %w", err)
+    }
+
+    mi := &metainfo.MetaInfo{
+        InfoBytes:    infoBytes,
+        CreationDate: time.Now().Unix(),
+        CreatedBy:    opts.CreatedBy,
+    }
 
     // Set announce lists
     if len(opts.Trackers) > 0 {
+        mi.Announce = opts.Trackers[0]
         mi.AnnounceList = [][]string{opts.Trackers}
     }
 
-    // Set creation date
-    mi.CreationDate = time.Now().Unix()
-
-    // Set created by
-    mi.CreatedBy = opts.CreatedBy
-
-    // Get info
-    info, err := mi.UnmarshalInfo()
-    if err != nil {
-        return nil, fmt.Errorf("failed to unmarshal info: %w", err)
-    }
-
     return &Torrent{
         info:     &info,
         metainfo: mi,
@@ -222,4 +208,20 @@ func CalculateHash(data []byte) string {
     hash := sha1.New()
     hash.Write(data)
     return hex.EncodeToString(hash.Sum(nil))
-}
\ No newline at end of file
+}
+
+// GetTrackers returns the announce URLs of all tiers, announce included, in
+// order and without duplicates
+func (t *Torrent) GetTrackers() []string {
+    var urls []string
+    seen := make(map[string]bool)
+    for _, tier := range t.metainfo.UpvertedAnnounceList() {
+        for _, u := range tier {
+            if u != "" && !seen[u] {
+                seen[u] = true
+                urls = append(urls, u)
+            }
+        }
+    }
+    return urls
+}
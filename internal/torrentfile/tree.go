@@ -0,0 +1,83 @@
+// internal/torrentfile/tree.go
+package torrentfile
+
+import (
+    "path"
+    "sort"
+    "strconv"
+)
+
+// FileNode is a file or folder of a torrent's content
+type FileNode struct {
+    Name     string      `json:"name"`
+    Path     string      `json:"path"`  // slash separated, relative to the torrent
+    Size     int64       `json:"size"`  // folders: the sum of their files
+    Index    int         `json:"index"` // file index as rTorrent numbers it, -1 for folders
+    Children []*FileNode `json:"children,omitempty"`
+}
+
+// FileTree returns the content of the torrent as a tree. Single-file
+// torrents are a lone file node; multi-file torrents a folder named after
+// the torrent. Files are indexed in info dictionary order, the order
+// rTorrent uses for f.* commands.
+func (t *Torrent) FileTree() *FileNode {
+    if !t.info.IsDir() {
+        return &FileNode{Name: t.info.BestName(), Path: t.info.BestName(), Size: t.info.Length, Index: 0}
+    }
+
+    root := &FileNode{Name: t.info.BestName(), Index: -1}
+    folders := map[string]*FileNode{"": root}
+    for i, f := range t.info.Files {
+        parts := f.BestPath()
+        if len(parts) == 0 {
+            // Malformed, but rTorrent still counts it
+            parts = []string{"file " + strconv.Itoa(i)}
+        }
+        parent := root
+        for depth := range parts[:len(parts)-1] {
+            dir := path.Join(parts[:depth+1]...)
+            node, ok := folders[dir]
+            if !ok {
+                node = &FileNode{Name: parts[depth], Path: dir, Index: -1}
+                folders[dir] = node
+                parent.Children = append(parent.Children, node)
+            }
+            parent = node
+        }
+        parent.Children = append(parent.Children, &FileNode{
+            Name:  parts[len(parts)-1],
+            Path:  path.Join(parts...),
+            Size:  f.Length,
+            Index: i,
+        })
+    }
+    root.sum()
+    return root
+}
+
+// sum fills in folder sizes and sorts children, folders first
+func (n *FileNode) sum() int64 {
+    if n.Index >= 0 {
+        return n.Size
+    }
+    n.Size = 0
+    for _, c := range n.Children {
+        n.Size += c.sum()
+    }
+    sort.SliceStable(n.Children, func(i, j int) bool {
+        a, b := n.Children[i], n.Children[j]
+        if (a.Index < 0) != (b.Index < 0) {
+            return a.Index < 0
+        }
+        return a.Name < b.Name
+    })
+    return n.Size
+}
+
+// FileCount returns the number of files in the torrent
+func (t *Torrent) FileCount() int {
+    if !t.info.IsDir() {
+        return 1
+    }
+    return len(t.info.Files)
+}
@@ -0,0 +1,168 @@
+// internal/trackers/trackers.go
+package trackers
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+
+    "rutorrent-web/internal/services"
+    "rutorrent-web/pkg/fileutil"
+)
+
+// domainPattern restricts tracker names used in file lookups
+var domainPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]{0,198}[a-z0-9])?$`)
+
+// iconTypes are the favicon files looked up per tracker, in order
+var iconTypes = []struct {
+    ext  string
+    mime string
+}{
+    {".png", "image/png"},
+    {".ico", "image/x-icon"},
+    {".svg", "image/svg+xml"},
+}
+
+// Group is a tracker domain with totals over the torrents whose primary
+// tracker it is, so each torrent counts once.
+type Group struct {
+    Domain   string `json:"domain"`
+    Name     string `json:"name"` // display name, the domain unless renamed
+    Count    int    `json:"count"`
+    Size     int64  `json:"size"`
+    DownRate int64  `json:"down_rate"`
+    UpRate   int64  `json:"up_rate"`
+}
+
+// Groups builds the tracker panel from the snapshot, sorted by display name
+func Groups(torrents []*services.Torrent, names map[string]string) []Group {
+    groups := make(map[string]*Group)
+    for _, t := range torrents {
+        domain := t.PrimaryTracker()
+        if domain == "" {
+            continue
+        }
+        g, ok := groups[domain]
+        if !ok {
+            g = &Group{Domain: domain, Name: domain}
+            if name := names[domain]; name != "" {
+                g.Name = name
+            }
+            groups[domain] = g
+        }
+        g.Count++
+        g.Size += t.Size
+        g.DownRate += t.DownloadRate
+        g.UpRate += t.UploadRate
+    }
+
+    result := make([]Group, 0, len(groups))
+    for _, g := range groups {
+        result = append(result, *g)
+    }
+    sort.Slice(result, func(i, j int) bool {
+        a, b := strings.ToLower(result[i].Name), strings.ToLower(result[j].Name)
+        if a != b {
+            return a < b
+        }
+        return result[i].Domain < result[j].Domain
+    })
+    return result
+}
+
+// Store keeps per-user tracker display names and finds tracker icons
+type Store struct {
+    path     func(user string) string
+    iconDirs []string
+    names    map[string]map[string]string // display names by user and domain
+    mu       sync.Mutex
+}
+
+// NewStore creates a store that keeps display names in trackers.json of
+// each user's settings directory and looks up icons in iconDirs, first
+// match wins
+func NewStore(iconDirs ...string) *Store {
+    return &Store{
+        path: func(user string) string {
+            return filepath.Join(fileutil.GetSettingsPathEx(user), "trackers.json")
+        },
+        iconDirs: iconDirs,
+        names:    make(map[string]map[string]string),
+    }
+}
+
+// Names returns the display names of a user by domain
+func (s *Store) Names(user string) (map[string]string, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.load(user)
+}
+
+// SetName sets the display name of a tracker domain. An empty name restores
+// the domain.
+func (s *Store) SetName(user, domain, name string) error {
+    domain = strings.ToLower(strings.TrimSpace(domain))
+    name = strings.TrimSpace(name)
+    if !domainPattern.MatchString(domain) {
+        return fmt.Errorf("invalid tracker: %s", domain)
+    }
+    if len(name) > 100 {
+        return fmt.Errorf("tracker name is too long")
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    names, err := s.load(user)
+    if err != nil {
+        return err
+    }
+    if name == "" || name == domain {
+        delete(names, domain)
+    } else {
+        names[domain] = name
+    }
+    if err := fileutil.WriteJSON(s.path(user), names); err != nil {
+        return err
+    }
+    s.names[user] = names
+    return nil
+}
+
+// Icon returns the icon file and its MIME type for a tracker domain
+func (s *Store) Icon(domain string) (string, string, bool) {
+    domain = strings.ToLower(domain)
+    if !domainPattern.MatchString(domain) {
+        return "", "", false
+    }
+    for _, dir := range s.iconDirs {
+        for _, it := range iconTypes {
+            path := filepath.Join(dir, domain+it.ext)
+            if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+                return path, it.mime, true
+            }
+        }
+    }
+    return "", "", false
+}
+
+// load returns a copy of the user's names. Callers hold s.mu.
+func (s *Store) load(user string) (map[string]string, error) {
+    names, ok := s.names[user]
+    if !ok {
+        if err := fileutil.ReadJSON(s.path(user), &names); err != nil {
+            return nil, err
+        }
+        s.names[user] = names
+    }
+
+    copied := make(map[string]string, len(names))
+    for k, v := range names {
+        copied[k] = v
+    }
+    return copied, nil
+}
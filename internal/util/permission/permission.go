@@ -6,6 +6,7 @@ import (
     "os/user"
     "strconv"
     "path/filepath"
+    "syscall"
 )
 
 // DoesUserHave checks if a user has the specified permissions on a file
@@ -3,7 +3,9 @@ package utility
 
 import (
     "fmt"
+    "io"
     "os"
+    "os/exec"
     "path/filepath"
     "sort"
     "strings"
@@ -82,7 +84,7 @@ func ExeExists(exe string) bool {
     }
 
     // Search in PATH
-    if path, err := exec.LookPath(exe); err == nil {
+    if _, err := exec.LookPath(exe); err == nil {
         return true
     }
 
@@ -171,13 +173,14 @@ func (p *PathUtil) RelPath(target string) (string, error) {
     return filepath.Rel(p.base, target)
 }
 
-// IsSubPath checks if a path is under the base path
+// IsSubPath checks if a path is the base path or under it. Both paths are
+// compared as given, resolve symlinks first when that matters.
 func (p *PathUtil) IsSubPath(path string) bool {
     rel, err := p.RelPath(path)
     if err != nil {
         return false
     }
-    return !strings.Contains(rel, "..")
+    return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
 }
 
 // Common configuration directories
@@ -0,0 +1,391 @@
+// internal/views/views.go
+package views
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+
+    "rutorrent-web/internal/services"
+    "rutorrent-web/pkg/fileutil"
+)
+
+// exportVersion is written into exported files so the format can evolve
+const exportVersion = 1
+
+// maxViews bounds the number of saved views per user
+const maxViews = 200
+
+// ErrNotFound is returned for view IDs the user doesn't have
+var ErrNotFound = errors.New("view not found")
+
+// Grouping modes a view can use for the torrent list
+var groupings = map[string]bool{
+    "":        true,
+    "label":   true,
+    "tracker": true,
+    "status":  true,
+}
+
+// View is a saved, named torrent list configuration
+type View struct {
+    ID       string    `json:"id"`
+    Name     string    `json:"name"`
+    Query    string    `json:"query"`             // search query, see services.ParseQuery
+    Sort     string    `json:"sort,omitempty"`    // sort spec, see services.ParseSort
+    Columns  []string  `json:"columns,omitempty"` // visible columns in order, empty for the default set
+    GroupBy  string    `json:"group_by,omitempty"`
+    Created  time.Time `json:"created"`
+    Modified time.Time `json:"modified"`
+}
+
+// Export is the JSON document used to share views between users
+type Export struct {
+    Version int    `json:"version"`
+    Views   []View `json:"views"`
+}
+
+// Store keeps saved views per user in the user's settings directory
+type Store struct {
+    path  func(user string) string
+    views map[string][]View // loaded views by user
+    mu    sync.Mutex
+}
+
+// NewStore creates a store that keeps views in views.json of each user's
+// settings directory
+func NewStore() *Store {
+    return &Store{
+        path: func(user string) string {
+            return filepath.Join(fileutil.GetSettingsPathEx(user), "views.json")
+        },
+        views: make(map[string][]View),
+    }
+}
+
+// List returns the views of a user in display order
+func (s *Store) List(user string) ([]View, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return s.load(user)
+}
+
+// Get returns a single view
+func (s *Store) Get(user, id string) (*View, error) {
+    views, err := s.List(user)
+    if err != nil {
+        return nil, err
+    }
+    for i := range views {
+        if views[i].ID == id {
+            return &views[i], nil
+        }
+    }
+    return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// Update replaces an existing view, failing with ErrNotFound when it was
+// deleted in the meantime
+func (s *Store) Update(user string, v View) (*View, error) {
+    if err := Validate(&v); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    views, err := s.load(user)
+    if err != nil {
+        return nil, err
+    }
+    for i := range views {
+        if views[i].ID == v.ID {
+            v.Created = views[i].Created
+            v.Modified = time.Now()
+            views[i] = v
+            if err := s.store(user, views); err != nil {
+                return nil, err
+            }
+            return &v, nil
+        }
+    }
+    return nil, fmt.Errorf("%w: %s", ErrNotFound, v.ID)
+}
+
+// Save creates a view, or replaces the view with the same ID
+func (s *Store) Save(user string, v View) (*View, error) {
+    if err := Validate(&v); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    views, err := s.load(user)
+    if err != nil {
+        return nil, err
+    }
+
+    now := time.Now()
+    v.Modified = now
+    replaced := false
+    if v.ID != "" {
+        for i := range views {
+            if views[i].ID == v.ID {
+                v.Created = views[i].Created
+                views[i] = v
+                replaced = true
+                break
+            }
+        }
+    }
+    if !replaced {
+        if len(views) >= maxViews {
+            return nil, fmt.Errorf("too many saved views (maximum %d)", maxViews)
+        }
+        v.ID = newID()
+        v.Created = now
+        views = append(views, v)
+    }
+
+    if err := s.store(user, views); err != nil {
+        return nil, err
+    }
+    return &v, nil
+}
+
+// Delete removes a view
+func (s *Store) Delete(user, id string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    views, err := s.load(user)
+    if err != nil {
+        return err
+    }
+    for i := range views {
+        if views[i].ID == id {
+            views = append(views[:i:i], views[i+1:]...)
+            return s.store(user, views)
+        }
+    }
+    return fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// Reorder sets the display order of views. IDs not mentioned keep their
+// relative order after the listed ones; repeated IDs are placed once.
+func (s *Store) Reorder(user string, ids []string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    views, err := s.load(user)
+    if err != nil {
+        return err
+    }
+
+    placed := make(map[string]bool, len(ids))
+    ordered := make([]View, 0, len(views))
+    for _, id := range ids {
+        if placed[id] {
+            continue
+        }
+        for _, v := range views {
+            if v.ID == id {
+                ordered = append(ordered, v)
+                placed[id] = true
+                break
+            }
+        }
+    }
+    for _, v := range views {
+        if !placed[v.ID] {
+            ordered = append(ordered, v)
+        }
+    }
+    return s.store(user, ordered)
+}
+
+// Export returns the given views, or all views when ids is empty, as a
+// shareable JSON document
+func (s *Store) Export(user string, ids []string) ([]byte, error) {
+    views, err := s.List(user)
+    if err != nil {
+        return nil, err
+    }
+
+    if len(ids) > 0 {
+        wanted := make(map[string]bool, len(ids))
+        for _, id := range ids {
+            wanted[id] = true
+        }
+        selected := views[:0]
+        for _, v := range views {
+            if wanted[v.ID] {
+                selected = append(selected, v)
+            }
+        }
+        views = selected
+    }
+
+    return json.MarshalIndent(Export{Version: exportVersion, Views: views}, "", "  ")
+}
+
+// Import adds the views of an exported document. Imported views get new IDs;
+// a view whose name is already taken is renamed unless replace is set, in
+// which case the existing view is overwritten.
+func (s *Store) Import(user string, data []byte, replace bool) ([]View, error) {
+    var doc Export
+    if err := json.Unmarshal(data, &doc); err != nil {
+        return nil, fmt.Errorf("invalid views file: %w", err)
+    }
+    if doc.Version > exportVersion {
+        return nil, fmt.Errorf("views file version %d is not supported", doc.Version)
+    }
+    for i := range doc.Views {
+        if err := Validate(&doc.Views[i]); err != nil {
+            return nil, fmt.Errorf("view %q: %w", doc.Views[i].Name, err)
+        }
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    views, err := s.load(user)
+    if err != nil {
+        return nil, err
+    }
+    if len(views)+len(doc.Views) > maxViews {
+        return nil, fmt.Errorf("too many saved views (maximum %d)", maxViews)
+    }
+
+    now := time.Now()
+    imported := make([]View, 0, len(doc.Views))
+    for _, v := range doc.Views {
+        v.ID = newID()
+        v.Created = now
+        v.Modified = now
+
+        existing := indexByName(views, v.Name)
+        switch {
+        case existing >= 0 && replace:
+            v.ID = views[existing].ID
+            v.Created = views[existing].Created
+            views[existing] = v
+        case existing >= 0:
+            v.Name = uniqueName(views, v.Name)
+            fallthrough
+        default:
+            views = append(views, v)
+        }
+        imported = append(imported, v)
+    }
+
+    if err := s.store(user, views); err != nil {
+        return nil, err
+    }
+    return imported, nil
+}
+
+// Counts returns the number of torrents currently matching each view,
+// keyed by view ID. Views whose query no longer parses count as -1. The
+// service keeps counts per snapshot revision, so calling this on every
+// page load costs one evaluation per query and refresh.
+func Counts(svc *services.TorrentService, views []View) map[string]int {
+    counts := make(map[string]int, len(views))
+    for _, v := range views {
+        n, err := svc.Count(v.Query)
+        if err != nil {
+            n = -1
+        }
+        counts[v.ID] = n
+    }
+    return counts
+}
+
+// Validate normalizes a view and checks its query, sort and grouping
+func Validate(v *View) error {
+    v.Name = strings.TrimSpace(v.Name)
+    v.Query = strings.TrimSpace(v.Query)
+    v.Sort = strings.TrimSpace(v.Sort)
+
+    if v.Name == "" {
+        return fmt.Errorf("view name is required")
+    }
+    if len(v.Name) > 100 {
+        return fmt.Errorf("view name is too long")
+    }
+    if _, err := services.ParseQuery(v.Query); err != nil {
+        return err
+    }
+    if _, err := services.ParseSort(v.Sort); err != nil {
+        return err
+    }
+    if !groupings[v.GroupBy] {
+        return fmt.Errorf("unknown grouping: %s", v.GroupBy)
+    }
+
+    columns := v.Columns[:0]
+    for _, c := range v.Columns {
+        if c = strings.TrimSpace(c); c != "" {
+            columns = append(columns, c)
+        }
+    }
+    v.Columns = columns
+    return nil
+}
+
+// load returns a copy of the cached views of a user, reading them on first
+// use, so callers can modify it and hand it to store. Callers hold s.mu.
+func (s *Store) load(user string) ([]View, error) {
+    views, ok := s.views[user]
+    if !ok {
+        if err := fileutil.ReadJSON(s.path(user), &views); err != nil {
+            return nil, err
+        }
+        s.views[user] = views
+    }
+    return append([]View(nil), views...), nil
+}
+
+// store writes the views of a user. Callers hold s.mu.
+func (s *Store) store(user string, views []View) error {
+    if views == nil {
+        views = []View{}
+    }
+    if err := fileutil.WriteJSON(s.path(user), views); err != nil {
+        return err
+    }
+    s.views[user] = views
+    return nil
+}
+
+func indexByName(views []View, name string) int {
+    for i, v := range views {
+        if strings.EqualFold(v.Name, name) {
+            return i
+        }
+    }
+    return -1
+}
+
+func uniqueName(views []View, name string) string {
+    for n := 2; ; n++ {
+        candidate := fmt.Sprintf("%s (%d)", name, n)
+        if indexByName(views, candidate) < 0 {
+            return candidate
+        }
+    }
+}
+
+func newID() string {
+    b := make([]byte, 8)
+    rand.Read(b)
+    return hex.EncodeToString(b)
+}
@@ -0,0 +1,321 @@
+// internal/watch/watch.go
+package watch
+
+import (
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "path"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "rutorrent-web/internal/config"
+    "rutorrent-web/internal/magnet"
+    "rutorrent-web/internal/services"
+    "rutorrent-web/internal/torrentfile"
+)
+
+const (
+    // DefaultInterval is the time between scans when none is configured
+    DefaultInterval = 10 * time.Second
+    // maxFileSize bounds the .torrent and .magnet files read
+    maxFileSize = 32 << 20
+
+    doneDir   = "done"
+    failedDir = "failed"
+)
+
+// state is what a scan saw of a file. Files are only added once a scan
+// finds them unchanged, so nothing half-written by a slow copy over the
+// network is picked up. Polling is used instead of inotify, which doesn't
+// see changes made by other hosts on network filesystems.
+type state struct {
+    size    int64
+    modTime time.Time
+}
+
+// Watcher polls directories for new .torrent and .magnet files and adds
+// them. Added files are moved to done/ under the watched directory, files
+// that can never be added to failed/ with a .reason file next to them.
+// Files that failed for reasons that may pass, rTorrent being unreachable
+// for example, stay where they are and are tried again on the next scan.
+type Watcher struct {
+    torrents *services.TorrentService
+    dirs     []config.WatchDir
+    interval time.Duration
+    seen     map[string]state
+    retrying map[string]bool // files whose failure was already logged
+    stop     chan struct{}
+    once     sync.Once
+}
+
+// permanentError marks files that will never be added, however often
+// they are tried
+type permanentError struct {
+    err error
+}
+
+func (e *permanentError) Error() string {
+    return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+    return e.err
+}
+
+func permanent(err error) error {
+    return &permanentError{err: err}
+}
+
+// New creates a watcher for the configured directories
+func New(torrents *services.TorrentService, cfg config.WatchConfig) *Watcher {
+    interval := time.Duration(cfg.Interval) * time.Second
+    if interval <= 0 {
+        interval = DefaultInterval
+    }
+    return &Watcher{
+        torrents: torrents,
+        dirs:     cfg.Dirs,
+        interval: interval,
+        seen:     make(map[string]state),
+        retrying: make(map[string]bool),
+        stop:     make(chan struct{}),
+    }
+}
+
+// Start scans in the background until Stop is called
+func (w *Watcher) Start() {
+    go func() {
+        ticker := time.NewTicker(w.interval)
+        defer ticker.Stop()
+
+        for {
+            select {
+            case <-ticker.C:
+                w.Scan()
+            case <-w.stop:
+                return
+            }
+        }
+    }()
+}
+
+// Stop ends background scanning
+func (w *Watcher) Stop() {
+    w.once.Do(func() { close(w.stop) })
+}
+
+// Scan checks every watched directory once. It is not safe to call
+// concurrently with itself.
+func (w *Watcher) Scan() {
+    current := make(map[string]state)
+    for _, dir := range w.dirs {
+        for _, file := range w.list(dir.Path) {
+            info, err := os.Stat(file)
+            if err != nil {
+                continue
+            }
+            st := state{size: info.Size(), modTime: info.ModTime()}
+            if prev, ok := w.seen[file]; !ok || prev != st {
+                current[file] = st
+                continue
+            }
+            if !w.process(dir, file) {
+                // Failed for now, try again on the next scan
+                current[file] = st
+            }
+        }
+    }
+    for file := range w.retrying {
+        if _, ok := current[file]; !ok {
+            delete(w.retrying, file)
+        }
+    }
+    w.seen = current
+}
+
+// list returns the watched files under root, leaving out done/ and failed/
+func (w *Watcher) list(root string) []string {
+    root = filepath.Clean(root)
+    var files []string
+    err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+        if err != nil {
+            if p == root {
+                return err
+            }
+            return nil // unreadable subfolder, try again next scan
+        }
+        if d.IsDir() {
+            if p != root && filepath.Dir(p) == root && (d.Name() == doneDir || d.Name() == failedDir) {
+                return filepath.SkipDir
+            }
+            if strings.HasPrefix(d.Name(), ".") && p != root {
+                return filepath.SkipDir
+            }
+            return nil
+        }
+        if d.Type().IsRegular() && watched(d.Name()) {
+            files = append(files, p)
+        }
+        return nil
+    })
+    if err != nil {
+        services.Warnf("watch: failed to scan %s: %v", root, err)
+    }
+    sort.Strings(files)
+    return files
+}
+
+// watched reports whether a file name is a torrent or magnet file
+func watched(name string) bool {
+    ext := strings.ToLower(filepath.Ext(name))
+    return (ext == ".torrent" || ext == ".magnet") && !strings.HasPrefix(name, ".")
+}
+
+// process adds one file and moves it out of the way. It returns false
+// when the file was left in place to be tried again.
+func (w *Watcher) process(dir config.WatchDir, file string) bool {
+    rel, err := filepath.Rel(filepath.Clean(dir.Path), file)
+    if err != nil {
+        return true
+    }
+    folder := path.Dir(filepath.ToSlash(rel))
+    if folder == "." {
+        folder = ""
+    }
+
+    opts := Options(dir, folder)
+    err = w.add(file, opts)
+    var perm *permanentError
+    switch {
+    case err == nil:
+        services.Infof("watch: added %s", file)
+        w.moveTo(dir.Path, rel, doneDir, nil)
+    case errors.As(err, &perm):
+        services.Warnf("watch: failed to add %s: %v", file, err)
+        w.moveTo(dir.Path, rel, failedDir, err)
+    default:
+        if !w.retrying[file] {
+            services.Warnf("watch: failed to add %s, will retry: %v", file, err)
+            w.retrying[file] = true
+        }
+        return false
+    }
+    delete(w.retrying, file)
+    return true
+}
+
+// Options returns how a file found in folder, a slash separated path
+// relative to the watched directory, is added
+func Options(dir config.WatchDir, folder string) *services.AddTorrentOptions {
+    opts := &services.AddTorrentOptions{
+        Start:     dir.Start,
+        Label:     dir.Label,
+        Directory: dir.Directory,
+        Source:    dir.Path,
+    }
+    if folder == "" {
+        return opts
+    }
+
+    opts.Label = folder
+    if dir.AppendFolder && dir.Directory != "" {
+        opts.Directory = filepath.Join(dir.Directory, filepath.FromSlash(folder))
+    }
+    // The longest configured folder containing this one wins
+    best := -1
+    for name, target := range dir.Folders {
+        name = strings.Trim(name, "/")
+        if (folder == name || strings.HasPrefix(folder, name+"/")) && len(name) > best {
+            best = len(name)
+            opts.Directory = target
+        }
+    }
+    return opts
+}
+
+// add loads a .torrent or .magnet file through the usual add pipeline.
+// Errors that retrying can't fix are wrapped in permanentError.
+func (w *Watcher) add(file string, opts *services.AddTorrentOptions) error {
+    data, err := readFile(file)
+    if err != nil {
+        return err
+    }
+
+    if strings.EqualFold(filepath.Ext(file), ".magnet") {
+        m, err := magnet.Parse(string(data))
+        if err != nil {
+            return permanent(err)
+        }
+        return classify(w.torrents.AddMagnetLink(m, opts))
+    }
+
+    t, err := torrentfile.NewFromBytes(data)
+    if err != nil {
+        return permanent(err)
+    }
+    if _, ok := w.torrents.GetTorrent(strings.ToUpper(t.GetInfoHash())); ok {
+        return permanent(services.ErrTorrentExists)
+    }
+    return classify(w.torrents.AddTorrentData(data, opts))
+}
+
+// classify marks the add errors that won't go away as permanent
+func classify(err error) error {
+    var invalid *magnet.Error
+    if errors.Is(err, services.ErrTorrentExists) || errors.As(err, &invalid) {
+        return permanent(err)
+    }
+    return err
+}
+
+// readFile reads a watched file, refusing ones too large to be torrents
+func readFile(file string) ([]byte, error) {
+    info, err := os.Stat(file)
+    if err != nil {
+        return nil, err
+    }
+    if info.Size() > maxFileSize {
+        return nil, permanent(fmt.Errorf("file is larger than %d bytes", maxFileSize))
+    }
+    return os.ReadFile(file)
+}
+
+// moveTo moves a processed file to done/ or failed/, keeping its subfolder.
+// Failures get a .reason file with the error.
+func (w *Watcher) moveTo(root, rel, to string, reason error) {
+    dest := uniquePath(filepath.Join(root, to, rel))
+    if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+        services.Warnf("watch: failed to create %s: %v", filepath.Dir(dest), err)
+        return
+    }
+    if err := os.Rename(filepath.Join(root, rel), dest); err != nil {
+        services.Warnf("watch: failed to move %s: %v", rel, err)
+        return
+    }
+    if reason != nil {
+        text := time.Now().Format(time.RFC3339) + " " + reason.Error() + "\n"
+        if err := os.WriteFile(dest+".reason", []byte(text), 0644); err != nil {
+            services.Warnf("watch: failed to write reason for %s: %v", rel, err)
+        }
+    }
+}
+
+// uniquePath returns p, or p with a number added when it already exists
+func uniquePath(p string) string {
+    if _, err := os.Lstat(p); errors.Is(err, fs.ErrNotExist) {
+        return p
+    }
+    ext := filepath.Ext(p)
+    base := strings.TrimSuffix(p, ext)
+    for i := 1; ; i++ {
+        candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+        if _, err := os.Lstat(candidate); errors.Is(err, fs.ErrNotExist) {
+            return candidate
+        }
+    }
+}
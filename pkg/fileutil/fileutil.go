@@ -2,6 +2,7 @@
 package fileutil
 
 import (
+    "encoding/json"
     "fmt"
     "io"
     "os"
@@ -67,7 +68,7 @@ func GetProfilePath() string {
 
     mu.Lock()
     defer mu.Unlock()
-    profilePathInstance = getProfilePathEx("")
+    profilePathInstance = GetProfilePathEx("")
     return profilePathInstance
 }
 
@@ -283,4 +284,41 @@ func GetConfFile(name string) string {
         return path
     }
     return ""
-}
\ No newline at end of file
+}
+// ReadJSON decodes a JSON file into v. A missing file is not an error and
+// leaves v untouched.
+func ReadJSON(path string, v interface{}) error {
+    data, err := os.ReadFile(path)
+    if os.IsNotExist(err) {
+        return nil
+    }
+    if err != nil {
+        return fmt.Errorf("failed to read %s: %w", path, err)
+    }
+    if err := json.Unmarshal(data, v); err != nil {
+        return fmt.Errorf("failed to parse %s: %w", path, err)
+    }
+    return nil
+}
+
+// WriteJSON encodes v to a JSON file. The data is written to a temporary
+// file first and renamed into place so readers never see a partial file.
+func WriteJSON(path string, v interface{}) error {
+    data, err := json.MarshalIndent(v, "", "  ")
+    if err != nil {
+        return fmt.Errorf("failed to encode %s: %w", path, err)
+    }
+    if err := MakeDirectory(filepath.Dir(path)); err != nil {
+        return err
+    }
+
+    tmp := fmt.Sprintf("%s.%d.tmp", path, time.Now().UnixNano())
+    if err := os.WriteFile(tmp, data, 0644); err != nil {
+        return fmt.Errorf("failed to write %s: %w", path, err)
+    }
+    if err := os.Rename(tmp, path); err != nil {
+        os.Remove(tmp)
+        return fmt.Errorf("failed to write %s: %w", path, err)
+    }
+    return nil
+}
@@ -0,0 +1,19 @@
+// pkg/respond/respond.go
+package respond
+
+import (
+    "encoding/json"
+    "net/http"
+)
+
+// JSON writes v as the JSON body of a response with the given status
+func JSON(w http.ResponseWriter, status int, v interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(v)
+}
+
+// Error writes {"error": "..."} with the given status
+func Error(w http.ResponseWriter, status int, err error) {
+    JSON(w, status, map[string]string{"error": err.Error()})
+}
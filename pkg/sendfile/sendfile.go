@@ -1,4 +1,4 @@
-// util/sendfile/sendfile.go
+// pkg/sendfile/sendfile.go
 package sendfile
 
 import (
@@ -11,9 +11,8 @@ import (
     "path/filepath"
     "strconv"
     "strings"
+    "syscall"
     "time"
-
-    "your-project/util/fileutil"
 )
 
 // SendFile sends a file to the client with proper headers and range support
@@ -46,11 +45,6 @@ func SendFile(w http.ResponseWriter, filename string, opts ...Option) error {
         nameToSend = filepath.Base(filename)
     }
 
-    // Handle user agent specific filename encoding
-    if isIE := strings.Contains(strings.ToLower(options.userAgent), "msie"); isIE {
-        nameToSend = url.QueryEscape(nameToSend)
-    }
-
     // Set content type
     contentType := options.contentType
     if contentType == "" {
@@ -59,8 +53,7 @@ func SendFile(w http.ResponseWriter, filename string, opts ...Option) error {
     w.Header().Set("Content-Type", contentType)
 
     // Set disposition header
-    w.Header().Set("Content-Disposition", 
-        fmt.Sprintf(`attachment; filename="%s"`, nameToSend))
+    w.Header().Set("Content-Disposition", contentDisposition(nameToSend, options.userAgent))
 
     // Handle caching headers
     etag := fmt.Sprintf(`"%x-%x-%x"`, stat.Sys().(*syscall.Stat_t).Ino, 
@@ -86,8 +79,15 @@ func SendFile(w http.ResponseWriter, filename string, opts ...Option) error {
         }
     }
 
-    // Handle range requests
+    w.Header().Set("Accept-Ranges", "bytes")
+
+    // Handle range requests. A Range with an If-Range that no longer matches
+    // the file gets the whole file, so a resumed download can't end up with
+    // pieces of two different versions.
     rangeHeader := options.request.Header.Get("Range")
+    if rangeHeader != "" && !ifRangeMatches(options.request.Header.Get("If-Range"), etag, stat.ModTime()) {
+        rangeHeader = ""
+    }
     if rangeHeader != "" {
         ranges, err := parseRange(rangeHeader, stat.Size())
         if err != nil {
@@ -120,7 +120,6 @@ func SendFile(w http.ResponseWriter, filename string, opts ...Option) error {
 
     // Full file response
     w.Header().Set("Content-Length", strconv.FormatInt(stat.Size(), 10))
-    w.Header().Set("Accept-Ranges", "bytes")
 
     if options.request.Method != http.MethodHead {
         if _, err := io.Copy(w, file); err != nil {
@@ -131,6 +130,70 @@ func SendFile(w http.ResponseWriter, filename string, opts ...Option) error {
     return nil
 }
 
+// SendData sends generated content as a download. WithContentName is
+// required, there is no file to take the name from.
+func SendData(w http.ResponseWriter, data []byte, opts ...Option) error {
+    options := defaultOptions()
+    for _, opt := range opts {
+        opt(options)
+    }
+    if options.contentName == "" {
+        return errors.New("no content name")
+    }
+
+    contentType := options.contentType
+    if contentType == "" {
+        contentType = "application/octet-stream"
+    }
+
+    w.Header().Set("Content-Type", contentType)
+    w.Header().Set("Content-Disposition", contentDisposition(options.contentName, options.userAgent))
+    w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+    w.Header().Set("Cache-Control", "no-cache")
+    w.WriteHeader(http.StatusOK)
+
+    if options.request.Method != http.MethodHead {
+        if _, err := w.Write(data); err != nil {
+            return fmt.Errorf("failed to write data: %w", err)
+        }
+    }
+    return nil
+}
+
+// ifRangeMatches reports whether an If-Range value allows a partial
+// response. It holds either an entity tag, which must match exactly, or a
+// date, which must equal the modification time.
+func ifRangeMatches(ifRange, etag string, modTime time.Time) bool {
+    ifRange = strings.TrimSpace(ifRange)
+    switch {
+    case ifRange == "":
+        return true
+    case strings.HasPrefix(ifRange, `"`):
+        return ifRange == etag
+    case strings.HasPrefix(ifRange, "W/"):
+        // Weak tags can't be used with If-Range
+        return false
+    }
+    t, err := http.ParseTime(ifRange)
+    return err == nil && t.Unix() == modTime.Unix()
+}
+
+// StatusCode returns the HTTP status for an error returned by SendFile:
+// 416 for unsatisfiable ranges, 404 for missing files, 403 when the file
+// can't be read and 500 otherwise
+func StatusCode(err error) int {
+    var he *httpError
+    switch {
+    case errors.As(err, &he):
+        return he.StatusCode
+    case errors.Is(err, os.ErrNotExist):
+        return http.StatusNotFound
+    case errors.Is(err, os.ErrPermission):
+        return http.StatusForbidden
+    }
+    return http.StatusInternalServerError
+}
+
 // SendCachedImage sends an image with caching headers
 func SendCachedImage(w http.ResponseWriter, location string, imageType string, duration time.Duration) error {
     w.Header().Set("Content-Type", imageType)
@@ -264,4 +327,43 @@ func parseRange(s string, size int64) ([]byteRange, error) {
         return nil, fmt.Errorf("invalid range: no overlap")
     }
     return ranges, nil
-}
\ No newline at end of file
+}
+
+// contentDisposition returns an attachment header for name. Quotes,
+// backslashes and non-ASCII characters are replaced in the plain filename,
+// which old clients use, and the exact name is sent as RFC 5987 filename*.
+// Old Internet Explorer understands neither and gets the URL-escaped name.
+func contentDisposition(name, userAgent string) string {
+    if strings.Contains(strings.ToLower(userAgent), "msie") {
+        return fmt.Sprintf(`attachment; filename="%s"`, url.QueryEscape(name))
+    }
+
+    fallback := strings.Map(func(r rune) rune {
+        if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+            return '_'
+        }
+        return r
+    }, name)
+    if fallback == name {
+        return fmt.Sprintf(`attachment; filename="%s"`, name)
+    }
+
+    var encoded strings.Builder
+    for _, b := range []byte(name) {
+        if isAttrChar(b) {
+            encoded.WriteByte(b)
+        } else {
+            fmt.Fprintf(&encoded, "%%%02X", b)
+        }
+    }
+    return fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, fallback, encoded.String())
+}
+
+// isAttrChar reports whether b may appear unescaped in an RFC 5987 value
+func isAttrChar(b byte) bool {
+    switch {
+    case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9':
+        return true
+    }
+    return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
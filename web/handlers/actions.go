@@ -0,0 +1,415 @@
+// handlers/actions.go
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Object types that actions can be registered for
+const (
+	ObjectTorrent = "torrent"
+	ObjectFile    = "file"
+	ObjectPeer    = "peer"
+	ObjectTracker = "tracker"
+	ObjectLabel   = "label"
+)
+
+// ActionRequest is passed to an action executor
+type ActionRequest struct {
+	Request    *http.Request
+	ObjectType string
+	IDs        []string
+}
+
+// ActionResult is rendered by objects/result.html after an action ran
+type ActionResult struct {
+	Success bool
+	Message string
+}
+
+// ActionExecutor runs an action against one or more objects
+type ActionExecutor func(req ActionRequest) (*ActionResult, error)
+
+// PropertySource loads the properties of a single object. Conditions of
+// registered actions are evaluated against these properties.
+type PropertySource func(objID string) (map[string]interface{}, error)
+
+// BatchPropertySource loads the properties of several objects at once,
+// keyed by the IDs given. Sources whose objects share an expensive lookup
+// (the files of one torrent, for example) use it so bulk actions make one
+// lookup instead of one per object.
+type BatchPropertySource func(objIDs []string) (map[string]map[string]interface{}, error)
+
+// RegisteredAction is an action definition as kept by the registry. The
+// embedded ObjectAction describes how the action is shown in menus.
+type RegisteredAction struct {
+	ObjectAction
+
+	// Order positions the action in menus, lower values first
+	Order int
+
+	// Permission, when set, must be granted by the permission checker
+	Permission string
+
+	// Execute runs the action. Actions without an executor are menu-only
+	// entries that point at their own HxGet/HxPost endpoint.
+	Execute ActionExecutor
+}
+
+// ActionRegistry holds the actions and property sources for every object type
+type ActionRegistry struct {
+	actions    map[string][]*RegisteredAction
+	sources    map[string]PropertySource
+	batches    map[string]BatchPropertySource
+	permission func(r *http.Request, permission string) bool
+	mu         sync.RWMutex
+}
+
+// NewActionRegistry creates an empty registry that grants all permissions
+func NewActionRegistry() *ActionRegistry {
+	return &ActionRegistry{
+		actions: make(map[string][]*RegisteredAction),
+		sources: make(map[string]PropertySource),
+		batches: make(map[string]BatchPropertySource),
+	}
+}
+
+// defaultRegistry is used by the object handlers
+var defaultRegistry = NewActionRegistry()
+
+// Actions returns the registry used by the object handlers
+func Actions() *ActionRegistry {
+	return defaultRegistry
+}
+
+// Register adds an action for an object type. Registering an ID twice for
+// the same type is an error so plugins can't silently replace core actions.
+func (reg *ActionRegistry) Register(objType string, action RegisteredAction) error {
+	if action.ID == "" {
+		return fmt.Errorf("action for %s has no ID", objType)
+	}
+	for _, cond := range action.Conditions {
+		if _, err := parseCondition(cond); err != nil {
+			return fmt.Errorf("action %s/%s: %w", objType, action.ID, err)
+		}
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	for _, existing := range reg.actions[objType] {
+		if existing.ID == action.ID {
+			return fmt.Errorf("action %s/%s already registered", objType, action.ID)
+		}
+	}
+
+	a := action
+	list := append(reg.actions[objType], &a)
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Order < list[j].Order
+	})
+	reg.actions[objType] = list
+	return nil
+}
+
+// Unregister removes an action
+func (reg *ActionRegistry) Unregister(objType, id string) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	list := reg.actions[objType]
+	for i, a := range list {
+		if a.ID == id {
+			reg.actions[objType] = append(list[:i:i], list[i+1:]...)
+			return
+		}
+	}
+}
+
+// SetPropertySource registers the property loader for an object type
+func (reg *ActionRegistry) SetPropertySource(objType string, source PropertySource) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.sources[objType] = source
+	delete(reg.batches, objType)
+}
+
+// SetBatchPropertySource registers a loader of many objects' properties
+// for an object type. Single objects are loaded through it as well.
+func (reg *ActionRegistry) SetBatchPropertySource(objType string, source BatchPropertySource) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.batches[objType] = source
+	reg.sources[objType] = func(objID string) (map[string]interface{}, error) {
+		props, err := source([]string{objID})
+		if err != nil {
+			return nil, err
+		}
+		return props[objID], nil
+	}
+}
+
+// SetPermissionChecker installs the function used to check action
+// permissions. Without one every permission is granted.
+func (reg *ActionRegistry) SetPermissionChecker(check func(r *http.Request, permission string) bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.permission = check
+}
+
+// Properties loads the properties of an object
+func (reg *ActionRegistry) Properties(objType, objID string) (map[string]interface{}, error) {
+	reg.mu.RLock()
+	source := reg.sources[objType]
+	reg.mu.RUnlock()
+
+	if source == nil {
+		return map[string]interface{}{}, nil
+	}
+	return source(objID)
+}
+
+// PropertiesOf loads the properties of several objects, keyed by ID
+func (reg *ActionRegistry) PropertiesOf(objType string, objIDs []string) (map[string]map[string]interface{}, error) {
+	reg.mu.RLock()
+	batch := reg.batches[objType]
+	reg.mu.RUnlock()
+
+	if batch != nil {
+		return batch(objIDs)
+	}
+	props := make(map[string]map[string]interface{}, len(objIDs))
+	for _, objID := range objIDs {
+		p, err := reg.Properties(objType, objID)
+		if err != nil {
+			return nil, err
+		}
+		props[objID] = p
+	}
+	return props, nil
+}
+
+// Lookup returns a registered action
+func (reg *ActionRegistry) Lookup(objType, id string) *RegisteredAction {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	for _, a := range reg.actions[objType] {
+		if a.ID == id {
+			return a
+		}
+	}
+	return nil
+}
+
+// Available returns the menu entries for an object whose conditions hold
+// and whose permission the user has
+func (reg *ActionRegistry) Available(r *http.Request, objType string, props map[string]interface{}) []ObjectAction {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	var actions []ObjectAction
+	for _, a := range reg.actions[objType] {
+		if !reg.allowed(r, a) || !conditionsHold(a.Conditions, props) {
+			continue
+		}
+		actions = append(actions, a.ObjectAction)
+	}
+	return trimDividers(actions)
+}
+
+// Allowed reports whether the user may run an action
+func (reg *ActionRegistry) Allowed(r *http.Request, objType, id string) bool {
+	a := reg.Lookup(objType, id)
+	if a == nil {
+		return false
+	}
+
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	return reg.allowed(r, a)
+}
+
+func (reg *ActionRegistry) allowed(r *http.Request, a *RegisteredAction) bool {
+	if a.Permission == "" || reg.permission == nil {
+		return true
+	}
+	return reg.permission(r, a.Permission)
+}
+
+// Execute runs an action on the given objects if the user has its
+// permission. Each object's conditions are checked first so bulk requests
+// can't apply an action where it makes no sense (starting an already
+// started torrent, for example).
+func (reg *ActionRegistry) Execute(r *http.Request, objType, id string, objIDs []string) (*ActionResult, error) {
+	a := reg.Lookup(objType, id)
+	if a == nil || a.Divider {
+		return nil, RequestError{Status: http.StatusNotFound, Message: "unknown action"}
+	}
+	if a.Execute == nil {
+		return nil, RequestError{Status: http.StatusBadRequest, Message: "action has no executor"}
+	}
+	reg.mu.RLock()
+	allowed := reg.allowed(r, a)
+	reg.mu.RUnlock()
+	if !allowed {
+		return nil, RequestError{Status: http.StatusForbidden, Message: "Action not allowed"}
+	}
+	if len(objIDs) > 1 && !a.Bulk {
+		return nil, RequestError{Status: http.StatusBadRequest, Message: "action does not support multiple objects"}
+	}
+
+	ids := objIDs
+	if len(a.Conditions) > 0 {
+		props, err := reg.PropertiesOf(objType, objIDs)
+		if err != nil {
+			return nil, err
+		}
+		ids = make([]string, 0, len(objIDs))
+		for _, objID := range objIDs {
+			if conditionsHold(a.Conditions, props[objID]) {
+				ids = append(ids, objID)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return &ActionResult{Success: false, Message: "Action not applicable to the selection"}, nil
+	}
+
+	return a.Execute(ActionRequest{
+		Request:    r,
+		ObjectType: objType,
+		IDs:        ids,
+	})
+}
+
+// trimDividers drops leading, trailing and repeated dividers left over after
+// filtering
+func trimDividers(actions []ObjectAction) []ObjectAction {
+	out := make([]ObjectAction, 0, len(actions))
+	for _, a := range actions {
+		if a.Divider && (len(out) == 0 || out[len(out)-1].Divider) {
+			continue
+		}
+		out = append(out, a)
+	}
+	if len(out) > 0 && out[len(out)-1].Divider {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+// Conditions are small expressions evaluated against object properties:
+//
+//	complete        property is truthy
+//	!complete       property is falsy
+//	state=paused    string equality (state!=paused for inequality)
+//	progress<100    numeric comparison, also <=, >, >=
+//
+// An action is shown only when all of its conditions hold.
+type condition struct {
+	key    string
+	op     string
+	value  string
+	negate bool
+}
+
+var conditionOps = []string{"!=", "<=", ">=", "=", "<", ">"}
+
+func parseCondition(s string) (condition, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return condition{}, fmt.Errorf("empty condition")
+	}
+	for _, op := range conditionOps {
+		if i := strings.Index(s, op); i > 0 {
+			return condition{
+				key:   strings.TrimSpace(s[:i]),
+				op:    op,
+				value: strings.TrimSpace(s[i+len(op):]),
+			}, nil
+		}
+	}
+	if strings.HasPrefix(s, "!") {
+		return condition{key: strings.TrimSpace(s[1:]), negate: true}, nil
+	}
+	return condition{key: s}, nil
+}
+
+func conditionsHold(conds []string, props map[string]interface{}) bool {
+	for _, s := range conds {
+		c, err := parseCondition(s)
+		if err != nil || !c.holds(props) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c condition) holds(props map[string]interface{}) bool {
+	v, ok := props[c.key]
+	if c.op == "" {
+		return truthy(v, ok) != c.negate
+	}
+
+	switch c.op {
+	case "=":
+		return ok && fmt.Sprint(v) == c.value
+	case "!=":
+		return !ok || fmt.Sprint(v) != c.value
+	}
+
+	a, okA := toFloat(v)
+	b, err := strconv.ParseFloat(c.value, 64)
+	if !ok || !okA || err != nil {
+		return false
+	}
+	switch c.op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+func truthy(v interface{}, ok bool) bool {
+	if !ok || v == nil {
+		return false
+	}
+	switch t := v.(type) {
+	case bool:
+		return t
+	case string:
+		return t != ""
+	}
+	if f, ok := toFloat(v); ok {
+		return f != 0
+	}
+	return true
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case int:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case float64:
+		return t, true
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
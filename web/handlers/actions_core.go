@@ -0,0 +1,341 @@
+// handlers/actions_core.go
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"rutorrent-web/internal/services"
+)
+
+// RegisterCoreActions registers the built-in torrent, file, tracker and label
+// actions together with their property sources. Plugins register their own
+// actions on the same registry through Actions().
+func RegisterCoreActions(reg *ActionRegistry, svc *services.TorrentService) error {
+	reg.SetPropertySource(ObjectTorrent, func(hash string) (map[string]interface{}, error) {
+		t, ok := svc.GetTorrent(hash)
+		if !ok {
+			return nil, RequestError{Status: http.StatusNotFound, Message: "torrent not found"}
+		}
+		return torrentProperties(t), nil
+	})
+	// Files and trackers are loaded once per torrent however many of them
+	// a bulk action selected
+	reg.SetBatchPropertySource(ObjectFile, func(ids []string) (map[string]map[string]interface{}, error) {
+		loaded := make(map[string][]services.TorrentFile)
+		props := make(map[string]map[string]interface{}, len(ids))
+		for _, id := range ids {
+			hash, idx, err := splitSubID(id)
+			if err != nil {
+				return nil, err
+			}
+			files, ok := loaded[hash]
+			if !ok {
+				if files, err = svc.GetTorrentFiles(hash); err != nil {
+					return nil, err
+				}
+				loaded[hash] = files
+			}
+			for _, f := range files {
+				if f.Index == idx {
+					props[id] = map[string]interface{}{
+						"hash":     hash,
+						"path":     f.Path,
+						"size":     f.Size,
+						"priority": f.Priority,
+						"progress": f.Progress,
+					}
+					break
+				}
+			}
+			if props[id] == nil {
+				return nil, RequestError{Status: http.StatusNotFound, Message: "file not found"}
+			}
+		}
+		return props, nil
+	})
+	reg.SetBatchPropertySource(ObjectTracker, func(ids []string) (map[string]map[string]interface{}, error) {
+		loaded := make(map[string][]services.Tracker)
+		props := make(map[string]map[string]interface{}, len(ids))
+		for _, id := range ids {
+			hash, idx, err := splitSubID(id)
+			if err != nil {
+				return nil, err
+			}
+			trackers, ok := loaded[hash]
+			if !ok {
+				if trackers, err = svc.GetTrackers(hash); err != nil {
+					return nil, err
+				}
+				loaded[hash] = trackers
+			}
+			for _, tr := range trackers {
+				if tr.Index == idx {
+					props[id] = map[string]interface{}{
+						"hash":    hash,
+						"url":     tr.URL,
+						"type":    tr.Type,
+						"enabled": tr.Enabled,
+						"dht":     tr.Type == 3,
+					}
+					break
+				}
+			}
+			if props[id] == nil {
+				return nil, RequestError{Status: http.StatusNotFound, Message: "tracker not found"}
+			}
+		}
+		return props, nil
+	})
+	reg.SetPropertySource(ObjectLabel, func(label string) (map[string]interface{}, error) {
+		count := 0
+		for _, t := range svc.Snapshot() {
+			if t.Label == label {
+				count++
+			}
+		}
+		return map[string]interface{}{"name": label, "count": count}, nil
+	})
+
+	torrentOp := func(op func([]string) error, done string) ActionExecutor {
+		return func(req ActionRequest) (*ActionResult, error) {
+			if err := op(req.IDs); err != nil {
+				return nil, err
+			}
+			return &ActionResult{Success: true, Message: fmt.Sprintf(done, len(req.IDs))}, nil
+		}
+	}
+
+	actions := []struct {
+		objType string
+		action  RegisteredAction
+	}{
+		{ObjectTorrent, RegisteredAction{
+			ObjectAction: ObjectAction{ID: "start", Text: "Start", Icon: "play", Bulk: true, Conditions: []string{"status!=downloading", "status!=seeding"}},
+			Order:        10,
+			Execute:      torrentOp(svc.StartTorrents, "Started %d torrent(s)"),
+		}},
+		{ObjectTorrent, RegisteredAction{
+			ObjectAction: ObjectAction{ID: "pause", Text: "Pause", Icon: "pause", Bulk: true, Conditions: []string{"active"}},
+			Order:        20,
+			Execute:      torrentOp(svc.PauseTorrents, "Paused %d torrent(s)"),
+		}},
+		{ObjectTorrent, RegisteredAction{
+			ObjectAction: ObjectAction{ID: "resume", Text: "Resume", Icon: "play", Bulk: true, Conditions: []string{"status=paused"}},
+			Order:        30,
+			Execute:      torrentOp(svc.ResumeTorrents, "Resumed %d torrent(s)"),
+		}},
+		{ObjectTorrent, RegisteredAction{
+			ObjectAction: ObjectAction{ID: "stop", Text: "Stop", Icon: "stop", Bulk: true, Conditions: []string{"open"}},
+			Order:        40,
+			Execute:      torrentOp(svc.StopTorrents, "Stopped %d torrent(s)"),
+		}},
+		{ObjectTorrent, RegisteredAction{
+			ObjectAction: ObjectAction{ID: "sep-control", Divider: true, Bulk: true},
+			Order:        50,
+		}},
+		{ObjectTorrent, RegisteredAction{
+			ObjectAction: ObjectAction{ID: "recheck", Text: "Force Recheck", Icon: "refresh", Bulk: true, Conditions: []string{"!hashing"}},
+			Order:        60,
+			Execute:      torrentOp(svc.RecheckTorrents, "Rechecking %d torrent(s)"),
+		}},
+		{ObjectTorrent, RegisteredAction{
+			ObjectAction: ObjectAction{ID: "clear-label", Text: "Remove Label", Icon: "tag", Bulk: true, Conditions: []string{"label"}},
+			Order:        70,
+			Execute: torrentOp(func(hashes []string) error {
+				return svc.SetLabel(hashes, "")
+			}, "Removed label from %d torrent(s)"),
+		}},
+		{ObjectTorrent, RegisteredAction{
+			ObjectAction: ObjectAction{ID: "sep-remove", Divider: true, Bulk: true},
+			Order:        80,
+		}},
+		{ObjectTorrent, RegisteredAction{
+			ObjectAction: ObjectAction{ID: "remove", Text: "Remove", Icon: "trash", Bulk: true, Dangerous: true, HxConfirm: "Remove the selected torrent(s)?"},
+			Order:        90,
+			Permission:   "torrent.remove",
+			Execute: func(req ActionRequest) (*ActionResult, error) {
+				// Protected torrents are only removed with "force" checked
+				return removeTorrents(reg, svc, req, req.Request.FormValue("force") == "on")
+			},
+		}},
+		{ObjectTorrent, RegisteredAction{
+			ObjectAction: ObjectAction{ID: "remove-data", Text: "Remove And Delete Data", Icon: "trash", Bulk: true, Dangerous: true, HxConfirm: "Remove the selected torrent(s) and delete their data?"},
+			Order:        100,
+			Permission:   "torrent.remove_data",
+			Execute: torrentOp(func(hashes []string) error {
+				return svc.EraseTorrents(hashes, true)
+			}, "Removed %d torrent(s) and their data"),
+		}},
+
+		{ObjectTorrent, RegisteredAction{
+			ObjectAction: ObjectAction{ID: "remove-force", Text: "Force Remove", Icon: "trash", Bulk: true, Dangerous: true, HxConfirm: "Remove the selected torrent(s) even if trackers still require seeding?"},
+			Order:        110,
+			Permission:   "torrent.remove_force",
+			Execute: func(req ActionRequest) (*ActionResult, error) {
+				return removeTorrents(reg, svc, req, true)
+			},
+		}},
+
+		{ObjectFile, fileAction(svc, "priority-high", "High Priority", 10, 2)},
+		{ObjectFile, fileAction(svc, "priority-normal", "Normal Priority", 20, 1)},
+		{ObjectFile, fileAction(svc, "priority-off", "Don't Download", 30, 0)},
+
+		{ObjectTracker, trackerAction(svc, "enable", "Enable", 10, true)},
+		{ObjectTracker, trackerAction(svc, "disable", "Disable", 20, false)},
+
+		{ObjectLabel, RegisteredAction{
+			ObjectAction: ObjectAction{ID: "start", Text: "Start All", Icon: "play", Conditions: []string{"count>0"}},
+			Order:        10,
+			Execute:      labelOp(svc, svc.StartTorrents, "Started %d torrent(s)"),
+		}},
+		{ObjectLabel, RegisteredAction{
+			ObjectAction: ObjectAction{ID: "stop", Text: "Stop All", Icon: "stop", Conditions: []string{"count>0"}},
+			Order:        20,
+			Execute:      labelOp(svc, svc.StopTorrents, "Stopped %d torrent(s)"),
+		}},
+	}
+
+	for _, a := range actions {
+		if err := reg.Register(a.objType, a.action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeTorrents removes the requested torrents, deleting their data when
+// the delete_files checkbox of the remove modal is on. Deleting data needs
+// the remove-data permission and skipping the remove guard the
+// remove-force one.
+func removeTorrents(reg *ActionRegistry, svc *services.TorrentService, req ActionRequest, force bool) (*ActionResult, error) {
+	deleteData := req.Request.FormValue("delete_files") == "on"
+	if deleteData && !reg.Allowed(req.Request, ObjectTorrent, "remove-data") {
+		return nil, RequestError{Status: http.StatusForbidden, Message: "Action not allowed"}
+	}
+	erase := svc.EraseTorrents
+	if force {
+		if !reg.Allowed(req.Request, ObjectTorrent, "remove-force") {
+			return nil, RequestError{Status: http.StatusForbidden, Message: "Action not allowed"}
+		}
+		erase = svc.ForceEraseTorrents
+	}
+	if err := erase(req.IDs, deleteData); err != nil {
+		var protected *services.ProtectedError
+		if errors.As(err, &protected) {
+			return nil, RequestError{Status: http.StatusConflict, Message: err.Error()}
+		}
+		return nil, err
+	}
+	return &ActionResult{Success: true, Message: fmt.Sprintf("Removed %d torrent(s)", len(req.IDs))}, nil
+}
+
+// torrentProperties exposes the snapshot fields action conditions test
+func torrentProperties(t *services.Torrent) map[string]interface{} {
+	return map[string]interface{}{
+		"hash":     t.Hash,
+		"name":     t.Name,
+		"label":    t.Label,
+		"status":   t.Status(),
+		"open":     t.Open,
+		"active":   t.Active,
+		"complete": t.Complete,
+		"hashing":  t.Hashing,
+		"private":  t.Private,
+		"progress": t.Progress,
+		"ratio":    t.Ratio,
+		"size":     t.Size,
+		"message":  t.Message,
+	}
+}
+
+func fileAction(svc *services.TorrentService, id, text string, order, priority int) RegisteredAction {
+	return RegisteredAction{
+		ObjectAction: ObjectAction{ID: id, Text: text, Bulk: true, Conditions: []string{"priority!=" + strconv.Itoa(priority)}},
+		Order:        order,
+		Execute: func(req ActionRequest) (*ActionResult, error) {
+			byHash, err := groupSubIDs(req.IDs)
+			if err != nil {
+				return nil, err
+			}
+			for hash, indexes := range byHash {
+				if err := svc.SetFilePriorities(hash, indexes, priority); err != nil {
+					return nil, err
+				}
+			}
+			return &ActionResult{Success: true, Message: fmt.Sprintf("Updated %d file(s)", len(req.IDs))}, nil
+		},
+	}
+}
+
+func trackerAction(svc *services.TorrentService, id, text string, order int, enabled bool) RegisteredAction {
+	cond := "enabled"
+	if enabled {
+		cond = "!enabled"
+	}
+	return RegisteredAction{
+		ObjectAction: ObjectAction{ID: id, Text: text, Bulk: true, Conditions: []string{cond, "!dht"}},
+		Order:        order,
+		Execute: func(req ActionRequest) (*ActionResult, error) {
+			byHash, err := groupSubIDs(req.IDs)
+			if err != nil {
+				return nil, err
+			}
+			for hash, indexes := range byHash {
+				if err := svc.SetTrackersEnabled(hash, indexes, enabled); err != nil {
+					return nil, err
+				}
+			}
+			return &ActionResult{Success: true, Message: fmt.Sprintf("Updated %d tracker(s)", len(req.IDs))}, nil
+		},
+	}
+}
+
+// labelOp applies a torrent operation to every torrent carrying the label
+func labelOp(svc *services.TorrentService, op func([]string) error, done string) ActionExecutor {
+	return func(req ActionRequest) (*ActionResult, error) {
+		labels := make(map[string]bool, len(req.IDs))
+		for _, id := range req.IDs {
+			labels[id] = true
+		}
+		var hashes []string
+		for _, t := range svc.Snapshot() {
+			if labels[t.Label] {
+				hashes = append(hashes, t.Hash)
+			}
+		}
+		if err := op(hashes); err != nil {
+			return nil, err
+		}
+		return &ActionResult{Success: true, Message: fmt.Sprintf(done, len(hashes))}, nil
+	}
+}
+
+// splitSubID splits "HASH:index" identifiers used for files and trackers
+func splitSubID(id string) (string, int, error) {
+	i := strings.LastIndex(id, ":")
+	if i <= 0 {
+		return "", 0, RequestError{Status: http.StatusBadRequest, Message: "invalid object id: " + id}
+	}
+	idx, err := strconv.Atoi(id[i+1:])
+	if err != nil || idx < 0 {
+		return "", 0, RequestError{Status: http.StatusBadRequest, Message: "invalid object id: " + id}
+	}
+	return strings.ToUpper(id[:i]), idx, nil
+}
+
+func groupSubIDs(ids []string) (map[string][]int, error) {
+	byHash := make(map[string][]int)
+	for _, id := range ids {
+		hash, idx, err := splitSubID(id)
+		if err != nil {
+			return nil, err
+		}
+		byHash[hash] = append(byHash[hash], idx)
+	}
+	return byHash, nil
+}
@@ -2,20 +2,43 @@
 package addtorrent
 
 import (
+    "crypto/rand"
+    "encoding/hex"
     "encoding/json"
+    "errors"
     "fmt"
     "io"
+    "mime/multipart"
     "net/http"
+    "os"
     "path/filepath"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
 
-    "your-project/services"
-    "your-project/util/fileutil"
+    "github.com/go-chi/chi/v5"
+
+    "rutorrent-web/internal/magnet"
+    "rutorrent-web/internal/services"
+    "rutorrent-web/internal/torrentfile"
+    "rutorrent-web/pkg/fileutil"
+)
+
+const (
+    // previewTTL is how long an uploaded torrent waits for its confirmation
+    previewTTL = time.Hour
+    // maxPreviews bounds the torrents waiting for confirmation
+    maxPreviews = 100
+    // maxVerifyPieces bounds the pieces hashed before skipping the check
+    maxVerifyPieces = 1000
 )
 
 // Response represents the add torrent response
 type Response struct {
     Result string `json:"result"`
     Name   string `json:"name,omitempty"`
+    Error  string `json:"error,omitempty"`
 }
 
 // Handler handles torrent addition requests
@@ -23,6 +46,7 @@ type Handler struct {
     torrentService *services.TorrentService
     maxFileSize    int64
     tempDir       string
+    previews       *previewStore
 }
 
 // Config holds handler configuration
@@ -38,9 +62,22 @@ func New(config Config) *Handler {
         torrentService: config.TorrentService,
         maxFileSize:    config.MaxFileSize,
         tempDir:       config.TempDir,
+        previews:       &previewStore{items: make(map[string]*preview)},
     }
 }
 
+// Routes returns the add routes: the one-step form post and the two-step
+// preview flow
+func (h *Handler) Routes() chi.Router {
+    r := chi.NewRouter()
+
+    r.Post("/", h.ServeHTTP)
+    r.Post("/preview", h.handlePreview)
+    r.Post("/confirm", h.handleConfirm)
+
+    return r
+}
+
 // ServeHTTP handles the add torrent request
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
     var responses []Response
@@ -63,6 +100,11 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
     skipChecking := r.FormValue("skip_checking") == "on"
     label := r.FormValue("label")
     dirEdit := r.FormValue("dir_edit")
+    verify, err := parseVerify(r.FormValue("verify_pieces"))
+    if err != nil {
+        h.sendError(w, r, "Invalid piece count", err)
+        return
+    }
 
     // Process directory
     if dirEdit != "" {
@@ -77,7 +119,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
     // Handle file uploads
     if files := r.MultipartForm.File["torrent_file"]; len(files) > 0 {
-        responses = h.handleFileUploads(files, startImmediately, skipChecking, dirEdit, label)
+        responses = h.handleFileUploads(files, startImmediately, skipChecking, verify, dirEdit, label)
     }
 
     // Handle magnet URLs
@@ -94,8 +136,10 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
     h.sendResponses(w, r, responses)
 }
 
-// handleFileUploads processes uploaded torrent files
-func (h *Handler) handleFileUploads(files []*multipart.FileHeader, start, skip bool, dir, label string) []Response {
+// handleFileUploads processes uploaded torrent files. With skip set the
+// content already in the download directory is taken as complete after
+// verify random pieces check out.
+func (h *Handler) handleFileUploads(files []*multipart.FileHeader, start, skip bool, verify int, dir, label string) []Response {
     var responses []Response
 
     for _, fileHeader := range files {
@@ -140,12 +184,14 @@ func (h *Handler) handleFileUploads(files []*multipart.FileHeader, start, skip b
 
         // Add torrent
         if err := h.torrentService.AddTorrentFile(tempName, &services.AddTorrentOptions{
-            Start:     start,
-            SkipCheck: skip,
-            Directory: dir,
-            Label:     label,
+            Start:        start,
+            SkipCheck:    skip,
+            Directory:    dir,
+            Label:        label,
+            VerifyPieces: verify,
         }); err != nil {
             response.Result = "Failed"
+            response.Error = err.Error()
             responses = append(responses, response)
             continue
         }
@@ -182,13 +228,25 @@ func (h *Handler) handleMagnet(url string, start bool, dir, label string) *Respo
             return response
         }
     } else {
-        // Add magnet directly
-        if err := h.torrentService.AddMagnet(url, &services.AddTorrentOptions{
+        m, err := magnet.Parse(url)
+        if err != nil {
+            response.Result = "FailedMagnet"
+            response.Error = err.Error()
+            return response
+        }
+        if m.Name != "" {
+            response.Name = m.Name
+        }
+        if err := h.torrentService.AddMagnetLink(m, &services.AddTorrentOptions{
             Start:     start,
             Directory: dir,
             Label:     label,
         }); err != nil {
             response.Result = "Failed"
+            if errors.Is(err, services.ErrTorrentExists) {
+                response.Result = "Duplicate"
+            }
+            response.Error = err.Error()
             return response
         }
     }
@@ -197,6 +255,235 @@ func (h *Handler) handleMagnet(url string, start bool, dir, label string) *Respo
     return response
 }
 
+// PreviewResponse describes an uploaded torrent waiting for confirmation
+type PreviewResponse struct {
+    Token  string                `json:"token"`
+    Hash   string                `json:"hash"`
+    Name   string                `json:"name"`
+    Size   int64                 `json:"size"`
+    Exists bool                  `json:"exists"` // already loaded in rTorrent
+    Files  *torrentfile.FileNode `json:"files"`
+}
+
+// handlePreview is the first step of adding a torrent: the uploaded
+// "torrent_file" is parsed and kept, and its file tree returned so the
+// user can pick files before anything is loaded
+func (h *Handler) handlePreview(w http.ResponseWriter, r *http.Request) {
+    if err := r.ParseMultipartForm(h.maxFileSize); err != nil {
+        h.sendError(w, r, "Failed to parse form", err)
+        return
+    }
+    defer r.MultipartForm.RemoveAll()
+
+    file, header, err := r.FormFile("torrent_file")
+    if err != nil {
+        h.sendError(w, r, "No torrent file", err)
+        return
+    }
+    data, err := io.ReadAll(io.LimitReader(file, h.maxFileSize))
+    file.Close()
+    if err != nil {
+        h.sendError(w, r, "Failed to read torrent file", err)
+        return
+    }
+
+    t, err := torrentfile.NewFromBytes(data)
+    if err != nil {
+        h.sendError(w, r, "Invalid torrent file", err)
+        return
+    }
+
+    p := &preview{
+        name:    header.Filename,
+        data:    data,
+        hash:    strings.ToUpper(t.GetInfoHash()),
+        files:   t.FileCount(),
+        expires: time.Now().Add(previewTTL),
+    }
+    token, err := h.previews.add(p)
+    if err != nil {
+        h.sendError(w, r, "Too many pending torrents", err)
+        return
+    }
+    _, exists := h.torrentService.GetTorrent(p.hash)
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(PreviewResponse{
+        Token:  token,
+        Hash:   p.hash,
+        Name:   t.GetName(),
+        Size:   t.GetSize(),
+        Exists: exists,
+        Files:  t.FileTree(),
+    })
+}
+
+// handleConfirm is the second step: the previewed torrent is loaded with
+// the chosen files. Form values: token, files (selected indices, comma
+// separated or repeated; all files if absent), dir_edit, label, priority
+// (1-3), start_immediately, skip_checking and verify_pieces.
+func (h *Handler) handleConfirm(w http.ResponseWriter, r *http.Request) {
+    if err := r.ParseForm(); err != nil {
+        h.sendError(w, r, "Failed to parse form", err)
+        return
+    }
+
+    token := r.FormValue("token")
+    p, ok := h.previews.get(token)
+    if !ok {
+        h.sendError(w, r, "Unknown torrent", fmt.Errorf("preview expired or unknown, upload the torrent again"))
+        return
+    }
+
+    selected, err := parseIndices(r.Form["files"], p.files)
+    if err != nil {
+        h.sendError(w, r, "Invalid file selection", err)
+        return
+    }
+    if len(selected) == 0 {
+        h.sendError(w, r, "Invalid file selection", fmt.Errorf("no files selected"))
+        return
+    }
+
+    verify, err := parseVerify(r.FormValue("verify_pieces"))
+    if err != nil {
+        h.sendError(w, r, "Invalid piece count", err)
+        return
+    }
+    opts := &services.AddTorrentOptions{
+        Start:        r.FormValue("start_immediately") == "on",
+        SkipCheck:    r.FormValue("skip_checking") == "on",
+        Directory:    r.FormValue("dir_edit"),
+        Label:        r.FormValue("label"),
+        VerifyPieces: verify,
+    }
+    if v := r.FormValue("priority"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n < 1 || n > 3 {
+            h.sendError(w, r, "Invalid priority", fmt.Errorf("priority must be 1 (low), 2 (normal) or 3 (high)"))
+            return
+        }
+        opts.Priority = n
+    }
+    if opts.Directory != "" {
+        if err := h.torrentService.ValidateDirectory(opts.Directory); err != nil {
+            h.sendResponses(w, r, []Response{{Result: "FailedDirectory", Name: p.name, Error: err.Error()}})
+            return
+        }
+    }
+
+    response := Response{Name: p.name, Result: "Success"}
+    err = h.torrentService.AddTorrentSelected(p.data, p.hash, p.files, selected, opts)
+    switch {
+    case errors.Is(err, services.ErrTorrentExists):
+        response.Result = "Duplicate"
+        response.Error = err.Error()
+        h.previews.remove(token)
+    case err != nil:
+        // Kept so the user can try again
+        response.Result = "Failed"
+        response.Error = err.Error()
+    default:
+        h.previews.remove(token)
+    }
+    h.sendResponses(w, r, []Response{response})
+}
+
+// parseVerify parses the number of pieces checked before hash checking is
+// skipped
+func parseVerify(v string) (int, error) {
+    if v == "" {
+        return 0, nil
+    }
+    n, err := strconv.Atoi(v)
+    if err != nil || n < 0 || n > maxVerifyPieces {
+        return 0, fmt.Errorf("verify_pieces must be between 0 and %d", maxVerifyPieces)
+    }
+    return n, nil
+}
+
+// parseIndices parses selected file indices. Values may be repeated or
+// comma separated; no values selects every file.
+func parseIndices(values []string, files int) ([]int, error) {
+    if len(values) == 0 {
+        all := make([]int, files)
+        for i := range all {
+            all[i] = i
+        }
+        return all, nil
+    }
+
+    var indices []int
+    for _, v := range values {
+        for _, field := range strings.Split(v, ",") {
+            if field = strings.TrimSpace(field); field == "" {
+                continue
+            }
+            i, err := strconv.Atoi(field)
+            if err != nil || i < 0 || i >= files {
+                return nil, fmt.Errorf("invalid file index: %s", field)
+            }
+            indices = append(indices, i)
+        }
+    }
+    return indices, nil
+}
+
+// preview is an uploaded torrent waiting for confirmation
+type preview struct {
+    name    string
+    data    []byte
+    hash    string
+    files   int
+    expires time.Time
+}
+
+// previewStore keeps previews in memory by token
+type previewStore struct {
+    items map[string]*preview
+    mu    sync.Mutex
+}
+
+func (s *previewStore) add(p *preview) (string, error) {
+    buf := make([]byte, 16)
+    if _, err := rand.Read(buf); err != nil {
+        return "", err
+    }
+    token := hex.EncodeToString(buf)
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    now := time.Now()
+    for t, item := range s.items {
+        if now.After(item.expires) {
+            delete(s.items, t)
+        }
+    }
+    if len(s.items) >= maxPreviews {
+        return "", fmt.Errorf("at most %d torrents can wait for confirmation", maxPreviews)
+    }
+    s.items[token] = p
+    return token, nil
+}
+
+func (s *previewStore) get(token string) (*preview, bool) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    p, ok := s.items[token]
+    if !ok || time.Now().After(p.expires) {
+        return nil, false
+    }
+    return p, true
+}
+
+func (s *previewStore) remove(token string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    delete(s.items, token)
+}
+
 // Helper functions
 
 func (h *Handler) generateTempName(original string) string {
@@ -252,8 +539,11 @@ func (h *Handler) sendResponses(w http.ResponseWriter, r *http.Request, response
             name,
             "addTorrent"+resp.Result,
             map[string]string{
-                "Success": "success",
-                "Failed":  "error",
+                "Success":         "success",
+                "Failed":          "error",
+                "FailedMagnet":    "error",
+                "FailedDirectory": "error",
+                "Duplicate":       "warning",
             }[resp.Result],
         ))
     }
@@ -0,0 +1,51 @@
+// handlers/automove/automove.go
+package automove
+
+import (
+    "net/http"
+
+    "github.com/go-chi/chi/v5"
+
+    "rutorrent-web/internal/automove"
+    "rutorrent-web/pkg/respond"
+)
+
+// Handler serves the move-on-complete dry run and log
+type Handler struct {
+    mover *automove.Mover
+}
+
+// Config holds handler configuration
+type Config struct {
+    Mover *automove.Mover
+}
+
+// New creates a new automove handler
+func New(config Config) *Handler {
+    return &Handler{mover: config.Mover}
+}
+
+// Routes returns the automove routes, to be mounted under /automove
+func (h *Handler) Routes() chi.Router {
+    r := chi.NewRouter()
+
+    r.Get("/preview", h.handlePreview)
+    r.Get("/log", h.handleLog)
+
+    return r
+}
+
+// handlePreview shows where each complete torrent would be moved, with the
+// configured template or the one in "template"
+func (h *Handler) handlePreview(w http.ResponseWriter, r *http.Request) {
+    moves := h.mover.Plan(r.URL.Query().Get("template"))
+    if moves == nil {
+        moves = []automove.Move{}
+    }
+    respond.JSON(w, http.StatusOK, moves)
+}
+
+// handleLog returns the recent moves, newest first
+func (h *Handler) handleLog(w http.ResponseWriter, r *http.Request) {
+    respond.JSON(w, http.StatusOK, h.mover.Log())
+}
@@ -8,12 +8,16 @@ import (
     "path/filepath"
 
     "ruTorrent-web/services"
+    "rutorrent-web/internal/browse"
+    "rutorrent-web/internal/share"
 )
 
 // Handler holds dependencies for all handlers
 type Handler struct {
     templates     *template.Template
     torrentSvc    *services.TorrentService
+    browser       *browse.Browser
+    shares        *share.Store
     templateCache map[string]*template.Template
 }
 
@@ -21,6 +25,8 @@ type Handler struct {
 type Config struct {
     TemplatesDir  string
     RTorrentURL   string
+    Browser       *browse.Browser
+    Shares        *share.Store
 }
 
 // New creates a new handler instance
@@ -37,6 +43,8 @@ func New(cfg Config) (*Handler, error) {
     return &Handler{
         templates:  templates,
         torrentSvc: torrentSvc,
+        browser:    cfg.Browser,
+        shares:     cfg.Shares,
         templateCache: make(map[string]*template.Template),
     }, nil
 }
@@ -0,0 +1,80 @@
+// handlers/browse/browse.go
+package browse
+
+import (
+    "errors"
+    "net/http"
+    "os"
+
+    "github.com/go-chi/chi/v5"
+
+    "rutorrent-web/internal/browse"
+    "rutorrent-web/pkg/respond"
+)
+
+// Handler serves the directory browser used by the add, move and create
+// dialogs
+type Handler struct {
+    browser *browse.Browser
+}
+
+// Config holds handler configuration
+type Config struct {
+    Browser *browse.Browser
+}
+
+// New creates a new directory browser handler
+func New(config Config) *Handler {
+    return &Handler{browser: config.Browser}
+}
+
+// Routes returns the browser routes, to be mounted under /browse
+func (h *Handler) Routes() chi.Router {
+    r := chi.NewRouter()
+
+    r.Get("/", h.handleList)
+    r.Get("/roots", h.handleRoots)
+    r.Post("/mkdir", h.handleMkdir)
+
+    return r
+}
+
+// handleList lists "path", the first root when empty. "dirs=1" leaves out
+// files.
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+    q := r.URL.Query()
+    listing, err := h.browser.List(q.Get("path"), q.Get("dirs") == "1")
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    respond.JSON(w, http.StatusOK, listing)
+}
+
+// handleRoots returns the directories the browser may show
+func (h *Handler) handleRoots(w http.ResponseWriter, r *http.Request) {
+    respond.JSON(w, http.StatusOK, h.browser.Roots())
+}
+
+// handleMkdir creates "name" inside "path" and returns the new directory
+func (h *Handler) handleMkdir(w http.ResponseWriter, r *http.Request) {
+    path, err := h.browser.Mkdir(r.FormValue("path"), r.FormValue("name"))
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    respond.JSON(w, http.StatusCreated, map[string]string{"path": path})
+}
+
+// writeError maps browser errors to status codes. Paths outside the roots
+// get the same answer whether they exist or not.
+func writeError(w http.ResponseWriter, err error) {
+    status := http.StatusBadRequest
+    switch {
+    case errors.Is(err, browse.ErrOutsideRoot), errors.Is(err, browse.ErrNotWritable), errors.Is(err, os.ErrPermission):
+        status = http.StatusForbidden
+    case errors.Is(err, os.ErrNotExist):
+        status = http.StatusNotFound
+    }
+    respond.Error(w, status, err)
+}
@@ -0,0 +1,389 @@
+// handlers/bulkmagnet/bulkmagnet.go
+package bulkmagnet
+
+import (
+    "fmt"
+    "html/template"
+    "io"
+    "net"
+    "net/http"
+    "net/netip"
+    "net/url"
+    "regexp"
+    "strings"
+    "sync"
+    "syscall"
+    "time"
+
+    "github.com/go-chi/chi/v5"
+
+    "rutorrent-web/internal/magnet"
+    "rutorrent-web/internal/services"
+    "rutorrent-web/internal/torrentfile"
+    "rutorrent-web/pkg/respond"
+)
+
+const (
+    // maxLines bounds the links in one import
+    maxLines = 2000
+    // batchSize is the number of torrents sent to rTorrent per multicall
+    batchSize = 50
+    // fetchWorkers is the number of .torrent URLs downloaded at once
+    fetchWorkers = 4
+    // maxTorrentSize bounds a downloaded .torrent
+    maxTorrentSize = 16 << 20
+)
+
+// bareHash matches a v1 info hash given without a magnet, hex or base32
+var bareHash = regexp.MustCompile(`^(?i:[0-9a-f]{40}|[a-z2-7]{32})$`)
+
+// resultsTable shows the outcome of an import in the bulk add dialog
+var resultsTable = template.Must(template.New("results").Parse(`<div class="mb-2">
+    <span class="badge badge-success">{{.Success}} added</span>
+    {{if .Error}}<span class="badge badge-error">{{.Error}} failed</span>{{end}}
+</div>
+{{if .Error}}
+<table class="table table-xs">
+    <tbody>
+        {{range .Results}}{{if ne .Status "success"}}
+        <tr>
+            <td>{{.Line}}</td>
+            <td class="truncate max-w-xs" title="{{.Value}}">{{.Value}}</td>
+            <td class="text-error">{{.Error}}</td>
+        </tr>
+        {{end}}{{end}}
+    </tbody>
+</table>
+{{end}}
+`))
+
+// Result is the outcome of one import line
+type Result struct {
+    Line   int    `json:"line"`
+    Value  string `json:"value"`
+    Status string `json:"status"` // "success" or "error"
+    Error  string `json:"error,omitempty"`
+}
+
+// Response summarizes an import. Success and Error are the counters the
+// old bulk_magnet plugin returned.
+type Response struct {
+    Success int      `json:"success"`
+    Error   int      `json:"error"`
+    Results []Result `json:"results"`
+}
+
+// Handler imports and exports torrents as lists of links
+type Handler struct {
+    torrentService *services.TorrentService
+    client         *http.Client
+    maxUploadSize  int64
+}
+
+// Config holds handler configuration
+type Config struct {
+    TorrentService *services.TorrentService
+    MaxUploadSize  int64
+    FetchTimeout   time.Duration
+}
+
+// New creates a new bulk magnet handler
+func New(config Config) *Handler {
+    if config.MaxUploadSize == 0 {
+        config.MaxUploadSize = 8 << 20
+    }
+    if config.FetchTimeout == 0 {
+        config.FetchTimeout = 30 * time.Second
+    }
+    // Links come from users, so downloads must not reach the server's own
+    // network. The check runs on every connection, redirects included.
+    dialer := &net.Dialer{Timeout: 10 * time.Second, Control: publicOnly}
+    return &Handler{
+        torrentService: config.TorrentService,
+        client: &http.Client{
+            Timeout: config.FetchTimeout,
+            Transport: &http.Transport{
+                DialContext:         dialer.DialContext,
+                TLSHandshakeTimeout: 10 * time.Second,
+            },
+        },
+        maxUploadSize: config.MaxUploadSize,
+    }
+}
+
+// Routes returns the bulk routes, to be mounted under /bulk
+func (h *Handler) Routes() chi.Router {
+    r := chi.NewRouter()
+
+    r.Post("/add", h.handleAdd)
+    r.Get("/export", h.handleExport)
+    r.Post("/export", h.handleExport)
+
+    return r
+}
+
+// item is an import line on its way to rTorrent
+type item struct {
+    result *Result
+    source services.AddSource
+}
+
+// handleAdd imports the links of the "list" textarea and the uploaded
+// "file", one per line: magnet URIs, bare info hashes and http(s) URLs of
+// .torrent files. Blank lines and lines starting with # are skipped.
+// Options: start, directory, label.
+func (h *Handler) handleAdd(w http.ResponseWriter, r *http.Request) {
+    if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+        if err := r.ParseMultipartForm(h.maxUploadSize); err != nil {
+            respond.Error(w, http.StatusBadRequest, err)
+            return
+        }
+        defer r.MultipartForm.RemoveAll()
+    }
+
+    text := r.FormValue("list")
+    if file, _, err := r.FormFile("file"); err == nil {
+        data, err := io.ReadAll(io.LimitReader(file, h.maxUploadSize))
+        file.Close()
+        if err != nil {
+            respond.Error(w, http.StatusBadRequest, err)
+            return
+        }
+        text += "\n" + string(data)
+    }
+
+    opts := &services.AddTorrentOptions{
+        Start:     parseBool(r.FormValue("start")),
+        Directory: r.FormValue("directory"),
+        Label:     r.FormValue("label"),
+    }
+    if opts.Directory != "" {
+        if err := h.torrentService.ValidateDirectory(opts.Directory); err != nil {
+            respond.Error(w, http.StatusBadRequest, err)
+            return
+        }
+    }
+
+    var results []Result
+    for i, line := range strings.Split(text, "\n") {
+        line = strings.TrimSpace(strings.TrimPrefix(line, "\ufeff"))
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        results = append(results, Result{Line: i + 1, Value: line})
+    }
+    if len(results) == 0 {
+        respond.Error(w, http.StatusBadRequest, fmt.Errorf("no links given"))
+        return
+    }
+    if len(results) > maxLines {
+        respond.Error(w, http.StatusBadRequest, fmt.Errorf("at most %d links at once", maxLines))
+        return
+    }
+
+    // Batches are prepared one at a time so at most batchSize downloaded
+    // torrents are held in memory
+    for start := 0; start < len(results); start += batchSize {
+        batch := h.prepare(results[start:min(start+batchSize, len(results))])
+        if len(batch) == 0 {
+            continue
+        }
+        sources := make([]services.AddSource, len(batch))
+        for i, it := range batch {
+            sources[i] = it.source
+        }
+        errs, err := h.torrentService.AddTorrents(sources, opts)
+        for i, it := range batch {
+            switch {
+            case err != nil:
+                it.result.Error = err.Error()
+            case errs[i] != nil:
+                it.result.Error = errs[i].Error()
+            default:
+                it.result.Status = "success"
+            }
+        }
+    }
+
+    var resp Response
+    for i := range results {
+        if results[i].Status == "success" {
+            resp.Success++
+        } else {
+            results[i].Status = "error"
+            resp.Error++
+        }
+    }
+    resp.Results = results
+    if r.Header.Get("HX-Request") == "true" {
+        w.Header().Set("Content-Type", "text/html; charset=utf-8")
+        resultsTable.Execute(w, resp)
+        return
+    }
+    respond.JSON(w, http.StatusOK, resp)
+}
+
+// prepare turns lines into sources. Magnets and hashes are parsed and
+// checked here; .torrent URLs are downloaded here so a dead link is reported on
+// its line instead of failing silently inside rTorrent. Lines that can't be
+// used get their error set and are left out.
+func (h *Handler) prepare(results []Result) []item {
+    items := make([]*item, len(results))
+    var fetch []int
+    for i := range results {
+        r := &results[i]
+        uri := r.Value
+        if bareHash.MatchString(uri) {
+            uri = "magnet:?xt=urn:btih:" + uri
+        }
+        switch {
+        case strings.HasPrefix(strings.ToLower(uri), "magnet:"):
+            m, err := magnet.Parse(uri)
+            if err != nil {
+                r.Error = err.Error()
+                continue
+            }
+            items[i] = &item{result: r, source: services.AddSource{Magnet: m}}
+        case isHTTP(uri):
+            fetch = append(fetch, i)
+        default:
+            r.Error = "not a magnet link, info hash or http(s) URL"
+        }
+    }
+
+    jobs := make(chan int)
+    var wg sync.WaitGroup
+    for n := 0; n < fetchWorkers && n < len(fetch); n++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := range jobs {
+                data, err := h.fetch(results[i].Value)
+                if err != nil {
+                    results[i].Error = err.Error()
+                    continue
+                }
+                items[i] = &item{result: &results[i], source: services.AddSource{Data: data}}
+            }
+        }()
+    }
+    for _, i := range fetch {
+        jobs <- i
+    }
+    close(jobs)
+    wg.Wait()
+
+    ready := make([]item, 0, len(items))
+    for _, it := range items {
+        if it != nil {
+            ready = append(ready, *it)
+        }
+    }
+    return ready
+}
+
+// fetch downloads a .torrent and checks that it is one
+func (h *Handler) fetch(link string) ([]byte, error) {
+    resp, err := h.client.Get(link)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        return nil, fmt.Errorf("download failed: %s", resp.Status)
+    }
+
+    data, err := io.ReadAll(io.LimitReader(resp.Body, maxTorrentSize+1))
+    if err != nil {
+        return nil, err
+    }
+    if len(data) > maxTorrentSize {
+        return nil, fmt.Errorf("torrent file larger than %d MB", maxTorrentSize>>20)
+    }
+    if _, err := torrentfile.InfoHash(data); err != nil {
+        return nil, err
+    }
+    return data, nil
+}
+
+// handleExport lists the torrents of the space separated "hash" parameter,
+// one per line in the order given. "type" selects magnet (default), hash or
+// name; with download=1 the list is sent as a file.
+func (h *Handler) handleExport(w http.ResponseWriter, r *http.Request) {
+    kind := r.FormValue("type")
+    if kind == "" {
+        kind = "magnet"
+    }
+    if kind != "magnet" && kind != "hash" && kind != "name" {
+        respond.Error(w, http.StatusBadRequest, fmt.Errorf("unknown export type: %s", kind))
+        return
+    }
+
+    var lines []string
+    for _, hash := range strings.Fields(r.FormValue("hash")) {
+        t, ok := h.torrentService.GetTorrent(hash)
+        if !ok {
+            continue
+        }
+        switch kind {
+        case "magnet":
+            lines = append(lines, magnetLink(t))
+        case "hash":
+            lines = append(lines, t.Hash)
+        case "name":
+            lines = append(lines, t.Name)
+        }
+    }
+    if len(lines) == 0 {
+        respond.Error(w, http.StatusNotFound, services.ErrTorrentNotFound)
+        return
+    }
+
+    w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+    if parseBool(r.FormValue("download")) {
+        w.Header().Set("Content-Disposition", `attachment; filename="`+kind+`s.txt"`)
+    }
+    io.WriteString(w, strings.Join(lines, "\n")+"\n")
+}
+
+// magnetLink builds a magnet URI with the torrent's name and trackers
+func magnetLink(t *services.Torrent) string {
+    m := magnet.Magnet{InfoHash: t.Hash, Name: t.Name, Trackers: t.Trackers}
+    return m.String()
+}
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which
+// netip doesn't count as private
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicOnly is a dialer control refusing loopback, private, link-local,
+// multicast and unspecified addresses. It sees the resolved address, so
+// host names pointing inside the network are refused too.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+    host, _, err := net.SplitHostPort(address)
+    if err != nil {
+        return err
+    }
+    ip, err := netip.ParseAddr(host)
+    if err != nil {
+        return err
+    }
+    ip = ip.Unmap()
+    if !ip.IsGlobalUnicast() || ip.IsPrivate() || sharedAddressSpace.Contains(ip) {
+        return fmt.Errorf("refusing to download from non-public address %s", ip)
+    }
+    return nil
+}
+
+func isHTTP(s string) bool {
+    u, err := url.Parse(s)
+    return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func parseBool(s string) bool {
+    switch strings.ToLower(s) {
+    case "1", "true", "on", "yes":
+        return true
+    }
+    return false
+}
@@ -0,0 +1,165 @@
+// handlers/data/data.go
+package data
+
+import (
+    "errors"
+    "mime"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+
+    "github.com/go-chi/chi/v5"
+
+    "rutorrent-web/internal/services"
+    "rutorrent-web/pkg/sendfile"
+)
+
+// Handler serves the data files of torrents
+type Handler struct {
+    torrentService *services.TorrentService
+}
+
+// Config holds handler configuration
+type Config struct {
+    TorrentService *services.TorrentService
+}
+
+// New creates a new data file handler
+func New(config Config) *Handler {
+    return &Handler{torrentService: config.TorrentService}
+}
+
+// Routes returns the data routes, to be mounted under /data
+func (h *Handler) Routes() chi.Router {
+    r := chi.NewRouter()
+
+    r.Get("/{hash}/archive", h.handleArchive)
+    r.Head("/{hash}/archive", h.handleArchive)
+    r.Get("/{hash}/{index}", h.handleFile)
+    r.Head("/{hash}/{index}", h.handleFile)
+
+    return r
+}
+
+// handleFile sends file number "index" of a torrent
+func (h *Handler) handleFile(w http.ResponseWriter, r *http.Request) {
+    index, err := strconv.Atoi(chi.URLParam(r, "index"))
+    if err != nil {
+        http.Error(w, "invalid file index", http.StatusBadRequest)
+        return
+    }
+    SendFile(w, r, h.torrentService, chi.URLParam(r, "hash"), index)
+}
+
+// handleArchive streams the completed files of a torrent as an archive.
+// "format" is zip (the default, stored) or tar; "files" is an optional comma
+// separated list of file indexes, without it every completed file is sent.
+func (h *Handler) handleArchive(w http.ResponseWriter, r *http.Request) {
+    q := r.URL.Query()
+    format, ok := ParseFormat(q.Get("format"))
+    if !ok {
+        http.Error(w, "unknown archive format", http.StatusBadRequest)
+        return
+    }
+
+    var indexes []int
+    for _, part := range strings.Split(q.Get("files"), ",") {
+        if part = strings.TrimSpace(part); part == "" {
+            continue
+        }
+        index, err := strconv.Atoi(part)
+        if err != nil {
+            http.Error(w, "invalid file index", http.StatusBadRequest)
+            return
+        }
+        indexes = append(indexes, index)
+    }
+    SendArchive(w, r, h.torrentService, chi.URLParam(r, "hash"), indexes, format)
+}
+
+// ParseFormat parses an archive format name, zip when empty
+func ParseFormat(name string) (sendfile.ArchiveFormat, bool) {
+    switch format := sendfile.ArchiveFormat(name); format {
+    case "":
+        return sendfile.FormatZip, true
+    case sendfile.FormatZip, sendfile.FormatTar:
+        return format, true
+    }
+    return "", false
+}
+
+// SendFile sends file number index of a torrent. Range and If-Range are
+// honoured so downloads can be resumed.
+func SendFile(w http.ResponseWriter, r *http.Request, svc *services.TorrentService, hash string, index int) {
+    path, _, err := svc.DataFile(hash, index)
+    if err != nil {
+        http.Error(w, err.Error(), statusCode(err))
+        return
+    }
+
+    opts := []sendfile.Option{sendfile.WithRequest(r)}
+    if ct := mime.TypeByExtension(filepath.Ext(path)); ct != "" {
+        opts = append(opts, sendfile.WithContentType(ct))
+    }
+    if err := sendfile.SendFile(w, path, opts...); err != nil {
+        // Once Content-Length is set the response has started and a failed
+        // copy can only be cut short
+        if w.Header().Get("Content-Length") == "" {
+            w.Header().Del("Content-Disposition")
+            http.Error(w, err.Error(), sendfile.StatusCode(err))
+        }
+    }
+}
+
+// SendArchive streams completed files of a torrent, all of them when
+// indexes is empty. Files of multi-file torrents are placed in a folder
+// named after the torrent.
+func SendArchive(w http.ResponseWriter, r *http.Request, svc *services.TorrentService, hash string, indexes []int, format sendfile.ArchiveFormat) {
+    t, files, err := svc.DataFiles(hash, indexes)
+    if err != nil {
+        http.Error(w, err.Error(), statusCode(err))
+        return
+    }
+
+    entries := make([]sendfile.ArchiveEntry, 0, len(files))
+    for _, f := range files {
+        info, err := os.Stat(f.Path)
+        if err != nil {
+            http.Error(w, err.Error(), statusCode(err))
+            return
+        }
+        name := filepath.ToSlash(f.File.Path)
+        if t.MultiFile {
+            name = t.Name + "/" + name
+        }
+        entries = append(entries, sendfile.ArchiveEntry{
+            Name:    name,
+            Path:    f.Path,
+            Size:    info.Size(),
+            ModTime: info.ModTime(),
+        })
+    }
+
+    if err := sendfile.SendArchive(w, t.Name, format, entries, sendfile.WithRequest(r)); err != nil {
+        if w.Header().Get("Content-Length") == "" {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        services.Warnf("archive of %s failed: %v", t.Hash, err)
+    }
+}
+
+func statusCode(err error) int {
+    switch {
+    case errors.Is(err, services.ErrTorrentNotFound), errors.Is(err, services.ErrNoSuchFile),
+        errors.Is(err, os.ErrNotExist):
+        return http.StatusNotFound
+    case errors.Is(err, services.ErrOutsideTorrent), errors.Is(err, os.ErrPermission):
+        return http.StatusForbidden
+    case errors.Is(err, services.ErrFileIncomplete):
+        return http.StatusConflict
+    }
+    return http.StatusInternalServerError
+}
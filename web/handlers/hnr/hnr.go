@@ -0,0 +1,93 @@
+// handlers/hnr/hnr.go
+package hnr
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "sort"
+    "time"
+
+    "github.com/go-chi/chi/v5"
+
+    "rutorrent-web/internal/hnr"
+    "rutorrent-web/internal/services"
+    "rutorrent-web/pkg/respond"
+)
+
+// Handler serves the hit-and-run policies
+type Handler struct {
+    torrentService *services.TorrentService
+    store          *hnr.Store
+}
+
+// Config holds handler configuration
+type Config struct {
+    TorrentService *services.TorrentService
+    Store          *hnr.Store
+}
+
+// New creates a new hit-and-run handler
+func New(config Config) *Handler {
+    return &Handler{
+        torrentService: config.TorrentService,
+        store:          config.Store,
+    }
+}
+
+// Routes returns the policy routes, to be mounted under /hnr
+func (h *Handler) Routes() chi.Router {
+    r := chi.NewRouter()
+
+    r.Get("/", h.handlePolicies)
+    r.Put("/", h.handleSetPolicies)
+    r.Get("/protected", h.handleProtected)
+
+    return r
+}
+
+// handlePolicies returns the tracker policies
+func (h *Handler) handlePolicies(w http.ResponseWriter, r *http.Request) {
+    respond.JSON(w, http.StatusOK, h.store.Policies())
+}
+
+// handleSetPolicies replaces the policies with the JSON array in the body
+func (h *Handler) handleSetPolicies(w http.ResponseWriter, r *http.Request) {
+    var policies []hnr.Policy
+    if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&policies); err != nil {
+        respond.Error(w, http.StatusBadRequest, fmt.Errorf("invalid policies: %w", err))
+        return
+    }
+    if err := h.store.SetPolicies(policies); err != nil {
+        respond.Error(w, http.StatusBadRequest, err)
+        return
+    }
+    respond.JSON(w, http.StatusOK, h.store.Policies())
+}
+
+// protectedTorrent is a protected torrent with what it still owes
+type protectedTorrent struct {
+    Hash       string         `json:"hash"`
+    Name       string         `json:"name"`
+    Obligation hnr.Obligation `json:"obligation"`
+    Remaining  string         `json:"remaining"`
+}
+
+// handleProtected lists the protected torrents, least remaining seed time
+// first
+func (h *Handler) handleProtected(w http.ResponseWriter, r *http.Request) {
+    now := time.Now()
+    list := []protectedTorrent{}
+    for _, t := range h.torrentService.Snapshot() {
+        if o := h.store.Obligation(t, now); o.Protected {
+            list = append(list, protectedTorrent{Hash: t.Hash, Name: t.Name, Obligation: o, Remaining: o.String()})
+        }
+    }
+    sort.Slice(list, func(i, j int) bool {
+        if list[i].Obligation.SeedTime != list[j].Obligation.SeedTime {
+            return list[i].Obligation.SeedTime < list[j].Obligation.SeedTime
+        }
+        return list[i].Hash < list[j].Hash
+    })
+    respond.JSON(w, http.StatusOK, list)
+}
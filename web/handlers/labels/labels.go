@@ -0,0 +1,123 @@
+// handlers/labels/labels.go
+package labels
+
+import (
+    "net/http"
+
+    "github.com/go-chi/chi/v5"
+
+    "rutorrent-web/internal/labels"
+    "rutorrent-web/internal/services"
+    "rutorrent-web/internal/services/user"
+    "rutorrent-web/pkg/respond"
+)
+
+// Handler serves the label management API
+type Handler struct {
+    torrentService *services.TorrentService
+    store          *labels.Store
+}
+
+// Config holds handler configuration
+type Config struct {
+    TorrentService *services.TorrentService
+    Store          *labels.Store
+}
+
+// New creates a new label handler
+func New(config Config) *Handler {
+    return &Handler{
+        torrentService: config.TorrentService,
+        store:          config.Store,
+    }
+}
+
+// Routes returns the label routes, to be mounted under /labels. Labels are
+// passed as form values since nested labels contain slashes.
+func (h *Handler) Routes() chi.Router {
+    r := chi.NewRouter()
+
+    r.Get("/", h.handleTree)
+    r.Post("/rename", h.handleRename)
+    r.Post("/merge", h.handleMerge)
+    r.Post("/delete", h.handleDelete)
+    r.Post("/style", h.handleStyle)
+
+    return r
+}
+
+// handleTree returns the label hierarchy with counts, sizes and speeds
+func (h *Handler) handleTree(w http.ResponseWriter, r *http.Request) {
+    styles, err := h.store.Styles(user.FromContext(r.Context()))
+    if err != nil {
+        respond.Error(w, http.StatusInternalServerError, err)
+        return
+    }
+    respond.JSON(w, http.StatusOK, labels.Tree(h.torrentService.Snapshot(), styles))
+}
+
+// handleRename renames "from" to "to", nested labels included
+func (h *Handler) handleRename(w http.ResponseWriter, r *http.Request) {
+    from, to := r.FormValue("from"), r.FormValue("to")
+    changed, err := h.torrentService.RenameLabel(from, to)
+    if err != nil {
+        respond.Error(w, http.StatusBadRequest, err)
+        return
+    }
+    if err := h.store.Move(user.FromContext(r.Context()), from, to); err != nil {
+        respond.Error(w, http.StatusInternalServerError, err)
+        return
+    }
+    respond.JSON(w, http.StatusOK, map[string]int{"changed": changed})
+}
+
+// handleMerge moves every "source" label into "target"
+func (h *Handler) handleMerge(w http.ResponseWriter, r *http.Request) {
+    if err := r.ParseForm(); err != nil {
+        respond.Error(w, http.StatusBadRequest, err)
+        return
+    }
+    sources, target := r.Form["source"], r.FormValue("target")
+
+    changed, err := h.torrentService.MergeLabels(sources, target)
+    if err != nil {
+        respond.Error(w, http.StatusBadRequest, err)
+        return
+    }
+    login := user.FromContext(r.Context())
+    for _, src := range sources {
+        if err := h.store.Move(login, src, target); err != nil {
+            respond.Error(w, http.StatusInternalServerError, err)
+            return
+        }
+    }
+    respond.JSON(w, http.StatusOK, map[string]int{"changed": changed})
+}
+
+// handleDelete removes "label" and its nested labels from all torrents
+func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request) {
+    label := r.FormValue("label")
+    changed, err := h.torrentService.DeleteLabel(label)
+    if err != nil {
+        respond.Error(w, http.StatusBadRequest, err)
+        return
+    }
+    if err := h.store.Forget(user.FromContext(r.Context()), label); err != nil {
+        respond.Error(w, http.StatusInternalServerError, err)
+        return
+    }
+    respond.JSON(w, http.StatusOK, map[string]int{"changed": changed})
+}
+
+// handleStyle sets the color and icon of "label"
+func (h *Handler) handleStyle(w http.ResponseWriter, r *http.Request) {
+    style := labels.Style{
+        Color: r.FormValue("color"),
+        Icon:  r.FormValue("icon"),
+    }
+    if err := h.store.SetStyle(user.FromContext(r.Context()), r.FormValue("label"), style); err != nil {
+        respond.Error(w, http.StatusBadRequest, err)
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
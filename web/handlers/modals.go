@@ -3,8 +3,10 @@ package handlers
 
 import (
    "net/http"
-   "path/filepath"
    "github.com/go-chi/chi/v5"
+
+   "rutorrent-web/internal/services/user"
+   "rutorrent-web/internal/share"
 )
 
 // Modal handler routes
@@ -12,8 +14,11 @@ func (h *Handler) ModalRoutes() chi.Router {
    r := chi.NewRouter()
    
    r.Get("/add-torrent", h.handleAddTorrentModal)
+   r.Get("/bulk-add", h.handleBulkAddModal)
    r.Get("/torrent-details/{hash}", h.handleTorrentDetailsModal)
    r.Get("/settings", h.handleSettingsModal)
+   r.Get("/file-browser", h.handleFileBrowserModal)
+   r.Get("/share-links", h.handleShareLinksModal)
    r.Get("/close-modal", h.handleCloseModal)
    
    return r
@@ -43,6 +48,21 @@ func (h *Handler) handleAddTorrentModal(w http.ResponseWriter, r *http.Request)
    h.renderPartial(w, "modals/add-torrent.html", data)
 }
 
+// Handle bulk add modal display
+func (h *Handler) handleBulkAddModal(w http.ResponseWriter, r *http.Request) {
+   labels, err := h.torrentSvc.GetLabels()
+   if err != nil {
+       labels = []string{}
+   }
+
+   data := map[string]interface{}{
+       "Labels":     labels,
+       "DefaultDir": h.torrentSvc.GetDefaultDirectory(),
+   }
+
+   h.renderPartial(w, "modals/bulk-add.html", data)
+}
+
 // Handle torrent details modal
 func (h *Handler) handleTorrentDetailsModal(w http.ResponseWriter, r *http.Request) {
    hash := chi.URLParam(r, "hash")
@@ -133,36 +153,44 @@ func (h *Handler) handleCloseModal(w http.ResponseWriter, r *http.Request) {
    w.WriteHeader(http.StatusOK)
 }
 
-// Handle file browser modal
+// Handle file browser modal. Only directories inside the browser roots can
+// be shown; an empty path opens the first root.
 func (h *Handler) handleFileBrowserModal(w http.ResponseWriter, r *http.Request) {
-   path := r.URL.Query().Get("path")
-   if path == "" {
-       path = h.torrentSvc.GetDefaultDirectory()
-   }
-
-   // Get directory contents
-   entries, err := h.torrentSvc.ListDirectory(path)
+   listing, err := h.browser.List(r.URL.Query().Get("path"), r.URL.Query().Get("files") != "1")
    if err != nil {
        h.handleError(w, err)
        return
    }
 
    data := map[string]interface{}{
-       "CurrentPath": path,
-       "ParentPath":  filepath.Dir(path),
-       "Entries":     entries,
+       "CurrentPath": listing.Path,
+       "ParentPath":  listing.Parent,
+       "Roots":       h.browser.Roots(),
+       "Writable":    listing.Writable,
+       "Entries":     listing.Entries,
    }
 
    h.renderPartial(w, "modals/file-browser.html", data)
 }
 
-// Helper struct for directory entries
-type DirectoryEntry struct {
-   Name      string
-   Path      string
-   IsDir     bool
-   Size      int64
-   Modified  time.Time
+// Handle share links modal, listing the user's links with their download
+// counters
+func (h *Handler) handleShareLinksModal(w http.ResponseWriter, r *http.Request) {
+   type link struct {
+       share.Link
+       URL string
+   }
+
+   var links []link
+   for _, l := range h.shares.List(user.FromContext(r.Context())) {
+       links = append(links, link{Link: l, URL: h.shares.URL(&l)})
+   }
+
+   data := map[string]interface{}{
+       "Links": links,
+   }
+
+   h.renderPartial(w, "modals/share-links.html", data)
 }
 
 // Helper struct for settings
@@ -3,9 +3,12 @@
 package handlers
 
 import (
-	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+
+	"github.com/go-chi/chi/v5"
 )
 
 // ObjectAction represents an available action for an object
@@ -21,6 +24,7 @@ type ObjectAction struct {
 	HxTarget    string   `json:"hx-target,omitempty"`
 	HxSwap      string   `json:"hx-swap,omitempty"`
 	HxConfirm   string   `json:"hx-confirm,omitempty"`
+	Bulk        bool     `json:"bulk,omitempty"`
 }
 
 // ObjectContext holds the context for object actions
@@ -47,13 +51,19 @@ func HandleObjectMenu(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Build available actions
-	actions := getAvailableActions(objType, properties)
+	actions := getAvailableActions(r, objType, properties)
 
 	// If multiple objects selected, filter to bulk actions
-	selectedIDs := strings.Split(selected, ",")
+	selectedIDs := splitIDs(selected)
+	if len(selectedIDs) == 0 {
+		selectedIDs = []string{objID}
+	}
 	if len(selectedIDs) > 1 {
 		actions = filterBulkActions(actions)
 	}
+	for i := range actions {
+		actions[i] = withActionEndpoint(actions[i], objType, selectedIDs)
+	}
 
 	context := ObjectContext{
 		ObjectType: objType,
@@ -74,18 +84,37 @@ func HandleObjectMenu(w http.ResponseWriter, r *http.Request) {
 func HandleObjectAction(w http.ResponseWriter, r *http.Request) {
 	action := r.URL.Query().Get("action")
 	objType := r.URL.Query().Get("type")
-	objIDs := strings.Split(r.URL.Query().Get("ids"), ",")
+	objIDs := splitIDs(r.URL.Query().Get("ids"))
+
+	// Modals post to /objects/{type}/{action}?id=...
+	if action == "" {
+		action = chi.URLParam(r, "action")
+	}
+	if objType == "" {
+		objType = chi.URLParam(r, "type")
+	}
+	if len(objIDs) == 0 {
+		objIDs = splitIDs(r.URL.Query().Get("id"))
+	}
+	if len(objIDs) == 0 {
+		http.Error(w, "No objects selected", http.StatusBadRequest)
+		return
+	}
 
 	// Validate action is allowed
-	if !isActionAllowed(action, objType) {
+	if !isActionAllowed(r, action, objType) {
 		http.Error(w, "Action not allowed", http.StatusForbidden)
 		return
 	}
 
 	// Execute action
-	result, err := executeAction(action, objType, objIDs)
+	result, err := executeAction(r, action, objType, objIDs)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		status := http.StatusInternalServerError
+		if reqErr, ok := err.(RequestError); ok {
+			status = reqErr.Status
+		}
+		http.Error(w, err.Error(), status)
 		return
 	}
 
@@ -131,32 +160,53 @@ func HandleObjectModal(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Helper functions below would integrate with your actual object/data layer
+// Helper functions below delegate to the action registry, see actions.go
 func getObjectProperties(objType, objID string) (map[string]interface{}, error) {
-	// Implementation depends on your data layer
-	return nil, nil
+	return defaultRegistry.Properties(objType, objID)
 }
 
-func getAvailableActions(objType string, properties map[string]interface{}) []ObjectAction {
-	// Implementation depends on your business logic
-	return nil
+func getAvailableActions(r *http.Request, objType string, properties map[string]interface{}) []ObjectAction {
+	return defaultRegistry.Available(r, objType, properties)
 }
 
 func filterBulkActions(actions []ObjectAction) []ObjectAction {
 	var bulkActions []ObjectAction
 	for _, action := range actions {
-		// Add logic to determine if action supports bulk operations
-		bulkActions = append(bulkActions, action)
+		if action.Bulk || action.Divider {
+			bulkActions = append(bulkActions, action)
+		}
 	}
-	return bulkActions
+	return trimDividers(bulkActions)
+}
+
+func isActionAllowed(r *http.Request, action, objType string) bool {
+	return defaultRegistry.Allowed(r, objType, action)
+}
+
+func executeAction(r *http.Request, action, objType string, objIDs []string) (*ActionResult, error) {
+	return defaultRegistry.Execute(r, objType, action, objIDs)
 }
 
-func isActionAllowed(action, objType string) bool {
-	// Implementation depends on your permissions system
-	return true
+// withActionEndpoint points actions that run through the registry at
+// HandleObjectAction unless they bring their own endpoint
+func withActionEndpoint(action ObjectAction, objType string, objIDs []string) ObjectAction {
+	if action.Divider || action.HxGet != "" || action.HxPost != "" {
+		return action
+	}
+	q := url.Values{}
+	q.Set("type", objType)
+	q.Set("action", action.ID)
+	q.Set("ids", strings.Join(objIDs, ","))
+	action.HxPost = "/objects/action?" + q.Encode()
+	return action
 }
 
-func executeAction(action, objType string, objIDs []string) (interface{}, error) {
-	// Implementation depends on your business logic
-	return nil, nil
-}
\ No newline at end of file
+func splitIDs(s string) []string {
+	var ids []string
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
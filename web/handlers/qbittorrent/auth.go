@@ -0,0 +1,142 @@
+// handlers/qbittorrent/auth.go
+package qbittorrent
+
+import (
+    "crypto/rand"
+    "crypto/subtle"
+    "encoding/hex"
+    "net/http"
+    "sync"
+    "time"
+)
+
+const sessionCookie = "SID"
+
+// sessionStore keeps track of logged in API clients
+type sessionStore struct {
+    sessions map[string]time.Time // SID -> expiry
+    timeout  time.Duration
+    mu       sync.Mutex
+}
+
+func newSessionStore(timeout time.Duration) *sessionStore {
+    return &sessionStore{
+        sessions: make(map[string]time.Time),
+        timeout:  timeout,
+    }
+}
+
+// create starts a new session and returns its ID
+func (s *sessionStore) create() (string, error) {
+    buf := make([]byte, 16)
+    if _, err := rand.Read(buf); err != nil {
+        return "", err
+    }
+    sid := hex.EncodeToString(buf)
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    now := time.Now()
+    for id, expiry := range s.sessions {
+        if now.After(expiry) {
+            delete(s.sessions, id)
+        }
+    }
+    s.sessions[sid] = now.Add(s.timeout)
+    return sid, nil
+}
+
+// touch validates a session and extends its lifetime
+func (s *sessionStore) touch(sid string) bool {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    expiry, ok := s.sessions[sid]
+    if !ok {
+        return false
+    }
+    if time.Now().After(expiry) {
+        delete(s.sessions, sid)
+        return false
+    }
+    s.sessions[sid] = time.Now().Add(s.timeout)
+    return true
+}
+
+func (s *sessionStore) remove(sid string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    delete(s.sessions, sid)
+}
+
+// handleLogin implements /auth/login. Like qBittorrent it always answers
+// 200 and reports the result in the body.
+func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
+    if err := r.ParseForm(); err != nil {
+        writeText(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    if h.authEnabled && !h.checkCredentials(r.PostForm.Get("username"), r.PostForm.Get("password")) {
+        writeText(w, http.StatusOK, "Fails.")
+        return
+    }
+
+    sid, err := h.sessions.create()
+    if err != nil {
+        writeText(w, http.StatusInternalServerError, err.Error())
+        return
+    }
+
+    setSessionCookie(w, sid)
+    writeText(w, http.StatusOK, "Ok.")
+}
+
+func setSessionCookie(w http.ResponseWriter, sid string) {
+    http.SetCookie(w, &http.Cookie{
+        Name:     sessionCookie,
+        Value:    sid,
+        Path:     "/",
+        HttpOnly: true,
+        SameSite: http.SameSiteStrictMode,
+    })
+}
+
+func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
+    if cookie, err := r.Cookie(sessionCookie); err == nil {
+        h.sessions.remove(cookie.Value)
+        h.syncStates.remove(cookie.Value)
+    }
+    http.SetCookie(w, &http.Cookie{
+        Name:   sessionCookie,
+        Value:  "",
+        Path:   "/",
+        MaxAge: -1,
+    })
+    writeText(w, http.StatusOK, "Ok.")
+}
+
+// requireAuth rejects requests without a valid session cookie
+func (h *Handler) requireAuth(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if !h.authEnabled {
+            next.ServeHTTP(w, r)
+            return
+        }
+
+        cookie, err := r.Cookie(sessionCookie)
+        if err != nil || !h.sessions.touch(cookie.Value) {
+            writeText(w, http.StatusForbidden, "Forbidden")
+            return
+        }
+
+        next.ServeHTTP(w, r)
+    })
+}
+
+func (h *Handler) checkCredentials(username, password string) bool {
+    userOK := subtle.ConstantTimeCompare([]byte(username), []byte(h.username)) == 1
+    passOK := subtle.ConstantTimeCompare([]byte(password), []byte(h.password)) == 1
+    return userOK && passOK
+}
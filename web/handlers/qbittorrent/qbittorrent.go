@@ -0,0 +1,134 @@
+// handlers/qbittorrent/qbittorrent.go
+package qbittorrent
+
+import (
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/go-chi/chi/v5"
+
+    "rutorrent-web/internal/services"
+)
+
+// API versions reported to clients. Tools such as autobrr and cross-seed
+// refuse to talk to servers reporting a Web API older than 2.2.
+const (
+    appVersion    = "v4.3.9"
+    webAPIVersion = "2.8.3"
+)
+
+// Handler implements a subset of the qBittorrent Web API v2 on top of rTorrent
+type Handler struct {
+    torrentService *services.TorrentService
+    sessions       *sessionStore
+    syncStates     *syncStore
+    authEnabled    bool
+    username       string
+    password       string
+    maxUploadSize  int64
+}
+
+// Config holds handler configuration
+type Config struct {
+    TorrentService *services.TorrentService
+    AuthEnabled    bool
+    Username       string
+    Password       string
+    SessionTimeout time.Duration
+    MaxUploadSize  int64
+}
+
+// New creates a new qBittorrent API handler
+func New(config Config) *Handler {
+    if config.SessionTimeout == 0 {
+        config.SessionTimeout = time.Hour
+    }
+    if config.MaxUploadSize == 0 {
+        config.MaxUploadSize = 32 << 20
+    }
+
+    return &Handler{
+        torrentService: config.TorrentService,
+        sessions:       newSessionStore(config.SessionTimeout),
+        syncStates:     newSyncStore(config.SessionTimeout),
+        authEnabled:    config.AuthEnabled,
+        username:       config.Username,
+        password:       config.Password,
+        maxUploadSize:  config.MaxUploadSize,
+    }
+}
+
+// Routes returns the API routes, to be mounted under /api/v2
+func (h *Handler) Routes() chi.Router {
+    r := chi.NewRouter()
+
+    r.Post("/auth/login", h.handleLogin)
+    r.Post("/auth/logout", h.handleLogout)
+
+    r.Group(func(r chi.Router) {
+        r.Use(h.requireAuth)
+
+        r.Get("/app/version", h.handleAppVersion)
+        r.Get("/app/webapiVersion", h.handleWebAPIVersion)
+
+        r.Get("/torrents/info", h.handleTorrentsInfo)
+        r.Get("/torrents/properties", h.handleTorrentProperties)
+        r.Get("/torrents/files", h.handleTorrentFiles)
+        r.Get("/torrents/trackers", h.handleTorrentTrackers)
+        r.Get("/torrents/categories", h.handleCategories)
+        r.Post("/torrents/add", h.handleAdd)
+        r.Post("/torrents/pause", h.handlePause)
+        r.Post("/torrents/resume", h.handleResume)
+        r.Post("/torrents/delete", h.handleDelete)
+        r.Post("/torrents/setCategory", h.handleSetCategory)
+        r.Post("/torrents/setLocation", h.handleSetLocation)
+
+        r.Get("/transfer/info", h.handleTransferInfo)
+        r.Get("/sync/maindata", h.handleSyncMainData)
+    })
+
+    return r
+}
+
+func (h *Handler) handleAppVersion(w http.ResponseWriter, r *http.Request) {
+    writeText(w, http.StatusOK, appVersion)
+}
+
+func (h *Handler) handleWebAPIVersion(w http.ResponseWriter, r *http.Request) {
+    writeText(w, http.StatusOK, webAPIVersion)
+}
+
+// Helper functions
+
+// resolveHashes expands the "hashes" parameter. qBittorrent separates hashes
+// with "|" and accepts "all" for every torrent.
+func (h *Handler) resolveHashes(param string) []string {
+    if param == "all" {
+        torrents := h.torrentService.Snapshot()
+        hashes := make([]string, 0, len(torrents))
+        for _, t := range torrents {
+            hashes = append(hashes, t.Hash)
+        }
+        return hashes
+    }
+
+    var hashes []string
+    for _, hash := range strings.Split(param, "|") {
+        if hash = strings.TrimSpace(hash); hash != "" {
+            hashes = append(hashes, strings.ToUpper(hash))
+        }
+    }
+    return hashes
+}
+
+func writeText(w http.ResponseWriter, status int, text string) {
+    w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+    w.WriteHeader(status)
+    w.Write([]byte(text))
+}
+
+// parseBool accepts the "true"/"false" strings qBittorrent clients send
+func parseBool(s string) bool {
+    return strings.EqualFold(s, "true") || s == "1"
+}
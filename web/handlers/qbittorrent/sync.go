@@ -0,0 +1,181 @@
+// handlers/qbittorrent/sync.go
+package qbittorrent
+
+import (
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+
+    "rutorrent-web/pkg/respond"
+)
+
+// syncState is what a client received from its last /sync/maindata call
+type syncState struct {
+    rid        int64
+    torrents   map[string]torrentInfo
+    categories map[string]bool
+    server     map[string]interface{}
+    lastSeen   time.Time
+}
+
+// syncStore keeps the last sync state per client so that follow-up requests
+// carrying the returned rid only get the changes since then
+type syncStore struct {
+    states  map[string]*syncState
+    timeout time.Duration
+    mu      sync.Mutex
+}
+
+func newSyncStore(timeout time.Duration) *syncStore {
+    return &syncStore{
+        states:  make(map[string]*syncState),
+        timeout: timeout,
+    }
+}
+
+// get returns the state for a client, pruning expired ones
+func (s *syncStore) get(key string) *syncState {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    now := time.Now()
+    for k, st := range s.states {
+        if now.Sub(st.lastSeen) > s.timeout {
+            delete(s.states, k)
+        }
+    }
+    return s.states[key]
+}
+
+func (s *syncStore) put(key string, state *syncState) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    state.lastSeen = time.Now()
+    s.states[key] = state
+}
+
+func (s *syncStore) remove(key string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    delete(s.states, key)
+}
+
+// syncKey identifies the client by its session cookie. Clients that never
+// logged in, which works with auth disabled, are given a session here;
+// addresses can't tell two clients on the same host apart.
+func (h *Handler) syncKey(w http.ResponseWriter, r *http.Request) (string, error) {
+    if cookie, err := r.Cookie(sessionCookie); err == nil && cookie.Value != "" {
+        return cookie.Value, nil
+    }
+    sid, err := h.sessions.create()
+    if err != nil {
+        return "", err
+    }
+    setSessionCookie(w, sid)
+    return sid, nil
+}
+
+// handleSyncMainData implements /sync/maindata. With rid=0, or a rid that
+// doesn't match the client's last response, a full update is sent;
+// otherwise only changed fields, removed torrents and removed categories.
+func (h *Handler) handleSyncMainData(w http.ResponseWriter, r *http.Request) {
+    rid, _ := strconv.ParseInt(r.URL.Query().Get("rid"), 10, 64)
+    key, err := h.syncKey(w, r)
+    if err != nil {
+        writeText(w, http.StatusInternalServerError, err.Error())
+        return
+    }
+    prev := h.syncStates.get(key)
+
+    cur := &syncState{
+        torrents:   make(map[string]torrentInfo),
+        categories: make(map[string]bool),
+        server:     h.serverState(),
+    }
+    for _, t := range h.torrentService.Snapshot() {
+        info := newTorrentInfo(t)
+        cur.torrents[info.Hash] = info
+    }
+    categories := h.categories()
+    for name := range categories {
+        cur.categories[name] = true
+    }
+
+    full := prev == nil || rid == 0 || rid != prev.rid
+    if prev != nil {
+        cur.rid = prev.rid + 1
+    } else {
+        cur.rid = 1
+    }
+
+    resp := map[string]interface{}{
+        "rid":         cur.rid,
+        "full_update": full,
+    }
+
+    if full {
+        resp["torrents"] = cur.torrents
+        resp["categories"] = categories
+        resp["server_state"] = cur.server
+        resp["tags"] = []string{}
+    } else {
+        torrents := make(map[string]interface{})
+        for hash, info := range cur.torrents {
+            old, ok := prev.torrents[hash]
+            if !ok {
+                torrents[hash] = info
+                continue
+            }
+            if changed := info.diff(&old); len(changed) > 0 {
+                torrents[hash] = changed
+            }
+        }
+        if len(torrents) > 0 {
+            resp["torrents"] = torrents
+        }
+
+        var removed []string
+        for hash := range prev.torrents {
+            if _, ok := cur.torrents[hash]; !ok {
+                removed = append(removed, hash)
+            }
+        }
+        if len(removed) > 0 {
+            resp["torrents_removed"] = removed
+        }
+
+        added := make(map[string]interface{})
+        for name := range cur.categories {
+            if !prev.categories[name] {
+                added[name] = categories[name]
+            }
+        }
+        if len(added) > 0 {
+            resp["categories"] = added
+        }
+
+        var removedCategories []string
+        for name := range prev.categories {
+            if !cur.categories[name] {
+                removedCategories = append(removedCategories, name)
+            }
+        }
+        if len(removedCategories) > 0 {
+            resp["categories_removed"] = removedCategories
+        }
+
+        server := make(map[string]interface{})
+        for k, v := range cur.server {
+            if prev.server[k] != v {
+                server[k] = v
+            }
+        }
+        if len(server) > 0 {
+            resp["server_state"] = server
+        }
+    }
+
+    h.syncStates.put(key, cur)
+    respond.JSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,398 @@
+// handlers/qbittorrent/torrents.go
+package qbittorrent
+
+import (
+    "io"
+    "net/http"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+
+    "rutorrent-web/internal/magnet"
+    "rutorrent-web/internal/services"
+    "rutorrent-web/pkg/respond"
+)
+
+// handleTorrentsInfo implements /torrents/info
+func (h *Handler) handleTorrentsInfo(w http.ResponseWriter, r *http.Request) {
+    q := r.URL.Query()
+    filter := q.Get("filter")
+    _, hasCategory := q["category"]
+    category := q.Get("category")
+
+    var wanted map[string]bool
+    if hashes := q.Get("hashes"); hashes != "" {
+        wanted = make(map[string]bool)
+        for _, hash := range h.resolveHashes(hashes) {
+            wanted[hash] = true
+        }
+    }
+
+    infos := make([]torrentInfo, 0)
+    for _, t := range h.torrentService.Snapshot() {
+        if wanted != nil && !wanted[t.Hash] {
+            continue
+        }
+        if hasCategory && t.Label != category {
+            continue
+        }
+        info := newTorrentInfo(t)
+        if !matchesFilter(&info, filter) {
+            continue
+        }
+        infos = append(infos, info)
+    }
+
+    field := q.Get("sort")
+    if field == "" {
+        field = "hash"
+    }
+    reverse := parseBool(q.Get("reverse"))
+    sort.SliceStable(infos, func(i, j int) bool {
+        if reverse {
+            return infos[j].less(&infos[i], field)
+        }
+        return infos[i].less(&infos[j], field)
+    })
+
+    if offset, err := strconv.Atoi(q.Get("offset")); err == nil {
+        if offset < 0 {
+            offset += len(infos)
+        }
+        if offset < 0 {
+            offset = 0
+        }
+        if offset > len(infos) {
+            offset = len(infos)
+        }
+        infos = infos[offset:]
+    }
+    if limit, err := strconv.Atoi(q.Get("limit")); err == nil && limit > 0 && limit < len(infos) {
+        infos = infos[:limit]
+    }
+
+    respond.JSON(w, http.StatusOK, infos)
+}
+
+// matchesFilter implements the state filters of /torrents/info
+func matchesFilter(info *torrentInfo, filter string) bool {
+    state := info.State
+    paused := strings.HasPrefix(state, "paused")
+    switch filter {
+    case "", "all":
+        return true
+    case "downloading":
+        return strings.HasSuffix(state, "DL") || state == "downloading"
+    case "seeding":
+        return state == "uploading" || state == "stalledUP" || state == "checkingUP"
+    case "completed":
+        return info.Progress >= 1
+    case "paused", "stopped":
+        return paused
+    case "resumed", "running":
+        return !paused
+    case "active":
+        return info.DlSpeed > 0 || info.UpSpeed > 0
+    case "inactive":
+        return info.DlSpeed == 0 && info.UpSpeed == 0
+    case "stalled":
+        return state == "stalledUP" || state == "stalledDL"
+    case "stalled_uploading":
+        return state == "stalledUP"
+    case "stalled_downloading":
+        return state == "stalledDL"
+    case "errored":
+        return state == "error" || state == "missingFiles"
+    }
+    return true
+}
+
+// handleTorrentProperties implements /torrents/properties
+func (h *Handler) handleTorrentProperties(w http.ResponseWriter, r *http.Request) {
+    t, ok := h.torrentService.GetTorrent(r.URL.Query().Get("hash"))
+    if !ok {
+        writeText(w, http.StatusNotFound, "Not Found")
+        return
+    }
+
+    info := newTorrentInfo(t)
+    completion := int64(-1)
+    if !t.FinishedAt.IsZero() {
+        completion = t.FinishedAt.Unix()
+    }
+    creation := int64(-1)
+    if !t.CreatedAt.IsZero() {
+        creation = t.CreatedAt.Unix()
+    }
+
+    respond.JSON(w, http.StatusOK, map[string]interface{}{
+        "save_path":        info.SavePath,
+        "creation_date":    creation,
+        "piece_size":       t.ChunkSize,
+        "comment":          "",
+        "total_wasted":     0,
+        "total_uploaded":   t.UpTotal,
+        "total_downloaded": t.DownTotal,
+        "up_limit":         -1,
+        "dl_limit":         -1,
+        "nb_connections":   t.Seeders + t.Peers,
+        "share_ratio":      t.Ratio,
+        "addition_date":    info.AddedOn,
+        "completion_date":  completion,
+        "created_by":       "",
+        "dl_speed":         t.DownloadRate,
+        "up_speed":         t.UploadRate,
+        "eta":              info.Eta,
+        "seeds":            t.Seeders,
+        "peers":            t.Peers,
+        "pieces_num":       t.Chunks,
+        "pieces_have":      t.CompletedChunks,
+        "total_size":       t.Size,
+        "is_private":       t.Private,
+    })
+}
+
+// handleTorrentFiles implements /torrents/files
+func (h *Handler) handleTorrentFiles(w http.ResponseWriter, r *http.Request) {
+    t, ok := h.torrentService.GetTorrent(r.URL.Query().Get("hash"))
+    if !ok {
+        writeText(w, http.StatusNotFound, "Not Found")
+        return
+    }
+
+    files, err := h.torrentService.GetTorrentFiles(t.Hash)
+    if err != nil {
+        writeText(w, http.StatusInternalServerError, err.Error())
+        return
+    }
+
+    result := make([]map[string]interface{}, 0, len(files))
+    for _, f := range files {
+        name := f.Path
+        if t.MultiFile {
+            // qBittorrent includes the torrent folder in file names
+            name = filepath.ToSlash(filepath.Join(t.Name, f.Path))
+        }
+        result = append(result, map[string]interface{}{
+            "index":        f.Index,
+            "name":         name,
+            "size":         f.Size,
+            "progress":     f.Progress / 100,
+            "priority":     filePriority(f.Priority),
+            "is_seed":      t.Complete,
+            "piece_range":  []int64{f.FirstChunk, f.LastChunk},
+            "availability": -1,
+        })
+    }
+    respond.JSON(w, http.StatusOK, result)
+}
+
+// filePriority maps rTorrent file priorities (0 off, 1 normal, 2 high) to
+// qBittorrent's scale (0 skip, 1 normal, 6 high)
+func filePriority(p int) int {
+    switch p {
+    case 0:
+        return 0
+    case 2:
+        return 6
+    }
+    return 1
+}
+
+// handleTorrentTrackers implements /torrents/trackers
+func (h *Handler) handleTorrentTrackers(w http.ResponseWriter, r *http.Request) {
+    t, ok := h.torrentService.GetTorrent(r.URL.Query().Get("hash"))
+    if !ok {
+        writeText(w, http.StatusNotFound, "Not Found")
+        return
+    }
+
+    trackers, err := h.torrentService.GetTrackers(t.Hash)
+    if err != nil {
+        writeText(w, http.StatusInternalServerError, err.Error())
+        return
+    }
+
+    result := make([]map[string]interface{}, 0, len(trackers))
+    for _, tr := range trackers {
+        if tr.Type == 3 {
+            // DHT pseudo-tracker
+            continue
+        }
+        status, msg := trackerStatus(t, tr)
+        result = append(result, map[string]interface{}{
+            "url":            tr.URL,
+            "status":         status,
+            "tier":           tr.Group,
+            "num_peers":      -1,
+            "num_seeds":      tr.Seeders,
+            "num_leeches":    tr.Leechers,
+            "num_downloaded": tr.Downloaded,
+            "msg":            msg,
+        })
+    }
+    respond.JSON(w, http.StatusOK, result)
+}
+
+// trackerStatus returns qBittorrent's tracker status code: 0 disabled,
+// 1 not contacted, 2 working, 4 not working
+func trackerStatus(t *services.Torrent, tr services.Tracker) (int, string) {
+    switch {
+    case !tr.Enabled:
+        return 0, ""
+    case tr.SuccessCount == 0 && tr.FailedCount == 0:
+        return 1, ""
+    case tr.LastFailure.After(tr.LastSuccess):
+        return 4, t.Message
+    }
+    return 2, ""
+}
+
+// handleCategories implements /torrents/categories. Categories are derived
+// from the labels currently in use.
+func (h *Handler) handleCategories(w http.ResponseWriter, r *http.Request) {
+    respond.JSON(w, http.StatusOK, h.categories())
+}
+
+func (h *Handler) categories() map[string]map[string]string {
+    categories := make(map[string]map[string]string)
+    for _, t := range h.torrentService.Snapshot() {
+        if t.Label == "" {
+            continue
+        }
+        if _, ok := categories[t.Label]; !ok {
+            categories[t.Label] = map[string]string{
+                "name":     t.Label,
+                "savePath": "",
+            }
+        }
+    }
+    return categories
+}
+
+// handleAdd implements /torrents/add
+func (h *Handler) handleAdd(w http.ResponseWriter, r *http.Request) {
+    if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+        if err := r.ParseMultipartForm(h.maxUploadSize); err != nil {
+            writeText(w, http.StatusBadRequest, err.Error())
+            return
+        }
+        defer r.MultipartForm.RemoveAll()
+    } else if err := r.ParseForm(); err != nil {
+        writeText(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    opts := &services.AddTorrentOptions{
+        Start:     !parseBool(r.FormValue("paused")) && !parseBool(r.FormValue("stopped")),
+        SkipCheck: parseBool(r.FormValue("skip_checking")),
+        Directory: r.FormValue("savepath"),
+        Label:     r.FormValue("category"),
+    }
+
+    added, failed := 0, 0
+    for _, line := range strings.Split(r.FormValue("urls"), "\n") {
+        if line = strings.TrimSpace(line); line == "" {
+            continue
+        }
+        var err error
+        if strings.HasPrefix(strings.ToLower(line), "magnet:") {
+            var m *magnet.Magnet
+            if m, err = magnet.Parse(line); err == nil {
+                err = h.torrentService.AddMagnetLink(m, opts)
+            }
+        } else {
+            err = h.torrentService.AddMagnet(line, opts)
+        }
+        if err != nil {
+            failed++
+            continue
+        }
+        added++
+    }
+
+    if r.MultipartForm != nil {
+        for _, fh := range r.MultipartForm.File["torrents"] {
+            file, err := fh.Open()
+            if err != nil {
+                failed++
+                continue
+            }
+            data, err := io.ReadAll(file)
+            file.Close()
+            if err != nil {
+                failed++
+                continue
+            }
+            if err := h.torrentService.AddTorrentData(data, opts); err != nil {
+                failed++
+                continue
+            }
+            added++
+        }
+    }
+
+    if added == 0 {
+        writeText(w, http.StatusUnsupportedMediaType, "Fails.")
+        return
+    }
+    writeText(w, http.StatusOK, "Ok.")
+}
+
+// handlePause implements /torrents/pause. qBittorrent's paused state has no
+// open connections, which matches a stopped torrent in rTorrent.
+func (h *Handler) handlePause(w http.ResponseWriter, r *http.Request) {
+    h.withHashes(w, r, h.torrentService.StopTorrents)
+}
+
+// handleResume implements /torrents/resume
+func (h *Handler) handleResume(w http.ResponseWriter, r *http.Request) {
+    h.withHashes(w, r, h.torrentService.StartTorrents)
+}
+
+// handleDelete implements /torrents/delete
+func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request) {
+    deleteFiles := parseBool(r.FormValue("deleteFiles"))
+    h.withHashes(w, r, func(hashes []string) error {
+        return h.torrentService.EraseTorrents(hashes, deleteFiles)
+    })
+}
+
+// handleSetCategory implements /torrents/setCategory
+func (h *Handler) handleSetCategory(w http.ResponseWriter, r *http.Request) {
+    category := r.FormValue("category")
+    h.withHashes(w, r, func(hashes []string) error {
+        return h.torrentService.SetLabel(hashes, category)
+    })
+}
+
+// handleSetLocation implements /torrents/setLocation
+func (h *Handler) handleSetLocation(w http.ResponseWriter, r *http.Request) {
+    location := strings.TrimSpace(r.FormValue("location"))
+    if location == "" {
+        writeText(w, http.StatusBadRequest, "Save path cannot be empty")
+        return
+    }
+
+    hashes := h.resolveHashes(r.FormValue("hashes"))
+    if err := h.torrentService.SetDirectory(hashes, location); err != nil {
+        writeText(w, http.StatusConflict, err.Error())
+        return
+    }
+    w.WriteHeader(http.StatusOK)
+}
+
+// withHashes runs an operation on the torrents named by the hashes form field
+func (h *Handler) withHashes(w http.ResponseWriter, r *http.Request, op func([]string) error) {
+    hashes := h.resolveHashes(r.FormValue("hashes"))
+    if len(hashes) == 0 {
+        w.WriteHeader(http.StatusOK)
+        return
+    }
+    if err := op(hashes); err != nil {
+        writeText(w, http.StatusInternalServerError, err.Error())
+        return
+    }
+    w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,43 @@
+// handlers/qbittorrent/transfer.go
+package qbittorrent
+
+import (
+    "net/http"
+
+    "rutorrent-web/pkg/respond"
+)
+
+// handleTransferInfo implements /transfer/info
+func (h *Handler) handleTransferInfo(w http.ResponseWriter, r *http.Request) {
+    respond.JSON(w, http.StatusOK, h.serverState())
+}
+
+// serverState returns the global transfer statistics in qBittorrent's
+// format. It is shared by /transfer/info and the server_state part of
+// /sync/maindata.
+func (h *Handler) serverState() map[string]interface{} {
+    info, err := h.torrentService.GetTransferInfo()
+    if err != nil {
+        return map[string]interface{}{
+            "connection_status": "disconnected",
+            "dht_nodes":         0,
+            "dl_info_speed":     0,
+            "dl_info_data":      0,
+            "up_info_speed":     0,
+            "up_info_data":      0,
+            "dl_rate_limit":     0,
+            "up_rate_limit":     0,
+        }
+    }
+
+    return map[string]interface{}{
+        "connection_status": "connected",
+        "dht_nodes":         0,
+        "dl_info_speed":     info.DownRate,
+        "dl_info_data":      info.DownTotal,
+        "up_info_speed":     info.UpRate,
+        "up_info_data":      info.UpTotal,
+        "dl_rate_limit":     info.DownLimit,
+        "up_rate_limit":     info.UpLimit,
+    }
+}
@@ -0,0 +1,197 @@
+// handlers/qbittorrent/types.go
+package qbittorrent
+
+import (
+    "path/filepath"
+    "reflect"
+    "strings"
+    "time"
+
+    "rutorrent-web/internal/services"
+)
+
+// etaInfinity is the value qBittorrent reports when no ETA can be computed
+const etaInfinity = 8640000
+
+// torrentInfo is a torrent as returned by /torrents/info and /sync/maindata
+type torrentInfo struct {
+    Hash          string  `json:"hash"`
+    Name          string  `json:"name"`
+    Size          int64   `json:"size"`
+    TotalSize     int64   `json:"total_size"`
+    Progress      float64 `json:"progress"`
+    DlSpeed       int64   `json:"dlspeed"`
+    UpSpeed       int64   `json:"upspeed"`
+    Priority      int     `json:"priority"`
+    NumSeeds      int     `json:"num_seeds"`
+    NumLeechs     int     `json:"num_leechs"`
+    Ratio         float64 `json:"ratio"`
+    Eta           int64   `json:"eta"`
+    State         string  `json:"state"`
+    Category      string  `json:"category"`
+    Tags          string  `json:"tags"`
+    AddedOn       int64   `json:"added_on"`
+    CompletionOn  int64   `json:"completion_on"`
+    SavePath      string  `json:"save_path"`
+    ContentPath   string  `json:"content_path"`
+    Downloaded    int64   `json:"downloaded"`
+    Uploaded      int64   `json:"uploaded"`
+    AmountLeft    int64   `json:"amount_left"`
+    Completed     int64   `json:"completed"`
+    DlLimit       int64   `json:"dl_limit"`
+    UpLimit       int64   `json:"up_limit"`
+    MaxRatio      float64 `json:"max_ratio"`
+    RatioLimit    float64 `json:"ratio_limit"`
+    AutoTMM       bool    `json:"auto_tmm"`
+    ForceStart    bool    `json:"force_start"`
+    SeqDl         bool    `json:"seq_dl"`
+    FLPiecePrio   bool    `json:"f_l_piece_prio"`
+    SuperSeeding  bool    `json:"super_seeding"`
+    IsPrivate     bool    `json:"is_private"`
+}
+
+// newTorrentInfo maps an rTorrent snapshot entry to the qBittorrent format
+func newTorrentInfo(t *services.Torrent) torrentInfo {
+    info := torrentInfo{
+        Hash:         strings.ToLower(t.Hash),
+        Name:         t.Name,
+        Size:         t.Size,
+        TotalSize:    t.Size,
+        DlSpeed:      t.DownloadRate,
+        UpSpeed:      t.UploadRate,
+        Priority:     0,
+        NumSeeds:     t.Seeders,
+        NumLeechs:    t.Peers,
+        Ratio:        t.Ratio,
+        Eta:          etaInfinity,
+        State:        torrentState(t),
+        Category:     t.Label,
+        AddedOn:      unixOrZero(t.AddedAt),
+        CompletionOn: unixOrZero(t.FinishedAt),
+        SavePath:     savePath(t),
+        ContentPath:  contentPath(t),
+        Downloaded:   t.DownTotal,
+        Uploaded:     t.UpTotal,
+        AmountLeft:   t.LeftBytes,
+        Completed:    t.Downloaded,
+        DlLimit:      -1,
+        UpLimit:      -1,
+        MaxRatio:     -1,
+        RatioLimit:   -2,
+        IsPrivate:    t.Private,
+    }
+
+    if t.Size > 0 {
+        info.Progress = float64(t.Downloaded) / float64(t.Size)
+    }
+    if t.Complete {
+        info.Eta = 0
+    } else if t.DownloadRate > 0 {
+        info.Eta = t.LeftBytes / t.DownloadRate
+    }
+    return info
+}
+
+// torrentState maps rTorrent's state flags to a qBittorrent state string
+func torrentState(t *services.Torrent) string {
+    switch {
+    case t.Hashing && t.Complete:
+        return "checkingUP"
+    case t.Hashing:
+        return "checkingDL"
+    case t.Error == services.ErrorDataMissing:
+        return "missingFiles"
+    case t.State == 0 || !t.Active:
+        if t.Error != services.ErrorNone && !t.Complete {
+            return "error"
+        }
+        if t.Complete {
+            return "pausedUP"
+        }
+        return "pausedDL"
+    case t.Complete && t.UploadRate > 0:
+        return "uploading"
+    case t.Complete:
+        return "stalledUP"
+    case t.Meta:
+        return "metaDL"
+    case t.DownloadRate > 0:
+        return "downloading"
+    default:
+        return "stalledDL"
+    }
+}
+
+// savePath returns the directory the torrent content lives in. For
+// multi-file torrents rTorrent's d.directory already includes the torrent
+// folder, while qBittorrent reports its parent.
+func savePath(t *services.Torrent) string {
+    if t.MultiFile {
+        return filepath.Dir(t.Directory)
+    }
+    return t.Directory
+}
+
+// contentPath returns the file or folder holding the torrent data
+func contentPath(t *services.Torrent) string {
+    if t.BasePath != "" {
+        return t.BasePath
+    }
+    if t.MultiFile {
+        return t.Directory
+    }
+    return filepath.Join(t.Directory, t.Name)
+}
+
+func unixOrZero(t time.Time) int64 {
+    if t.IsZero() {
+        return 0
+    }
+    return t.Unix()
+}
+
+// infoFields maps JSON field names of torrentInfo to struct field indices,
+// used for sorting by arbitrary keys and for computing sync deltas
+var infoFields = func() map[string]int {
+    fields := make(map[string]int)
+    typ := reflect.TypeOf(torrentInfo{})
+    for i := 0; i < typ.NumField(); i++ {
+        name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+        fields[name] = i
+    }
+    return fields
+}()
+
+// diff returns the fields of cur that differ from prev
+func (cur *torrentInfo) diff(prev *torrentInfo) map[string]interface{} {
+    changed := make(map[string]interface{})
+    cv := reflect.ValueOf(cur).Elem()
+    pv := reflect.ValueOf(prev).Elem()
+    for name, i := range infoFields {
+        if a := cv.Field(i).Interface(); a != pv.Field(i).Interface() {
+            changed[name] = a
+        }
+    }
+    return changed
+}
+
+// less compares two torrents by a JSON field name
+func (cur *torrentInfo) less(other *torrentInfo, field string) bool {
+    i, ok := infoFields[field]
+    if !ok {
+        return false
+    }
+    a := reflect.ValueOf(cur).Elem().Field(i)
+    b := reflect.ValueOf(other).Elem().Field(i)
+    switch a.Kind() {
+    case reflect.String:
+        return strings.ToLower(a.String()) < strings.ToLower(b.String())
+    case reflect.Int, reflect.Int64:
+        return a.Int() < b.Int()
+    case reflect.Float64:
+        return a.Float() < b.Float()
+    case reflect.Bool:
+        return !a.Bool() && b.Bool()
+    }
+    return false
+}
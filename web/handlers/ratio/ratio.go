@@ -0,0 +1,95 @@
+// handlers/ratio/ratio.go
+package ratio
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "strings"
+
+    "github.com/go-chi/chi/v5"
+
+    "rutorrent-web/internal/ratio"
+    "rutorrent-web/pkg/respond"
+)
+
+// Handler serves the ratio groups
+type Handler struct {
+    manager *ratio.Manager
+}
+
+// Config holds handler configuration
+type Config struct {
+    Manager *ratio.Manager
+}
+
+// New creates a new ratio groups handler
+func New(config Config) *Handler {
+    return &Handler{manager: config.Manager}
+}
+
+// Routes returns the ratio routes, to be mounted under /ratio
+func (h *Handler) Routes() chi.Router {
+    r := chi.NewRouter()
+
+    r.Get("/", h.handleSettings)
+    r.Put("/", h.handleSetSettings)
+    r.Post("/assign", h.handleAssign)
+    r.Post("/check", h.handleCheck)
+    r.Get("/log", h.handleLog)
+
+    return r
+}
+
+// handleSettings returns the groups and check interval
+func (h *Handler) handleSettings(w http.ResponseWriter, r *http.Request) {
+    respond.JSON(w, http.StatusOK, h.manager.Settings())
+}
+
+// handleSetSettings replaces the settings with the JSON body
+func (h *Handler) handleSetSettings(w http.ResponseWriter, r *http.Request) {
+    var s ratio.Settings
+    if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&s); err != nil {
+        respond.Error(w, http.StatusBadRequest, fmt.Errorf("invalid settings: %w", err))
+        return
+    }
+    if err := h.manager.SetSettings(s); err != nil {
+        respond.Error(w, http.StatusBadRequest, err)
+        return
+    }
+    respond.JSON(w, http.StatusOK, h.manager.Settings())
+}
+
+// handleAssign puts the torrents in "hash" (space separated) in "group",
+// or back on their label's group when it is empty
+func (h *Handler) handleAssign(w http.ResponseWriter, r *http.Request) {
+    hashes := strings.Fields(r.FormValue("hash"))
+    if len(hashes) == 0 {
+        respond.Error(w, http.StatusBadRequest, fmt.Errorf("no torrents given"))
+        return
+    }
+    err := h.manager.Assign(hashes, r.FormValue("group"))
+    switch {
+    case errors.Is(err, ratio.ErrNoGroup):
+        respond.Error(w, http.StatusNotFound, err)
+    case err != nil:
+        respond.Error(w, http.StatusBadGateway, err)
+    default:
+        w.WriteHeader(http.StatusNoContent)
+    }
+}
+
+// handleCheck runs the check now and returns the actions taken
+func (h *Handler) handleCheck(w http.ResponseWriter, r *http.Request) {
+    entries := h.manager.Check()
+    if entries == nil {
+        entries = []ratio.Entry{}
+    }
+    respond.JSON(w, http.StatusOK, entries)
+}
+
+// handleLog returns the actions taken, newest first
+func (h *Handler) handleLog(w http.ResponseWriter, r *http.Request) {
+    respond.JSON(w, http.StatusOK, h.manager.Log())
+}
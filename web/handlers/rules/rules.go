@@ -0,0 +1,120 @@
+// handlers/rules/rules.go
+package rules
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+
+    "github.com/go-chi/chi/v5"
+
+    "rutorrent-web/internal/rules"
+    "rutorrent-web/internal/services"
+    "rutorrent-web/pkg/respond"
+)
+
+// Handler serves the add rules
+type Handler struct {
+    torrentService *services.TorrentService
+    store          *rules.Store
+}
+
+// Config holds handler configuration
+type Config struct {
+    TorrentService *services.TorrentService
+    Store          *rules.Store
+}
+
+// New creates a new rules handler
+func New(config Config) *Handler {
+    return &Handler{
+        torrentService: config.TorrentService,
+        store:          config.Store,
+    }
+}
+
+// Routes returns the rule routes, to be mounted under /rules
+func (h *Handler) Routes() chi.Router {
+    r := chi.NewRouter()
+
+    r.Get("/", h.handleList)
+    r.Put("/", h.handleSet)
+    r.Post("/test", h.handleTest)
+    r.Post("/apply", h.handleApply)
+
+    return r
+}
+
+// handleList returns the rules in order
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+    respond.JSON(w, http.StatusOK, h.store.List())
+}
+
+// handleSet replaces the rules with the JSON array in the body
+func (h *Handler) handleSet(w http.ResponseWriter, r *http.Request) {
+    var list []rules.Rule
+    if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&list); err != nil {
+        respond.Error(w, http.StatusBadRequest, fmt.Errorf("invalid rules: %w", err))
+        return
+    }
+    if err := h.store.Set(list); err != nil {
+        respond.Error(w, http.StatusBadRequest, err)
+        return
+    }
+    respond.JSON(w, http.StatusOK, h.store.List())
+}
+
+// testResult tells which rule matches a torrent and what it would set
+type testResult struct {
+    Info    *services.AddInfo           `json:"info"`
+    Index   int                         `json:"index"` // -1 when no rule matches
+    Rule    *rules.Rule                 `json:"rule,omitempty"`
+    Options *services.AddTorrentOptions `json:"options,omitempty"`
+}
+
+// handleTest shows which rule matches a loaded torrent ("hash") or a
+// torrent described by the JSON body: name, size, trackers, files and
+// source
+func (h *Handler) handleTest(w http.ResponseWriter, r *http.Request) {
+    var info *services.AddInfo
+    if hash := r.URL.Query().Get("hash"); hash != "" {
+        t, ok := h.torrentService.GetTorrent(strings.ToUpper(hash))
+        if !ok {
+            respond.Error(w, http.StatusNotFound, fmt.Errorf("torrent not found"))
+            return
+        }
+        var err error
+        if info, err = rules.Info(h.torrentService, t, h.store.NeedsFiles()); err != nil {
+            respond.Error(w, http.StatusBadGateway, err)
+            return
+        }
+    } else {
+        info = &services.AddInfo{}
+        if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(info); err != nil {
+            respond.Error(w, http.StatusBadRequest, fmt.Errorf("invalid torrent description: %w", err))
+            return
+        }
+    }
+
+    result := testResult{Info: info}
+    result.Index, result.Rule = h.store.Match(info)
+    if result.Rule != nil {
+        result.Options = &services.AddTorrentOptions{Source: info.Source}
+        result.Rule.Apply(result.Options)
+    }
+    respond.JSON(w, http.StatusOK, result)
+}
+
+// handleApply runs the rules over loaded torrents: "hash" (space separated,
+// every torrent if empty), "move=1" to move data to rule directories and
+// "dry=1" to only report what would change
+func (h *Handler) handleApply(w http.ResponseWriter, r *http.Request) {
+    results := h.store.ApplyExisting(
+        h.torrentService,
+        strings.Fields(r.FormValue("hash")),
+        r.FormValue("move") == "1",
+        r.FormValue("dry") == "1",
+    )
+    respond.JSON(w, http.StatusOK, results)
+}
@@ -0,0 +1,99 @@
+// handlers/scheduler/scheduler.go
+package scheduler
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+
+    "github.com/go-chi/chi/v5"
+
+    "rutorrent-web/internal/scheduler"
+    "rutorrent-web/pkg/respond"
+)
+
+// Handler serves the weekly scheduler
+type Handler struct {
+    scheduler *scheduler.Scheduler
+}
+
+// Config holds handler configuration
+type Config struct {
+    Scheduler *scheduler.Scheduler
+}
+
+// New creates a new scheduler handler
+func New(config Config) *Handler {
+    return &Handler{scheduler: config.Scheduler}
+}
+
+// Routes returns the scheduler routes, to be mounted under /scheduler
+func (h *Handler) Routes() chi.Router {
+    r := chi.NewRouter()
+
+    r.Get("/", h.handleSettings)
+    r.Put("/", h.handleSetSettings)
+    r.Get("/status", h.handleStatus)
+    r.Get("/modes", h.handleModes)
+    r.Post("/override", h.handleOverride)
+    r.Delete("/override", h.handleClearOverride)
+
+    return r
+}
+
+// settingsResponse is the grid together with the behavior in force
+type settingsResponse struct {
+    scheduler.Settings
+    Status scheduler.Status `json:"status"`
+}
+
+// handleSettings returns the grid, limits and current status
+func (h *Handler) handleSettings(w http.ResponseWriter, r *http.Request) {
+    respond.JSON(w, http.StatusOK, settingsResponse{
+        Settings: h.scheduler.Settings(),
+        Status:   h.scheduler.Status(),
+    })
+}
+
+// handleSetSettings replaces the settings with the JSON body
+func (h *Handler) handleSetSettings(w http.ResponseWriter, r *http.Request) {
+    var s scheduler.Settings
+    if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&s); err != nil {
+        respond.Error(w, http.StatusBadRequest, fmt.Errorf("invalid settings: %w", err))
+        return
+    }
+    if err := h.scheduler.SetSettings(s); err != nil {
+        respond.Error(w, http.StatusBadRequest, err)
+        return
+    }
+    h.handleSettings(w, r)
+}
+
+// handleStatus returns the slot and behavior in force
+func (h *Handler) handleStatus(w http.ResponseWriter, r *http.Request) {
+    respond.JSON(w, http.StatusOK, h.scheduler.Status())
+}
+
+// handleModes returns the behaviors a slot can have
+func (h *Handler) handleModes(w http.ResponseWriter, r *http.Request) {
+    respond.JSON(w, http.StatusOK, scheduler.Modes)
+}
+
+// handleOverride replaces the grid with "mode" until the next slot
+func (h *Handler) handleOverride(w http.ResponseWriter, r *http.Request) {
+    o, err := h.scheduler.SetOverride(scheduler.Mode(r.FormValue("mode")))
+    if err != nil {
+        respond.Error(w, http.StatusBadRequest, err)
+        return
+    }
+    respond.JSON(w, http.StatusOK, o)
+}
+
+// handleClearOverride returns to the grid
+func (h *Handler) handleClearOverride(w http.ResponseWriter, r *http.Request) {
+    if err := h.scheduler.ClearOverride(); err != nil {
+        respond.Error(w, http.StatusInternalServerError, err)
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,283 @@
+// handlers/share/share.go
+package share
+
+import (
+    "errors"
+    "fmt"
+    "html/template"
+    "net/http"
+    "path"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/go-chi/chi/v5"
+
+    "rutorrent-web/internal/services"
+    "rutorrent-web/internal/services/user"
+    "rutorrent-web/internal/share"
+    "rutorrent-web/pkg/respond"
+    "rutorrent-web/web/handlers/data"
+)
+
+// passwordPage asks for the password of a protected link
+var passwordPage = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Name}}</title></head>
+<body>
+<form method="post">
+<p>{{.Name}} is password protected.</p>
+{{with .Error}}<p>{{.}}</p>{{end}}
+<input type="password" name="password" autofocus>
+<button type="submit">Download</button>
+</form>
+</body>
+</html>
+`))
+
+// Handler manages share links and serves them to people without accounts
+type Handler struct {
+    store          *share.Store
+    torrentService *services.TorrentService
+}
+
+// Config holds handler configuration
+type Config struct {
+    Store          *share.Store
+    TorrentService *services.TorrentService
+}
+
+// linkItem is a link as listed in the management page
+type linkItem struct {
+    share.Link
+    Password  string `json:"-"`
+    Protected bool   `json:"protected"`
+    URL       string `json:"url"`
+}
+
+// New creates a new share link handler
+func New(config Config) *Handler {
+    return &Handler{
+        store:          config.Store,
+        torrentService: config.TorrentService,
+    }
+}
+
+// Routes returns the management routes, to be mounted under /share
+func (h *Handler) Routes() chi.Router {
+    r := chi.NewRouter()
+
+    r.Get("/", h.handleList)
+    r.Post("/", h.handleCreate)
+    r.Delete("/{id}", h.handleRevoke)
+
+    return r
+}
+
+// PublicRoutes returns the download routes, to be mounted under /s outside
+// of authentication
+func (h *Handler) PublicRoutes() chi.Router {
+    r := chi.NewRouter()
+
+    r.Get("/{id}", h.handleDownload)
+    r.Head("/{id}", h.handleDownload)
+    r.Post("/{id}", h.handleUnlock)
+
+    return r
+}
+
+// handleList returns the user's active links with their URLs and counters
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+    links := h.store.List(user.FromContext(r.Context()))
+    items := make([]linkItem, 0, len(links))
+    for i := range links {
+        items = append(items, h.item(&links[i]))
+    }
+    respond.JSON(w, http.StatusOK, items)
+}
+
+// handleCreate creates a link. Form values:
+//
+//	hash      torrent to share
+//	file      file index, omitted or -1 for the whole torrent
+//	format    archive format for whole torrents (zip, tar)
+//	expires   lifetime such as 12h or 7d, 7 days if empty
+//	password  optional
+func (h *Handler) handleCreate(w http.ResponseWriter, r *http.Request) {
+    t, ok := h.torrentService.GetTorrent(r.FormValue("hash"))
+    if !ok {
+        writeError(w, http.StatusNotFound, services.ErrTorrentNotFound)
+        return
+    }
+
+    file := share.WholeTorrent
+    if v := r.FormValue("file"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil {
+            writeError(w, http.StatusBadRequest, fmt.Errorf("invalid file index: %s", v))
+            return
+        }
+        file = n
+    }
+
+    name := t.Name
+    format := ""
+    if file == share.WholeTorrent {
+        f, ok := data.ParseFormat(r.FormValue("format"))
+        if !ok {
+            writeError(w, http.StatusBadRequest, fmt.Errorf("unknown archive format: %s", r.FormValue("format")))
+            return
+        }
+        format = string(f)
+    } else {
+        _, f, err := h.torrentService.DataFile(t.Hash, file)
+        if err != nil {
+            writeError(w, http.StatusBadRequest, err)
+            return
+        }
+        name = path.Base(f.Path)
+    }
+
+    ttl, err := parseTTL(r.FormValue("expires"))
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err)
+        return
+    }
+
+    l, err := h.store.Create(user.FromContext(r.Context()), t.Hash, file, format, name, ttl, r.FormValue("password"))
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err)
+        return
+    }
+    respond.JSON(w, http.StatusCreated, h.item(l))
+}
+
+// handleRevoke deletes a link. The empty 200 lets the management page
+// swap out the row.
+func (h *Handler) handleRevoke(w http.ResponseWriter, r *http.Request) {
+    if err := h.store.Revoke(user.FromContext(r.Context()), chi.URLParam(r, "id")); err != nil {
+        writeError(w, http.StatusNotFound, err)
+        return
+    }
+    w.WriteHeader(http.StatusOK)
+}
+
+// handleDownload serves a link. Protected links show the password form
+// until the unlock cookie is present.
+func (h *Handler) handleDownload(w http.ResponseWriter, r *http.Request) {
+    l, ok := h.resolve(w, r)
+    if !ok {
+        return
+    }
+    if !h.store.Unlocked(l, cookieValue(r, cookieName(l))) {
+        w.Header().Set("Cache-Control", "no-store")
+        passwordPage.Execute(w, map[string]interface{}{"Name": l.Name})
+        return
+    }
+
+    // Resumed downloads aren't counted again
+    if r.Method == http.MethodGet && !resumed(r) {
+        h.store.CountDownload(l.ID)
+    }
+    if l.File == share.WholeTorrent {
+        format, _ := data.ParseFormat(l.Format)
+        data.SendArchive(w, r, h.torrentService, l.Hash, nil, format)
+        return
+    }
+    data.SendFile(w, r, h.torrentService, l.Hash, l.File)
+}
+
+// handleUnlock checks the password of a protected link, sets the unlock
+// cookie and sends the browser back to the download
+func (h *Handler) handleUnlock(w http.ResponseWriter, r *http.Request) {
+    l, ok := h.resolve(w, r)
+    if !ok {
+        return
+    }
+    if err := h.store.CheckPassword(l, r.FormValue("password")); err != nil {
+        status := http.StatusForbidden
+        var locked *share.LockedError
+        if errors.As(err, &locked) {
+            status = http.StatusTooManyRequests
+            w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(locked.Until)/time.Second)+1))
+        }
+        w.Header().Set("Cache-Control", "no-store")
+        w.WriteHeader(status)
+        passwordPage.Execute(w, map[string]interface{}{"Name": l.Name, "Error": err.Error()})
+        return
+    }
+
+    http.SetCookie(w, &http.Cookie{
+        Name:     cookieName(l),
+        Value:    h.store.UnlockToken(l),
+        Path:     "/s/" + l.ID,
+        Expires:  l.Expires,
+        HttpOnly: true,
+        Secure:   r.TLS != nil,
+        SameSite: http.SameSiteLaxMode,
+    })
+    http.Redirect(w, r, r.URL.RequestURI(), http.StatusSeeOther)
+}
+
+// resolve checks the link of a public request and answers invalid ones
+func (h *Handler) resolve(w http.ResponseWriter, r *http.Request) (*share.Link, bool) {
+    q := r.URL.Query()
+    l, err := h.store.Resolve(chi.URLParam(r, "id"), q.Get("e"), q.Get("sig"))
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return nil, false
+    }
+    return l, true
+}
+
+func (h *Handler) item(l *share.Link) linkItem {
+    return linkItem{Link: *l, Protected: l.Protected(), URL: h.store.URL(l)}
+}
+
+// parseTTL parses a link lifetime: a Go duration or a number of days
+// followed by "d"
+func parseTTL(s string) (time.Duration, error) {
+    s = strings.TrimSpace(s)
+    if s == "" {
+        return 0, nil
+    }
+    if days, ok := strings.CutSuffix(s, "d"); ok {
+        n, err := strconv.Atoi(days)
+        if err != nil {
+            return 0, fmt.Errorf("invalid lifetime: %s", s)
+        }
+        return time.Duration(n) * 24 * time.Hour, nil
+    }
+    d, err := time.ParseDuration(s)
+    if err != nil {
+        return 0, fmt.Errorf("invalid lifetime: %s", s)
+    }
+    return d, nil
+}
+
+// resumed reports whether a request continues a download rather than
+// starting it
+func resumed(r *http.Request) bool {
+    rng := r.Header.Get("Range")
+    return rng != "" && !strings.HasPrefix(rng, "bytes=0-")
+}
+
+func cookieName(l *share.Link) string {
+    return "share_" + l.ID
+}
+
+func cookieValue(r *http.Request, name string) string {
+    c, err := r.Cookie(name)
+    if err != nil {
+        return ""
+    }
+    return c.Value
+}
+
+// writeError reports unknown torrents as not found
+func writeError(w http.ResponseWriter, status int, err error) {
+    if errors.Is(err, services.ErrTorrentNotFound) {
+        status = http.StatusNotFound
+    }
+    respond.Error(w, status, err)
+}
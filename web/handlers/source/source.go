@@ -0,0 +1,167 @@
+// handlers/source/source.go
+package source
+
+import (
+    "archive/zip"
+    "bytes"
+    "errors"
+    "fmt"
+    "net/http"
+    "os"
+    "strings"
+
+    "github.com/go-chi/chi/v5"
+
+    "rutorrent-web/internal/services"
+    "rutorrent-web/pkg/sendfile"
+)
+
+// torrentType is the MIME type of .torrent files
+const torrentType = "application/x-bittorrent"
+
+// maxBulk bounds the torrents in one bulk download
+const maxBulk = 1000
+
+// Handler serves the original .torrent files of loaded torrents
+type Handler struct {
+    torrentService *services.TorrentService
+}
+
+// Config holds handler configuration
+type Config struct {
+    TorrentService *services.TorrentService
+}
+
+// New creates a new torrent source handler
+func New(config Config) *Handler {
+    return &Handler{torrentService: config.TorrentService}
+}
+
+// Routes returns the source routes, to be mounted under /source
+func (h *Handler) Routes() chi.Router {
+    r := chi.NewRouter()
+
+    r.Get("/{hash}", h.handleSource)
+    r.Post("/", h.handleBulk)
+
+    return r
+}
+
+// handleSource sends the .torrent of one torrent
+func (h *Handler) handleSource(w http.ResponseWriter, r *http.Request) {
+    h.sendSource(w, r, chi.URLParam(r, "hash"))
+}
+
+// sendSource sends a .torrent named after its torrent
+func (h *Handler) sendSource(w http.ResponseWriter, r *http.Request, hash string) {
+    src, err := h.torrentService.GetTorrentSource(hash)
+    if err != nil {
+        http.Error(w, err.Error(), statusCode(err))
+        return
+    }
+
+    opts := []sendfile.Option{
+        sendfile.WithRequest(r),
+        sendfile.WithContentType(torrentType),
+        sendfile.WithContentName(src.Name + ".torrent"),
+    }
+    if src.Path != "" {
+        err = sendfile.SendFile(w, src.Path, opts...)
+    } else {
+        err = sendfile.SendData(w, src.Data, opts...)
+    }
+    if err != nil && w.Header().Get("Content-Length") == "" {
+        http.Error(w, err.Error(), sendfile.StatusCode(err))
+    }
+}
+
+// handleBulk sends the .torrent files of the space separated "hash" list.
+// A single hash gets the plain file, several a zip. Torrents without a
+// source are left out of the zip; if none has one the request fails.
+func (h *Handler) handleBulk(w http.ResponseWriter, r *http.Request) {
+    hashes := strings.Fields(r.FormValue("hash"))
+    switch {
+    case len(hashes) == 0:
+        http.Error(w, "no torrents given", http.StatusBadRequest)
+        return
+    case len(hashes) == 1:
+        h.sendSource(w, r, hashes[0])
+        return
+    case len(hashes) > maxBulk:
+        http.Error(w, fmt.Sprintf("at most %d torrents at once", maxBulk), http.StatusBadRequest)
+        return
+    }
+
+    var buf bytes.Buffer
+    zw := zip.NewWriter(&buf)
+    names := make(map[string]int, len(hashes))
+    added := 0
+    for _, hash := range hashes {
+        src, err := h.torrentService.GetTorrentSource(hash)
+        if err != nil {
+            continue
+        }
+        data := src.Data
+        if src.Path != "" {
+            if data, err = os.ReadFile(src.Path); err != nil {
+                continue
+            }
+        }
+
+        // Torrents can share a name, number the later ones
+        name := sanitize(src.Name)
+        names[name]++
+        if n := names[name]; n > 1 {
+            name = fmt.Sprintf("%s (%d)", name, n)
+        }
+        fw, err := zw.Create(name + ".torrent")
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        if _, err := fw.Write(data); err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        added++
+    }
+    if err := zw.Close(); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    if added == 0 {
+        http.Error(w, services.ErrSourceNotFound.Error(), http.StatusNotFound)
+        return
+    }
+
+    err := sendfile.SendData(w, buf.Bytes(),
+        sendfile.WithRequest(r),
+        sendfile.WithContentType("application/zip"),
+        sendfile.WithContentName("torrents.zip"),
+    )
+    if err != nil && w.Header().Get("Content-Length") == "" {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
+
+// sanitize makes a torrent name usable as a zip entry name
+func sanitize(name string) string {
+    name = strings.Map(func(r rune) rune {
+        if r == '/' || r == '\\' || r < 0x20 {
+            return '_'
+        }
+        return r
+    }, name)
+    if name = strings.Trim(name, ". "); name == "" {
+        return "torrent"
+    }
+    return name
+}
+
+func statusCode(err error) int {
+    switch {
+    case errors.Is(err, services.ErrTorrentNotFound), errors.Is(err, services.ErrSourceNotFound):
+        return http.StatusNotFound
+    }
+    return http.StatusInternalServerError
+}
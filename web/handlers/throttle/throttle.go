@@ -0,0 +1,96 @@
+// handlers/throttle/throttle.go
+package throttle
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "strings"
+
+    "github.com/go-chi/chi/v5"
+
+    "rutorrent-web/internal/throttle"
+    "rutorrent-web/pkg/respond"
+)
+
+// Handler serves the throttle channels
+type Handler struct {
+    store *throttle.Store
+}
+
+// Config holds handler configuration
+type Config struct {
+    Store *throttle.Store
+}
+
+// New creates a new throttle channels handler
+func New(config Config) *Handler {
+    return &Handler{store: config.Store}
+}
+
+// Routes returns the throttle routes, to be mounted under /throttle
+func (h *Handler) Routes() chi.Router {
+    r := chi.NewRouter()
+
+    r.Get("/", h.handleSettings)
+    r.Put("/", h.handleSetSettings)
+    r.Get("/usage", h.handleUsage)
+    r.Post("/assign", h.handleAssign)
+    r.Post("/apply", h.handleApply)
+
+    return r
+}
+
+// handleSettings returns the channels and label bindings
+func (h *Handler) handleSettings(w http.ResponseWriter, r *http.Request) {
+    respond.JSON(w, http.StatusOK, h.store.Settings())
+}
+
+// handleSetSettings replaces the settings with the JSON body and applies
+// the limits
+func (h *Handler) handleSetSettings(w http.ResponseWriter, r *http.Request) {
+    var s throttle.Settings
+    if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&s); err != nil {
+        respond.Error(w, http.StatusBadRequest, fmt.Errorf("invalid settings: %w", err))
+        return
+    }
+    if err := h.store.SetSettings(s); err != nil {
+        respond.Error(w, http.StatusBadRequest, err)
+        return
+    }
+    respond.JSON(w, http.StatusOK, h.store.Settings())
+}
+
+// handleUsage returns each channel with its torrent count and rates
+func (h *Handler) handleUsage(w http.ResponseWriter, r *http.Request) {
+    respond.JSON(w, http.StatusOK, h.store.Usage())
+}
+
+// handleAssign puts the torrents in "hash" (space separated) on "channel",
+// or takes them off when it is empty
+func (h *Handler) handleAssign(w http.ResponseWriter, r *http.Request) {
+    hashes := strings.Fields(r.FormValue("hash"))
+    if len(hashes) == 0 {
+        respond.Error(w, http.StatusBadRequest, fmt.Errorf("no torrents given"))
+        return
+    }
+    err := h.store.Assign(hashes, r.FormValue("channel"))
+    switch {
+    case errors.Is(err, throttle.ErrNoChannel):
+        respond.Error(w, http.StatusNotFound, err)
+    case err != nil:
+        respond.Error(w, http.StatusBadGateway, err)
+    default:
+        w.WriteHeader(http.StatusNoContent)
+    }
+}
+
+// handleApply sets the channels up in rTorrent again, after it restarted
+func (h *Handler) handleApply(w http.ResponseWriter, r *http.Request) {
+    if err := h.store.Apply(); err != nil {
+        respond.Error(w, http.StatusBadGateway, err)
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
@@ -2,17 +2,38 @@
 package handlers
 
 import (
+    "encoding/json"
+    "fmt"
     "net/http"
-    "your-project/internal/rtorrent"
+    "strconv"
+    "strings"
+    "time"
+
+    "rutorrent-web/internal/hnr"
+    "rutorrent-web/internal/labels"
+    "rutorrent-web/internal/services"
+    "rutorrent-web/internal/services/user"
+    "rutorrent-web/internal/trackers"
+    "rutorrent-web/internal/views"
 )
 
 type TorrentListHeaderData struct {
     Search        string
     CurrentFilter string
+    CurrentSort   string
     Filters       []Filter
+    Views         []ViewEntry   // saved views for the category panel
+    Labels        []*labels.Node   // label hierarchy for the category panel
+    Trackers      []trackers.Group // tracker domains for the trackers panel
+    CurrentView   *views.View
     SortOptions   []SortOption
     Stats         Stats
-    Torrents      map[string]Torrent
+    Torrents      []Torrent
+    Total         int    // torrents known to rTorrent
+    Filtered      int    // torrents matching search and filter
+    Offset        int
+    Limit         int
+    NextCursor    string
 }
 
 type Filter struct {
@@ -20,6 +41,13 @@ type Filter struct {
     Label string
 }
 
+// ViewEntry is a saved view with its live torrent count
+type ViewEntry struct {
+    ID    string
+    Name  string
+    Count int
+}
+
 type SortOption struct {
     Field string
     Label string
@@ -32,60 +60,195 @@ type Stats struct {
 }
 
 type Torrent struct {
+    Hash         string
     Name         string
     Size         int64
     Downloaded   int64
     UploadRate   int64
     DownloadRate int64
     State        int
+    Status       string
     SeedersTotal int
     PeersTotal   int
     Label        string
+    Tracker      string // registered domain of the primary tracker
+    Error        string // error category, empty when the torrent is fine
+    ErrorTitle   string
+    Message      string // d.message as reported by rTorrent
     Progress     float64
+    Ratio        float64
+    AddedAt      time.Time
+    Protected    bool   // hit-and-run protected, removal is refused
+    Obligation   string // seeding still owed to the tracker
 }
 
+// defaultPageSize and maxPageSize bound the rows sent per list request
+const (
+    defaultPageSize = 200
+    maxPageSize     = 5000
+)
+
 type TorrentHandler struct {
-    client *rtorrent.Client
+    torrentSvc *services.TorrentService
+    views      *views.Store
+    labels     *labels.Store
+    trackers   *trackers.Store
+    hnr        *hnr.Store
+}
+
+// TorrentHandlerConfig holds the dependencies of the torrent list
+type TorrentHandlerConfig struct {
+    TorrentService *services.TorrentService
+    Views          *views.Store
+    Labels         *labels.Store
+    Trackers       *trackers.Store
+    HitAndRun      *hnr.Store // optional, fills the obligation column
 }
 
-func NewTorrentHandler(endpoint string) *TorrentHandler {
+func NewTorrentHandler(config TorrentHandlerConfig) *TorrentHandler {
     return &TorrentHandler{
-        client: rtorrent.New(endpoint),
+        torrentSvc: config.TorrentService,
+        views:      config.Views,
+        labels:     config.Labels,
+        trackers:   config.Trackers,
+        hnr:        config.HitAndRun,
     }
 }
 
+// HandleTorrentList returns one page of the filtered and sorted torrent
+// list. Query parameters:
+//
+//	view     saved view ID; its query is combined with search and its sort
+//	         is used unless sort is given
+//	search   search query, e.g. label:tv size>10GiB -sample
+//	filter   named filter (all, downloading, seeding, ...)
+//	label    label including its nested labels
+//	sort     comma separated fields, "-" prefix for descending ("label,-size")
+//	sort2    secondary sort field, appended to sort
+//	offset   start of the page
+//	limit    page size
+//	cursor   continue after the previous page instead of using offset
 func (h *TorrentHandler) HandleTorrentList(w http.ResponseWriter, r *http.Request) {
-    // Get torrent list from rTorrent
-    torrents, err := h.getTorrents()
+    q := r.URL.Query()
+    login := user.FromContext(r.Context())
+
+    search := q.Get("search")
+    sortSpec := q.Get("sort")
+
+    var current *views.View
+    if id := q.Get("view"); id != "" {
+        v, err := h.views.Get(login, id)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusNotFound)
+            return
+        }
+        current = v
+        search = combineQueries(v.Query, search)
+        if sortSpec == "" {
+            sortSpec = v.Sort
+        }
+    }
+
+    if sort2 := q.Get("sort2"); sort2 != "" {
+        sortSpec += "," + sort2
+    }
+    sortKeys, err := services.ParseSort(sortSpec)
     if err != nil {
-        http.Error(w, "Failed to fetch torrents: "+err.Error(), http.StatusInternalServerError)
+        http.Error(w, err.Error(), http.StatusBadRequest)
         return
     }
 
-    // Calculate stats
-    var stats Stats
-    var activeTorrents int
-    var totalDownRate, totalUpRate int64
-    for _, t := range torrents {
+    limit := defaultPageSize
+    if v, err := strconv.Atoi(q.Get("limit")); err == nil && v > 0 {
+        limit = v
+    }
+    if limit > maxPageSize {
+        limit = maxPageSize
+    }
+    offset, _ := strconv.Atoi(q.Get("offset"))
+
+    filter := q.Get("filter")
+    page, err := h.torrentSvc.List(services.ListQuery{
+        Filter: filter,
+        Label:  q.Get("label"),
+        Search: search,
+        Sort:   sortKeys,
+        Offset: offset,
+        Limit:  limit,
+        Cursor: q.Get("cursor"),
+    })
+    if err != nil {
+        writeListError(w, r, err)
+        return
+    }
+
+    snapshot := h.torrentSvc.Snapshot()
+    speeds, _ := h.torrentSvc.GetTotalSpeeds()
+    activeTorrents := 0
+    for _, t := range snapshot {
         if t.DownloadRate > 0 || t.UploadRate > 0 {
             activeTorrents++
         }
-        totalDownRate += t.DownloadRate
-        totalUpRate += t.UploadRate
+    }
+
+    savedViews, err := h.views.List(login)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    counts := views.Counts(h.torrentSvc, savedViews)
+    viewEntries := make([]ViewEntry, 0, len(savedViews))
+    for _, v := range savedViews {
+        viewEntries = append(viewEntries, ViewEntry{ID: v.ID, Name: v.Name, Count: counts[v.ID]})
+    }
+
+    styles, err := h.labels.Styles(login)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    trackerNames, err := h.trackers.Names(login)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    now := time.Now()
+    torrents := make([]Torrent, 0, len(page.Torrents))
+    for _, t := range page.Torrents {
+        row := newTorrent(t)
+        if h.hnr != nil {
+            o := h.hnr.Obligation(t, now)
+            row.Protected, row.Obligation = o.Protected, o.String()
+        }
+        torrents = append(torrents, row)
+    }
+
+    filters := []Filter{
+        {ID: "all", Label: "All"},
+        {ID: "downloading", Label: "Downloading"},
+        {ID: "seeding", Label: "Seeding"},
+        {ID: "completed", Label: "Completed"},
+        {ID: "active", Label: "Active"},
+        {ID: "inactive", Label: "Inactive"},
+        {ID: "error", Label: "Error"},
+    }
+    if h.hnr != nil {
+        filters = append(filters, Filter{ID: "protected", Label: "Protected"})
+    }
+    for _, c := range services.ErrorCategories {
+        filters = append(filters, Filter{ID: string(c), Label: c.Title()})
     }
 
     data := TorrentListHeaderData{
-        Search:        r.URL.Query().Get("search"),
-        CurrentFilter: r.URL.Query().Get("filter"),
-        Filters: []Filter{
-            {ID: "all", Label: "All"},
-            {ID: "downloading", Label: "Downloading"},
-            {ID: "seeding", Label: "Seeding"},
-            {ID: "completed", Label: "Completed"},
-            {ID: "active", Label: "Active"},
-            {ID: "inactive", Label: "Inactive"},
-            {ID: "error", Label: "Error"},
-        },
+        Search:        q.Get("search"),
+        CurrentFilter: filter,
+        CurrentSort:   sortSpec,
+        Filters:     filters,
+        Views:       viewEntries,
+        Labels:      labels.Tree(snapshot, styles),
+        Trackers:    trackers.Groups(snapshot, trackerNames),
+        CurrentView: current,
         SortOptions: []SortOption{
             {Field: "name", Label: "Name"},
             {Field: "size", Label: "Size"},
@@ -94,20 +257,22 @@ func (h *TorrentHandler) HandleTorrentList(w http.ResponseWriter, r *http.Reques
             {Field: "added", Label: "Date Added"},
         },
         Stats: Stats{
-            DownloadSpeed:  formatSpeed(totalDownRate),
-            UploadSpeed:    formatSpeed(totalUpRate),
+            DownloadSpeed:  formatSpeed(speeds.Download),
+            UploadSpeed:    formatSpeed(speeds.Upload),
             ActiveTorrents: activeTorrents,
         },
-        Torrents: torrents,
+        Torrents:   torrents,
+        Total:      page.Total,
+        Filtered:   page.Filtered,
+        Offset:     page.Offset,
+        Limit:      limit,
+        NextCursor: page.NextCursor,
     }
 
-    // Filter torrents based on search and filter
-    data.Torrents = h.filterTorrents(torrents, data.Search, data.CurrentFilter)
-
-    // Sort torrents
-    sortField := r.URL.Query().Get("sort")
-    if sortField != "" {
-        h.sortTorrents(data.Torrents, sortField)
+    if wantsJSON(r) {
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(data)
+        return
     }
 
     // If this is an HTMX request, render just the torrent list
@@ -120,122 +285,67 @@ func (h *TorrentHandler) HandleTorrentList(w http.ResponseWriter, r *http.Reques
     renderTemplate(w, "torrents/index", data)
 }
 
-func (h *TorrentHandler) getTorrents() (map[string]Torrent, error) {
-    // Get list of torrent hashes
-    hashes, err := h.client.GetDownloadList()
-    if err != nil {
-        return nil, err
+// combineQueries ANDs a view's query with the search box
+func combineQueries(viewQuery, search string) string {
+    switch {
+    case strings.TrimSpace(viewQuery) == "":
+        return search
+    case strings.TrimSpace(search) == "":
+        return viewQuery
     }
-
-    torrents := make(map[string]Torrent)
-    for _, hash := range hashes {
-        info, err := h.client.GetTorrentInfo(hash)
-        if err != nil {
-            continue
-        }
-
-        // Calculate progress percentage
-        progress := float64(0)
-        if info["size"].(int64) > 0 {
-            progress = float64(info["completed"].(int64)) / float64(info["size"].(int64)) * 100
-        }
-
-        torrents[hash] = Torrent{
-            Name:         info["name"].(string),
-            Size:         info["size"].(int64),
-            Downloaded:   info["completed"].(int64),
-            UploadRate:   info["upload_rate"].(int64),
-            DownloadRate: info["download_rate"].(int64),
-            State:        info["state"].(int),
-            SeedersTotal: info["seeders"].(int),
-            PeersTotal:   info["peers"].(int),
-            Progress:     progress,
-        }
-    }
-
-    return torrents, nil
+    return "(" + viewQuery + ") (" + search + ")"
 }
 
-func (h *TorrentHandler) filterTorrents(torrents map[string]Torrent, search string, filter string) map[string]Torrent {
-    if search == "" && (filter == "" || filter == "all") {
-        return torrents
+// writeListError reports an invalid list request. Search syntax errors carry
+// the position of the problem so the UI can point at it; HTMX requests also
+// get them as a "searchError" event since error responses aren't swapped in.
+func writeListError(w http.ResponseWriter, r *http.Request, err error) {
+    body := map[string]interface{}{"error": err.Error()}
+    if qErr, ok := err.(*services.QueryError); ok {
+        body["position"] = qErr.Pos
+        body["message"] = qErr.Msg
     }
 
-    filtered := make(map[string]Torrent)
-    for hash, torrent := range torrents {
-        // Apply search filter
-        if search != "" && !strings.Contains(strings.ToLower(torrent.Name), strings.ToLower(search)) {
-            continue
-        }
-
-        // Apply status filter
-        if filter != "" && filter != "all" {
-            switch filter {
-            case "downloading":
-                if torrent.DownloadRate == 0 {
-                    continue
-                }
-            case "seeding":
-                if torrent.Progress < 100 || torrent.UploadRate == 0 {
-                    continue
-                }
-            case "completed":
-                if torrent.Progress < 100 {
-                    continue
-                }
-            case "active":
-                if torrent.DownloadRate == 0 && torrent.UploadRate == 0 {
-                    continue
-                }
-            case "inactive":
-                if torrent.DownloadRate > 0 || torrent.UploadRate > 0 {
-                    continue
-                }
-            case "error":
-                if torrent.State&0x10 == 0 { // Assuming error bit is 0x10
-                    continue
-                }
-            }
+    if r.Header.Get("HX-Request") == "true" {
+        if event, err := json.Marshal(map[string]interface{}{"searchError": body}); err == nil {
+            w.Header().Set("HX-Trigger", string(event))
         }
-
-        filtered[hash] = torrent
     }
 
-    return filtered
+    if wantsJSON(r) {
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(http.StatusBadRequest)
+        json.NewEncoder(w).Encode(body)
+        return
+    }
+    http.Error(w, err.Error(), http.StatusBadRequest)
 }
 
-func (h *TorrentHandler) sortTorrents(torrents map[string]Torrent, field string) {
-    // Create a slice of hashes for sorting
-    hashes := make([]string, 0, len(torrents))
-    for hash := range torrents {
-        hashes = append(hashes, hash)
-    }
-
-    // Sort the hashes based on the field
-    sort.Slice(hashes, func(i, j int) bool {
-        t1, t2 := torrents[hashes[i]], torrents[hashes[j]]
-        switch field {
-        case "name":
-            return t1.Name < t2.Name
-        case "size":
-            return t1.Size < t2.Size
-        case "progress":
-            return t1.Progress < t2.Progress
-        case "speed":
-            return (t1.DownloadRate + t1.UploadRate) < (t2.DownloadRate + t2.UploadRate)
-        default:
-            return false
-        }
-    })
+func wantsJSON(r *http.Request) bool {
+    return r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json")
+}
 
-    // Create a new sorted map
-    sorted := make(map[string]Torrent)
-    for _, hash := range hashes {
-        sorted[hash] = torrents[hash]
+func newTorrent(t *services.Torrent) Torrent {
+    return Torrent{
+        Hash:         t.Hash,
+        Name:         t.Name,
+        Size:         t.Size,
+        Downloaded:   t.Downloaded,
+        UploadRate:   t.UploadRate,
+        DownloadRate: t.DownloadRate,
+        State:        t.State,
+        Status:       t.Status(),
+        SeedersTotal: t.Seeders,
+        PeersTotal:   t.Peers,
+        Label:        t.Label,
+        Tracker:      t.PrimaryTracker(),
+        Error:        string(t.Error),
+        ErrorTitle:   t.Error.Title(),
+        Message:      t.Message,
+        Progress:     t.Progress,
+        Ratio:        t.Ratio,
+        AddedAt:      t.AddedAt,
     }
-
-    // Replace the original map
-    torrents = sorted
 }
 
 // Helper functions
@@ -0,0 +1,77 @@
+// handlers/trackers/trackers.go
+package trackers
+
+import (
+    "net/http"
+
+    "github.com/go-chi/chi/v5"
+
+    "rutorrent-web/internal/services"
+    "rutorrent-web/internal/services/user"
+    "rutorrent-web/internal/trackers"
+    "rutorrent-web/pkg/respond"
+)
+
+// Handler serves the tracker groups panel
+type Handler struct {
+    torrentService *services.TorrentService
+    store          *trackers.Store
+}
+
+// Config holds handler configuration
+type Config struct {
+    TorrentService *services.TorrentService
+    Store          *trackers.Store
+}
+
+// New creates a new tracker groups handler
+func New(config Config) *Handler {
+    return &Handler{
+        torrentService: config.TorrentService,
+        store:          config.Store,
+    }
+}
+
+// Routes returns the tracker routes, to be mounted under /trackers
+func (h *Handler) Routes() chi.Router {
+    r := chi.NewRouter()
+
+    r.Get("/", h.handleGroups)
+    r.Post("/name", h.handleSetName)
+    r.Get("/icon", h.handleIcon)
+
+    return r
+}
+
+// handleGroups returns the tracker domains with counts and rates
+func (h *Handler) handleGroups(w http.ResponseWriter, r *http.Request) {
+    names, err := h.store.Names(user.FromContext(r.Context()))
+    if err != nil {
+        respond.Error(w, http.StatusInternalServerError, err)
+        return
+    }
+    respond.JSON(w, http.StatusOK, trackers.Groups(h.torrentService.Snapshot(), names))
+}
+
+// handleSetName sets the display name of "tracker" to "name"
+func (h *Handler) handleSetName(w http.ResponseWriter, r *http.Request) {
+    err := h.store.SetName(user.FromContext(r.Context()), r.FormValue("tracker"), r.FormValue("name"))
+    if err != nil {
+        respond.Error(w, http.StatusBadRequest, err)
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// handleIcon serves the favicon of "tracker" from the icon directories.
+// Icons are never fetched from the tracker itself.
+func (h *Handler) handleIcon(w http.ResponseWriter, r *http.Request) {
+    path, mime, ok := h.store.Icon(r.URL.Query().Get("tracker"))
+    if !ok {
+        http.NotFound(w, r)
+        return
+    }
+    w.Header().Set("Content-Type", mime)
+    w.Header().Set("Cache-Control", "max-age=86400")
+    http.ServeFile(w, r, path)
+}
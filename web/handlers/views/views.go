@@ -0,0 +1,209 @@
+// handlers/views/views.go
+package views
+
+import (
+    "encoding/json"
+    "errors"
+    "io"
+    "net/http"
+    "strings"
+
+    "github.com/go-chi/chi/v5"
+
+    "rutorrent-web/internal/services"
+    "rutorrent-web/internal/services/user"
+    "rutorrent-web/internal/views"
+    "rutorrent-web/pkg/respond"
+)
+
+// maxImportSize bounds uploaded view exports
+const maxImportSize = 1 << 20
+
+// Handler serves the saved views API
+type Handler struct {
+    store          *views.Store
+    torrentService *services.TorrentService
+}
+
+// Config holds handler configuration
+type Config struct {
+    Store          *views.Store
+    TorrentService *services.TorrentService
+}
+
+// viewItem is a view as listed in the category panel
+type viewItem struct {
+    views.View
+    Count int `json:"count"` // matching torrents, -1 if the query is invalid
+}
+
+// New creates a new saved views handler
+func New(config Config) *Handler {
+    return &Handler{
+        store:          config.Store,
+        torrentService: config.TorrentService,
+    }
+}
+
+// Routes returns the saved views routes, to be mounted under /views
+func (h *Handler) Routes() chi.Router {
+    r := chi.NewRouter()
+
+    r.Get("/", h.handleList)
+    r.Post("/", h.handleCreate)
+    r.Get("/export", h.handleExport)
+    r.Post("/import", h.handleImport)
+    r.Post("/order", h.handleOrder)
+    r.Get("/{id}", h.handleGet)
+    r.Put("/{id}", h.handleUpdate)
+    r.Delete("/{id}", h.handleDelete)
+
+    return r
+}
+
+// handleList returns the user's views with live torrent counts
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+    list, err := h.store.List(user.FromContext(r.Context()))
+    if err != nil {
+        writeError(w, http.StatusInternalServerError, err)
+        return
+    }
+
+    counts := views.Counts(h.torrentService, list)
+    items := make([]viewItem, 0, len(list))
+    for _, v := range list {
+        items = append(items, viewItem{View: v, Count: counts[v.ID]})
+    }
+    respond.JSON(w, http.StatusOK, items)
+}
+
+func (h *Handler) handleGet(w http.ResponseWriter, r *http.Request) {
+    v, err := h.store.Get(user.FromContext(r.Context()), chi.URLParam(r, "id"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err)
+        return
+    }
+    respond.JSON(w, http.StatusOK, v)
+}
+
+func (h *Handler) handleCreate(w http.ResponseWriter, r *http.Request) {
+    var v views.View
+    if err := json.NewDecoder(io.LimitReader(r.Body, maxImportSize)).Decode(&v); err != nil {
+        writeError(w, http.StatusBadRequest, err)
+        return
+    }
+    v.ID = ""
+
+    saved, err := h.store.Save(user.FromContext(r.Context()), v)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err)
+        return
+    }
+    respond.JSON(w, http.StatusCreated, saved)
+}
+
+func (h *Handler) handleUpdate(w http.ResponseWriter, r *http.Request) {
+    var v views.View
+    if err := json.NewDecoder(io.LimitReader(r.Body, maxImportSize)).Decode(&v); err != nil {
+        writeError(w, http.StatusBadRequest, err)
+        return
+    }
+    v.ID = chi.URLParam(r, "id")
+
+    saved, err := h.store.Update(user.FromContext(r.Context()), v)
+    if errors.Is(err, views.ErrNotFound) {
+        writeError(w, http.StatusNotFound, err)
+        return
+    }
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err)
+        return
+    }
+    respond.JSON(w, http.StatusOK, saved)
+}
+
+func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request) {
+    if err := h.store.Delete(user.FromContext(r.Context()), chi.URLParam(r, "id")); err != nil {
+        writeError(w, http.StatusNotFound, err)
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// handleOrder sets the panel order from a JSON array of view IDs
+func (h *Handler) handleOrder(w http.ResponseWriter, r *http.Request) {
+    var ids []string
+    if err := json.NewDecoder(io.LimitReader(r.Body, maxImportSize)).Decode(&ids); err != nil {
+        writeError(w, http.StatusBadRequest, err)
+        return
+    }
+    if err := h.store.Reorder(user.FromContext(r.Context()), ids); err != nil {
+        writeError(w, http.StatusInternalServerError, err)
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// handleExport downloads views as a JSON file. ids selects views by
+// comma separated ID, all views are exported without it.
+func (h *Handler) handleExport(w http.ResponseWriter, r *http.Request) {
+    var ids []string
+    for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+        if id = strings.TrimSpace(id); id != "" {
+            ids = append(ids, id)
+        }
+    }
+
+    data, err := h.store.Export(user.FromContext(r.Context()), ids)
+    if err != nil {
+        writeError(w, http.StatusInternalServerError, err)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.Header().Set("Content-Disposition", `attachment; filename="views.json"`)
+    w.Write(data)
+}
+
+// handleImport adds views from an exported file, sent either as the request
+// body or as the "file" field of a multipart form. With replace=1 views with
+// the same name are overwritten instead of renamed.
+func (h *Handler) handleImport(w http.ResponseWriter, r *http.Request) {
+    var body io.Reader = r.Body
+    if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+        file, _, err := r.FormFile("file")
+        if err != nil {
+            writeError(w, http.StatusBadRequest, err)
+            return
+        }
+        defer file.Close()
+        body = file
+    }
+
+    data, err := io.ReadAll(io.LimitReader(body, maxImportSize+1))
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err)
+        return
+    }
+    if len(data) > maxImportSize {
+        respond.JSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": "views file too large"})
+        return
+    }
+
+    replace := r.FormValue("replace") == "1" || r.FormValue("replace") == "true"
+    imported, err := h.store.Import(user.FromContext(r.Context()), data, replace)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err)
+        return
+    }
+    respond.JSON(w, http.StatusOK, imported)
+}
+
+// writeError adds the position of query errors to the error body
+func writeError(w http.ResponseWriter, status int, err error) {
+    body := map[string]interface{}{"error": err.Error()}
+    if qErr, ok := err.(*services.QueryError); ok {
+        body["position"] = qErr.Pos
+    }
+    respond.JSON(w, status, body)
+}
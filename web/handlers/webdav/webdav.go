@@ -0,0 +1,103 @@
+// handlers/webdav/webdav.go
+package webdav
+
+import (
+    "crypto/subtle"
+    "net/http"
+    "strings"
+
+    "github.com/go-chi/chi/v5"
+    "golang.org/x/net/webdav"
+
+    "rutorrent-web/internal/davfs"
+    "rutorrent-web/internal/services"
+)
+
+// allowedMethods are the methods of the read-only view
+const allowedMethods = "OPTIONS, GET, HEAD, PROPFIND"
+
+func init() {
+    // chi only routes methods it knows about
+    chi.RegisterMethod("PROPFIND")
+}
+
+// Handler serves completed torrents over read-only WebDAV
+type Handler struct {
+    dav         *webdav.Handler
+    authEnabled bool
+    username    string
+    password    string
+}
+
+// Config holds handler configuration
+type Config struct {
+    Prefix      string // path the handler is mounted at
+    FS          *davfs.FS
+    AuthEnabled bool
+    Username    string
+    Password    string
+}
+
+// New creates a new WebDAV handler
+func New(config Config) *Handler {
+    return &Handler{
+        dav: &webdav.Handler{
+            Prefix:     strings.TrimSuffix(config.Prefix, "/"),
+            FileSystem: config.FS,
+            LockSystem: webdav.NewMemLS(),
+            Logger: func(r *http.Request, err error) {
+                if err != nil {
+                    services.Warnf("webdav %s %s: %v", r.Method, r.URL.Path, err)
+                }
+            },
+        },
+        authEnabled: config.AuthEnabled,
+        username:    config.Username,
+        password:    config.Password,
+    }
+}
+
+// ServeHTTP implements http.Handler. WebDAV clients send credentials with
+// every request, so the app's basic auth is checked here rather than with
+// a session.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    if h.authEnabled {
+        username, password, ok := r.BasicAuth()
+        if !ok || !h.checkCredentials(username, password) {
+            w.Header().Set("WWW-Authenticate", `Basic realm="rutorrent", charset="UTF-8"`)
+            http.Error(w, "Unauthorized", http.StatusUnauthorized)
+            return
+        }
+    }
+
+    switch r.Method {
+    case http.MethodOptions:
+        // Answered here so clients don't see the write methods of the
+        // underlying handler and mount the share read-only
+        w.Header().Set("Allow", allowedMethods)
+        w.Header().Set("DAV", "1")
+        w.Header().Set("MS-Author-Via", "DAV")
+        w.WriteHeader(http.StatusOK)
+    case "PROPFIND":
+        // A missing Depth means infinity, which would walk every download
+        if d := r.Header.Get("Depth"); d != "0" && d != "1" {
+            w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+            w.WriteHeader(http.StatusForbidden)
+            w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>` +
+                `<D:error xmlns:D="DAV:"><D:propfind-finite-depth/></D:error>`))
+            return
+        }
+        h.dav.ServeHTTP(w, r)
+    case http.MethodGet, http.MethodHead:
+        h.dav.ServeHTTP(w, r)
+    default:
+        w.Header().Set("Allow", allowedMethods)
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+func (h *Handler) checkCredentials(username, password string) bool {
+    userOK := subtle.ConstantTimeCompare([]byte(username), []byte(h.username)) == 1
+    passOK := subtle.ConstantTimeCompare([]byte(password), []byte(h.password)) == 1
+    return userOK && passOK
+}